@@ -0,0 +1,799 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type kind byte
+
+const (
+	kindFallback kind = iota
+	kindBool
+	kindUint8
+	kindInt8
+	kindDouble
+	kindU16
+	kindI16
+	kindU32
+	kindI32
+	kindU64
+	kindI64
+	kindString
+	kindBinary
+	kindStruct
+	kindPtr
+	kindList
+	kindSet
+	kindMap
+)
+
+var builtinKinds = map[string]kind{
+	"bool":    kindBool,
+	"byte":    kindUint8,
+	"uint8":   kindUint8,
+	"int8":    kindInt8,
+	"float32": kindDouble,
+	"float64": kindDouble,
+	"uint16":  kindU16,
+	"int16":   kindI16,
+	"uint32":  kindU32,
+	"int32":   kindI32,
+	"rune":    kindI32,
+	"uint64":  kindU64,
+	"uint":    kindU64,
+	"int64":   kindI64,
+	"int":     kindI64,
+	"string":  kindString,
+}
+
+// basic describes how a basic kind is written and read,
+// it mirrors the encoders of dynamic package.
+type basic struct {
+	ttype, write, read, raw string
+}
+
+var basics = map[kind]basic{
+	kindBool:   {"thrift.BOOL", "WriteBool", "ReadBool", "bool"},
+	kindUint8:  {"thrift.BYTE", "WriteByte", "ReadByte", "byte"},
+	kindInt8:   {"thrift.BYTE", "WriteByte", "ReadByte", "byte"},
+	kindDouble: {"thrift.DOUBLE", "WriteDouble", "ReadDouble", "float64"},
+	kindU16:    {"thrift.I16", "WriteU16", "ReadU16", "uint16"},
+	kindI16:    {"thrift.I16", "WriteI16", "ReadI16", "int16"},
+	kindU32:    {"thrift.I32", "WriteU32", "ReadU32", "uint32"},
+	kindI32:    {"thrift.I32", "WriteI32", "ReadI32", "int32"},
+	kindU64:    {"thrift.I64", "WriteU64", "ReadU64", "uint64"},
+	kindI64:    {"thrift.I64", "WriteI64", "ReadI64", "int64"},
+	kindString: {"thrift.STRING", "WriteString", "ReadString", "string"},
+	kindBinary: {"thrift.STRING", "WriteBinary", "ReadBinary", "[]byte"},
+}
+
+// typeInfo a resolved field type, expr is its Go type expression.
+type typeInfo struct {
+	kind      kind
+	expr      string
+	key, elem *typeInfo
+	// named is true for types declared in the package, such as enums.
+	named bool
+}
+
+var fallback = &typeInfo{kind: kindFallback}
+
+// containers reports whether t has a set or map, which are sorted on canonical output.
+func (t *typeInfo) containers() bool {
+	switch t.kind {
+	case kindSet, kindMap:
+		return true
+	case kindPtr, kindList:
+		return t.elem.containers()
+	}
+	return false
+}
+
+func (t *typeInfo) ttype() string {
+	switch t.kind {
+	case kindStruct:
+		return "thrift.STRUCT"
+	case kindPtr:
+		return t.elem.ttype()
+	case kindList:
+		return "thrift.LIST"
+	case kindSet:
+		return "thrift.SET"
+	case kindMap:
+		return "thrift.MAP"
+	}
+	return basics[t.kind].ttype
+}
+
+// hints list and set hints of field tag.
+type hints struct {
+	list  []bool
+	index int
+}
+
+func (h *hints) nextIsList() bool {
+	if h.index < len(h.list) {
+		h.index++
+		return h.list[h.index-1]
+	}
+	return true
+}
+
+type field struct {
+	name     string
+	identity int16
+	required bool
+	typ      *typeInfo
+}
+
+type structInfo struct {
+	name    string
+	fields  []field
+	unknown string
+}
+
+// sorted returns fields of s sorted by identity, as canonical output does.
+func (s structInfo) sorted() []field {
+	r := append([]field(nil), s.fields...)
+	sort.SliceStable(r, func(i, j int) bool {
+		return r[i].identity < r[j].identity
+	})
+	return r
+}
+
+// canonical reports whether output of s differs when canonical,
+// nested structs handle canonical output by their own Write methods.
+func (s structInfo) canonical() bool {
+	if s.unknown != "" {
+		return true
+	}
+	for i, f := range s.sorted() {
+		if f.identity != s.fields[i].identity || f.typ.containers() {
+			return true
+		}
+	}
+	return false
+}
+
+type generator struct {
+	specs    map[string]*ast.TypeSpec
+	generate map[string]bool
+	visiting map[string]bool
+	// isZero structs which need generated zero check.
+	isZero map[string]bool
+
+	buf         bytes.Buffer
+	tmp         int
+	usesDynamic bool
+}
+
+// Generate returns source of generated methods of typeNames
+// of package in dir, file output is excluded from parsing.
+func Generate(dir string, typeNames []string, output string) ([]byte, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		name := fi.Name()
+		return !strings.HasSuffix(name, "_test.go") && name != output
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected one package in %s, got %d", dir, len(pkgs))
+	}
+	var pkg *ast.Package
+	for _, v := range pkgs {
+		pkg = v
+	}
+	g := &generator{
+		specs:    make(map[string]*ast.TypeSpec),
+		generate: make(map[string]bool),
+		visiting: make(map[string]bool),
+		isZero:   make(map[string]bool),
+	}
+	fileNames := make([]string, 0, len(pkg.Files))
+	for name := range pkg.Files {
+		fileNames = append(fileNames, name)
+	}
+	sort.Strings(fileNames)
+	var order []string
+	methods := make(map[string]bool)
+	for _, name := range fileNames {
+		for _, decl := range pkg.Files[name].Decls {
+			switch decl := decl.(type) {
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					if spec, ok := spec.(*ast.TypeSpec); ok {
+						g.specs[spec.Name.Name] = spec
+						if s, ok := spec.Type.(*ast.StructType); ok && hasThriftTag(s) {
+							order = append(order, spec.Name.Name)
+						}
+					}
+				}
+			case *ast.FuncDecl:
+				if decl.Recv != nil && (decl.Name.Name == "Read" || decl.Name.Name == "Write" || decl.Name.Name == "ThriftGenerated") {
+					methods[receiverName(decl.Recv.List[0].Type)] = true
+				}
+			}
+		}
+	}
+	if len(typeNames) != 0 {
+		order = typeNames
+	}
+	for _, name := range order {
+		spec, ok := g.specs[name]
+		if !ok {
+			return nil, fmt.Errorf("type %s not found", name)
+		}
+		if _, ok := spec.Type.(*ast.StructType); !ok {
+			return nil, fmt.Errorf("type %s must be struct", name)
+		}
+		if methods[name] {
+			return nil, fmt.Errorf("type %s already has Read, Write or ThriftGenerated method", name)
+		}
+		g.generate[name] = true
+	}
+	var structs []structInfo
+	for _, name := range order {
+		s, err := g.structInfo(name)
+		if err != nil {
+			return nil, err
+		}
+		structs = append(structs, s)
+	}
+	for _, s := range structs {
+		g.p("\n// ThriftGenerated marks methods of %s as generated by thrift-dynamic-gen.", s.name)
+		g.p("func (*%s) ThriftGenerated() {}", s.name)
+		g.writeMethod(s)
+		g.readMethod(s)
+	}
+	if err = g.isZeroMethods(); err != nil {
+		return nil, err
+	}
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by thrift-dynamic-gen. DO NOT EDIT.\n\npackage %s\n\nimport (\n", pkg.Name)
+	if g.usesDynamic {
+		fmt.Fprintf(&src, "%q\n", "github.com/b1avk/thrift/pkg/dynamic")
+	}
+	fmt.Fprintf(&src, "%q\n)\n", "github.com/b1avk/thrift/pkg/thrift")
+	src.Write(g.buf.Bytes())
+	return format.Source(src.Bytes())
+}
+
+func hasThriftTag(s *ast.StructType) bool {
+	for _, f := range s.Fields.List {
+		if _, ok := lookupTag(f); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func lookupTag(f *ast.Field) (string, bool) {
+	if f.Tag == nil {
+		return "", false
+	}
+	tag, err := strconv.Unquote(f.Tag.Value)
+	if err != nil {
+		return "", false
+	}
+	return reflect.StructTag(tag).Lookup("thrift")
+}
+
+func receiverName(e ast.Expr) string {
+	if s, ok := e.(*ast.StarExpr); ok {
+		e = s.X
+	}
+	if i, ok := e.(*ast.Ident); ok {
+		return i.Name
+	}
+	return ""
+}
+
+func fieldNames(f *ast.Field) []string {
+	if len(f.Names) != 0 {
+		names := make([]string, len(f.Names))
+		for i, n := range f.Names {
+			names[i] = n.Name
+		}
+		return names
+	}
+	e := f.Type
+	if s, ok := e.(*ast.StarExpr); ok {
+		e = s.X
+	}
+	if s, ok := e.(*ast.SelectorExpr); ok {
+		return []string{s.Sel.Name}
+	}
+	return []string{types.ExprString(e)}
+}
+
+func (g *generator) structInfo(name string) (s structInfo, err error) {
+	s.name = name
+	seen := make(map[int16]string)
+	for _, f := range g.specs[name].Type.(*ast.StructType).Fields.List {
+		tag, ok := lookupTag(f)
+		if !ok {
+			if len(f.Names) == 0 {
+				return s, fmt.Errorf("%s: embedded field %s is not supported", name, fieldNames(f)[0])
+			}
+			continue
+		}
+		for _, fname := range fieldNames(f) {
+			if tag == "-,unknown" {
+				s.unknown = fname
+				continue
+			}
+			splited := strings.Split(tag, ",")
+			var id int
+			if id, err = strconv.Atoi(splited[0]); err != nil {
+				// not a thrift field, as dynamic does.
+				err = nil
+				continue
+			}
+			if id < -32768 || id > 32767 {
+				return s, fmt.Errorf("%s.%s: field identity %d out of range", name, fname, id)
+			}
+			if other, ok := seen[int16(id)]; ok {
+				return s, fmt.Errorf("%s.%s: field identity %d already used by %s", name, fname, id, other)
+			}
+			seen[int16(id)] = fname
+			h := new(hints)
+			required := false
+			for _, opt := range splited[1:] {
+				switch opt {
+				case "list", "set":
+					h.list = append(h.list, opt == "list")
+				case "required":
+					required = true
+				}
+			}
+			t := g.resolve(f.Type, h)
+			if t.kind == kindFallback {
+				return s, fmt.Errorf("%s.%s: type %s is not supported", name, fname, types.ExprString(f.Type))
+			}
+			s.fields = append(s.fields, field{fname, int16(id), required, t})
+		}
+	}
+	return
+}
+
+func (g *generator) resolve(e ast.Expr, h *hints) *typeInfo {
+	switch e := e.(type) {
+	case *ast.ParenExpr:
+		return g.resolve(e.X, h)
+	case *ast.Ident:
+		spec, ok := g.specs[e.Name]
+		if !ok {
+			if k, ok := builtinKinds[e.Name]; ok {
+				return &typeInfo{kind: k, expr: e.Name}
+			}
+			return fallback
+		}
+		if _, ok := spec.Type.(*ast.StructType); ok {
+			if g.generate[e.Name] {
+				return &typeInfo{kind: kindStruct, expr: e.Name}
+			}
+			return fallback
+		}
+		if g.visiting[e.Name] || spec.Assign.IsValid() {
+			return fallback
+		}
+		g.visiting[e.Name] = true
+		defer delete(g.visiting, e.Name)
+		t := g.resolve(spec.Type, h)
+		if t.kind == kindFallback {
+			return t
+		}
+		r := *t
+		r.expr = e.Name
+		r.named = true
+		return &r
+	case *ast.StarExpr:
+		if x, ok := e.X.(*ast.SelectorExpr); ok {
+			// struct of other package, which must implement thrift.TStruct.
+			return &typeInfo{kind: kindPtr, expr: types.ExprString(e), elem: &typeInfo{kind: kindStruct, expr: types.ExprString(x)}}
+		}
+		elem := g.resolve(e.X, h)
+		if elem.kind == kindFallback || elem.kind == kindPtr {
+			return fallback
+		}
+		return &typeInfo{kind: kindPtr, expr: types.ExprString(e), elem: elem}
+	case *ast.ArrayType:
+		if e.Len != nil {
+			return fallback
+		}
+		if i, ok := e.Elt.(*ast.Ident); ok && (i.Name == "byte" || i.Name == "uint8") && g.specs[i.Name] == nil {
+			return &typeInfo{kind: kindBinary, expr: types.ExprString(e)}
+		}
+		k := kindSet
+		if h.nextIsList() {
+			k = kindList
+		}
+		elem := g.resolve(e.Elt, h)
+		if elem.kind == kindFallback || elem.kind == kindUint8 && elem.expr != "byte" && elem.expr != "uint8" {
+			return fallback
+		}
+		return &typeInfo{kind: k, expr: types.ExprString(e), elem: elem}
+	case *ast.MapType:
+		key := g.resolve(e.Key, h)
+		elem := g.resolve(e.Value, h)
+		if key.kind == kindFallback || elem.kind == kindFallback {
+			return fallback
+		}
+		return &typeInfo{kind: kindMap, expr: types.ExprString(e), key: key, elem: elem}
+	}
+	return fallback
+}
+
+func (g *generator) p(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+	g.buf.WriteByte('\n')
+}
+
+func (g *generator) next(prefix string) string {
+	g.tmp++
+	return prefix + strconv.Itoa(g.tmp)
+}
+
+// check emits call that assigns err and returns on error.
+func (g *generator) check(call string) {
+	g.assign("err = " + call)
+}
+
+// assign emits stmt that assigns err and returns on error.
+func (g *generator) assign(stmt string) {
+	g.p("if %s; err != nil {\nreturn\n}", stmt)
+}
+
+// zeroCheck returns condition of writing field f of v, as dynamic does
+// zero optional fields and zero struct or pointer fields are omitted.
+func (g *generator) zeroCheck(f field) string {
+	x := "v." + f.name
+	switch f.typ.kind {
+	case kindStruct:
+		g.isZero[f.typ.expr] = true
+		return fmt.Sprintf("if !%s.thriftIsZero()", x)
+	case kindPtr:
+		return fmt.Sprintf("if %s != nil", x)
+	}
+	if f.required {
+		return ""
+	}
+	switch f.typ.kind {
+	case kindBool:
+		return "if " + x
+	case kindString:
+		return fmt.Sprintf("if %s != \"\"", x)
+	case kindBinary, kindList, kindSet, kindMap:
+		return fmt.Sprintf("if %s != nil", x)
+	}
+	return fmt.Sprintf("if %s != 0", x)
+}
+
+func (g *generator) writeMethod(s structInfo) {
+	g.tmp = 0
+	g.p("\n// Write writes v to p.")
+	g.p("func (v *%s) Write(p thrift.TProtocol) (err error) {", s.name)
+	canonical := s.canonical()
+	if canonical {
+		g.p("canonical := thrift.TConfigurationOf(p).IsCanonical()")
+	}
+	g.check("p.WriteStructBegin(thrift.TStructHeader{})")
+	if s.unknown != "" {
+		g.usesDynamic = true
+		g.p("unknown := v.%s", s.unknown)
+		g.p("if canonical {\nunknown = unknown.Sorted()\n}")
+	}
+	sorted := s.sorted()
+	inOrder := true
+	for i, f := range sorted {
+		inOrder = inOrder && f.identity == s.fields[i].identity
+	}
+	if inOrder {
+		g.writeFields(s, s.fields, s.unknown != "")
+	} else {
+		g.p("if canonical {")
+		g.writeFields(s, sorted, s.unknown != "")
+		g.p("} else {")
+		g.writeFields(s, s.fields, false)
+		g.p("}")
+	}
+	if s.unknown != "" {
+		g.p("for _, f := range unknown {")
+		g.check("f.Write(p)")
+		g.p("}")
+	}
+	g.check("p.WriteFieldStop()")
+	g.p("return p.WriteStructEnd()\n}")
+}
+
+// writeFields writes fields of v, unknown fields with lower identity are
+// written before each field on canonical output if merge is true.
+func (g *generator) writeFields(s structInfo, fields []field, merge bool) {
+	for _, f := range fields {
+		cond := g.zeroCheck(f)
+		if cond != "" {
+			g.p("%s {", cond)
+		}
+		if merge {
+			g.p("for canonical && len(unknown) != 0 && unknown[0].Identity < %d {", f.identity)
+			g.check("unknown[0].Write(p)")
+			g.p("unknown = unknown[1:]\n}")
+		}
+		g.check(fmt.Sprintf("p.WriteFieldBegin(thrift.TFieldHeader{Name: %q, Type: %s, Identity: %d})", f.name, f.typ.ttype(), f.identity))
+		g.writeValue(f.typ, "v."+f.name)
+		g.check("p.WriteFieldEnd()")
+		if cond != "" {
+			g.p("}")
+		}
+	}
+}
+
+func (g *generator) writeValue(t *typeInfo, x string) {
+	switch t.kind {
+	case kindStruct:
+		g.check(x + ".Write(p)")
+	case kindPtr:
+		if t.elem.kind == kindStruct {
+			g.check(x + ".Write(p)")
+		} else {
+			g.writeValue(t.elem, "(*"+x+")")
+		}
+	case kindList, kindSet:
+		header, end := "TListHeader", "WriteListEnd"
+		begin := "WriteListBegin"
+		if t.kind == kindSet {
+			header, begin, end = "TSetHeader", "WriteSetBegin", "WriteSetEnd"
+		}
+		g.check(fmt.Sprintf("p.%s(thrift.%s{Element: %s, Size: len(%s)})", begin, header, t.elem.ttype(), x))
+		if t.kind == kindSet {
+			g.usesDynamic = true
+			o, i := g.next("o"), g.next("i")
+			g.p("if canonical {\nvar %s []int", o)
+			g.p("if %s, err = dynamic.CanonicalOrder(len(%s), func(%s int, p thrift.TProtocol) (err error) {", o, x, i)
+			g.writeValue(t.elem, x+"["+i+"]")
+			g.p("return\n}); err != nil {\nreturn\n}")
+			g.p("for _, %s := range %s {", i, o)
+			g.writeValue(t.elem, x+"["+i+"]")
+			g.p("}\n} else {")
+		}
+		i := g.next("i")
+		g.p("for %s := range %s {", i, x)
+		g.writeValue(t.elem, x+"["+i+"]")
+		g.p("}")
+		if t.kind == kindSet {
+			g.p("}")
+		}
+		g.check("p." + end + "()")
+	case kindMap:
+		g.check(fmt.Sprintf("p.WriteMapBegin(thrift.TMapHeader{Key: %s, Value: %s, Size: len(%s)})", t.key.ttype(), t.elem.ttype(), x))
+		g.usesDynamic = true
+		keys, o, i, k, v := g.next("keys"), g.next("o"), g.next("i"), g.next("k"), g.next("v")
+		g.p("if canonical {\n%s := make([]%s, 0, len(%s))", keys, t.key.expr, x)
+		g.p("for %s := range %s {\n%s = append(%s, %s)\n}", k, x, keys, keys, k)
+		g.p("var %s []int", o)
+		g.p("if %s, err = dynamic.CanonicalOrder(len(%s), func(%s int, p thrift.TProtocol) (err error) {", o, keys, i)
+		g.writeValue(t.key, keys+"["+i+"]")
+		g.p("return\n}); err != nil {\nreturn\n}")
+		g.p("for _, %s := range %s {", i, o)
+		g.p("%s := %s[%s[%s]]", v, x, keys, i)
+		g.writeValue(t.key, keys+"["+i+"]")
+		g.writeValue(t.elem, v)
+		g.p("}\n} else {")
+		k, v = g.next("k"), g.next("v")
+		g.p("for %s, %s := range %s {", k, v, x)
+		g.writeValue(t.key, k)
+		g.writeValue(t.elem, v)
+		g.p("}\n}")
+		g.check("p.WriteMapEnd()")
+	default:
+		b := basics[t.kind]
+		if t.expr == b.raw {
+			g.check(fmt.Sprintf("p.%s(%s)", b.write, x))
+		} else {
+			g.check(fmt.Sprintf("p.%s(%s(%s))", b.write, b.raw, x))
+		}
+	}
+}
+
+func (g *generator) readMethod(s structInfo) {
+	g.tmp = 0
+	g.p("\n// Read reads v from p.")
+	g.p("func (v *%s) Read(p thrift.TProtocol) (err error) {", s.name)
+	g.assign("_, err = p.ReadStructBegin()")
+	if s.unknown != "" {
+		g.usesDynamic = true
+		g.p("var unknown dynamic.UnknownFields")
+		g.p("defer func() {\nv.%s = unknown\n}()", s.unknown)
+	}
+	g.p("var h thrift.TFieldHeader\nfor {")
+	g.assign("h, err = p.ReadFieldBegin()")
+	g.p("if h.Type == thrift.STOP {\nbreak\n}")
+	g.p("switch h.Identity {")
+	for _, f := range s.fields {
+		g.p("case %d:", f.identity)
+		g.p("if h.Type == %s {", f.typ.ttype())
+		g.readValue(f.typ, "v."+f.name)
+		if s.unknown != "" {
+			g.p("} else {")
+			g.readUnknown(s)
+			g.p("}")
+		} else {
+			g.p("} else if err = p.Skip(h.Type); err != nil {\nreturn\n}")
+		}
+	}
+	g.p("default:")
+	g.readUnknown(s)
+	g.p("}")
+	g.check("p.ReadFieldEnd()")
+	g.p("}")
+	g.p("return p.ReadStructEnd()\n}")
+}
+
+// readUnknown reads field h into unknown fields of s, or skips it.
+func (g *generator) readUnknown(s structInfo) {
+	if s.unknown != "" {
+		g.p("var f dynamic.UnknownField")
+		g.assign("f, err = dynamic.ReadUnknownField(h, p)")
+		g.p("unknown = append(unknown, f)")
+	} else {
+		g.check("p.Skip(h.Type)")
+	}
+}
+
+func (g *generator) readValue(t *typeInfo, x string) {
+	switch t.kind {
+	case kindStruct:
+		g.check(x + ".Read(p)")
+	case kindPtr:
+		g.p("%s = new(%s)", x, t.elem.expr)
+		if t.elem.kind == kindStruct {
+			g.check(x + ".Read(p)")
+		} else {
+			g.readValue(t.elem, "(*"+x+")")
+		}
+	case kindList, kindSet:
+		header, begin, end := "TListHeader", "ReadListBegin", "ReadListEnd"
+		if t.kind == kindSet {
+			header, begin, end = "TSetHeader", "ReadSetBegin", "ReadSetEnd"
+		}
+		h, i := g.next("h"), g.next("i")
+		g.p("var %s thrift.%s", h, header)
+		g.assign(fmt.Sprintf("%s, err = p.%s()", h, begin))
+		g.p("if %s.Element != %s {", h, t.elem.ttype())
+		g.p("for %s := 0; %s < %s.Size; %s++ {", i, i, h, i)
+		g.check(fmt.Sprintf("p.Skip(%s.Element)", h))
+		g.p("}\n} else {")
+		g.p("if %s.Size > len(%s) || %s == nil {\n%s = make(%s, %s.Size)\n} else {\n%s = %s[:%s.Size]\n}", h, x, x, x, t.expr, h, x, x, h)
+		g.p("for %s := 0; %s < %s.Size; %s++ {", i, i, h, i)
+		g.readValue(t.elem, x+"["+i+"]")
+		g.p("}\n}")
+		g.check("p." + end + "()")
+	case kindMap:
+		h, i, k, v := g.next("h"), g.next("i"), g.next("k"), g.next("v")
+		g.p("var %s thrift.TMapHeader", h)
+		g.assign(fmt.Sprintf("%s, err = p.ReadMapBegin()", h))
+		g.p("if %s.Key != %s || %s.Value != %s {", h, t.key.ttype(), h, t.elem.ttype())
+		g.p("for %s := 0; %s < %s.Size; %s++ {", i, i, h, i)
+		g.check(fmt.Sprintf("p.Skip(%s.Key)", h))
+		g.check(fmt.Sprintf("p.Skip(%s.Value)", h))
+		g.p("}\n} else {")
+		g.p("if %s == nil {\n%s = make(%s, %s.Size)\n}", x, x, t.expr, h)
+		g.p("for %s := 0; %s < %s.Size; %s++ {", i, i, h, i)
+		g.p("var %s %s", k, t.key.expr)
+		g.readValue(t.key, k)
+		g.p("var %s %s", v, t.elem.expr)
+		g.readValue(t.elem, v)
+		g.p("%s[%s] = %s", x, k, v)
+		g.p("}\n}")
+		g.check("p.ReadMapEnd()")
+	default:
+		b := basics[t.kind]
+		if t.expr == b.raw {
+			g.assign(fmt.Sprintf("%s, err = p.%s()", x, b.read))
+		} else {
+			r := g.next("r")
+			g.p("var %s %s", r, b.raw)
+			g.assign(fmt.Sprintf("%s, err = p.%s()", r, b.read))
+			g.p("%s = %s(%s)", x, t.expr, r)
+		}
+		if t.named && t.kind >= kindUint8 && t.kind <= kindI64 && t.kind != kindDouble {
+			g.usesDynamic = true
+			g.check(fmt.Sprintf("dynamic.CheckEnum(%s)", x))
+		}
+	}
+}
+
+// isZeroMethods emits thriftIsZero methods of structs used as struct fields,
+// which report whether every field is zero, as reflect.Value.IsZero does.
+func (g *generator) isZeroMethods() error {
+	done := make(map[string]bool)
+	for {
+		var names []string
+		for name := range g.isZero {
+			if !done[name] {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			return nil
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			done[name] = true
+			var conds []string
+			for _, f := range g.specs[name].Type.(*ast.StructType).Fields.List {
+				for _, fname := range fieldNames(f) {
+					if fname == "_" {
+						continue
+					}
+					cond, err := g.zeroExpr("v."+fname, f.Type)
+					if err != nil {
+						return fmt.Errorf("%s.%s: %w", name, fname, err)
+					}
+					conds = append(conds, cond)
+				}
+			}
+			if len(conds) == 0 {
+				conds = append(conds, "true")
+			}
+			g.p("\n// thriftIsZero reports whether every field of v is zero.")
+			g.p("func (v *%s) thriftIsZero() bool {\nreturn %s\n}", name, strings.Join(conds, " &&\n"))
+		}
+	}
+}
+
+// zeroExpr returns expression which reports whether x of type e is zero.
+func (g *generator) zeroExpr(x string, e ast.Expr) (string, error) {
+	switch e := e.(type) {
+	case *ast.ParenExpr:
+		return g.zeroExpr(x, e.X)
+	case *ast.Ident:
+		spec, ok := g.specs[e.Name]
+		if !ok {
+			switch k := builtinKinds[e.Name]; {
+			case k == kindBool:
+				return "!" + x, nil
+			case k == kindString:
+				return x + ` == ""`, nil
+			case k != kindFallback, e.Name == "uintptr", e.Name == "complex64", e.Name == "complex128":
+				return x + " == 0", nil
+			case e.Name == "error" || e.Name == "any":
+				return x + " == nil", nil
+			}
+			return "", fmt.Errorf("cannot check zero value of type %s", e.Name)
+		}
+		if _, ok := spec.Type.(*ast.StructType); ok {
+			if !g.generate[e.Name] {
+				return "", fmt.Errorf("struct %s must be generated too", e.Name)
+			}
+			g.isZero[e.Name] = true
+			return x + ".thriftIsZero()", nil
+		}
+		if g.visiting[e.Name] {
+			return "", fmt.Errorf("cannot check zero value of type %s", e.Name)
+		}
+		g.visiting[e.Name] = true
+		defer delete(g.visiting, e.Name)
+		return g.zeroExpr(x, spec.Type)
+	case *ast.StarExpr, *ast.MapType, *ast.FuncType, *ast.ChanType, *ast.InterfaceType:
+		return x + " == nil", nil
+	case *ast.ArrayType:
+		if e.Len == nil {
+			return x + " == nil", nil
+		}
+	}
+	return "", fmt.Errorf("cannot check zero value of type %s", types.ExprString(e))
+}
@@ -0,0 +1,58 @@
+// Command thrift-dynamic-gen generates reflection-free Read and Write methods
+// for Go types with `thrift:` tags, the generated methods have the same
+// semantics as dynamic.ValueEncoder, which prefers them over reflection,
+// see dynamic.GeneratedStruct.
+//
+// Usage:
+//
+//	thrift-dynamic-gen [-type T1,T2] [-output file] [dir]
+//
+// without -type, every struct type of the package in dir that has
+// at least one `thrift:` tagged field is generated.
+// fields must have basic types, generated structs, pointers of structs of
+// other packages which implement thrift.TStruct, or lists, sets and maps of them;
+// struct fields must have fields of which zero value can be checked.
+// other fields are reported as errors.
+// it is intended to be used with go:generate:
+//
+//	//go:generate thrift-dynamic-gen -type Foo,Bar
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const defaultOutput = "thrift_dynamic_gen.go"
+
+func main() {
+	typeNames := flag.String("type", "", "comma separated list of type names; default all tagged struct types")
+	output := flag.String("output", defaultOutput, "output file name, relative to dir")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: thrift-dynamic-gen [-type T1,T2] [-output file] [dir]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+	var types []string
+	if *typeNames != "" {
+		types = strings.Split(*typeNames, ",")
+	}
+	out := filepath.Join(dir, *output)
+	src, err := Generate(dir, types, filepath.Base(out))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "thrift-dynamic-gen:", err)
+		os.Exit(1)
+	}
+	if err = ioutil.WriteFile(out, src, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, "thrift-dynamic-gen:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerateUpToDate(t *testing.T) {
+	dir := filepath.Join("..", "..", "pkg", "dynamic", "internal", "gentest")
+	src, err := Generate(dir, []string{"Inner", "Bench", "Unordered"}, defaultOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := ioutil.ReadFile(filepath.Join(dir, defaultOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, old) {
+		t.Fatal("generated file is out of date, run go generate")
+	}
+}
+
+func TestGenerateError(t *testing.T) {
+	dir := filepath.Join("..", "..", "pkg", "dynamic", "internal", "gentest")
+	if _, err := Generate(dir, []string{"Missing"}, defaultOutput); err == nil {
+		t.Fatal("expected error for missing type")
+	}
+}
+
+func TestGenerateWithoutReflection(t *testing.T) {
+	dir := filepath.Join("..", "..", "pkg", "dynamic", "internal", "gentest")
+	src, err := Generate(dir, nil, defaultOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(src, []byte(`"reflect"`)) {
+		t.Fatal("generated code must not use reflection")
+	}
+}
+
+func TestGenerateUnsupported(t *testing.T) {
+	for name, src := range map[string]string{
+		"Array":     "type T struct {\n\tX [2]int32 `thrift:\"1\"`\n}",
+		"Foreign":   "type T struct {\n\tX time.Time `thrift:\"1\"`\n}",
+		"Interface": "type T struct {\n\tX interface{} `thrift:\"1\"`\n}",
+		"Zero":      "type T struct {\n\tX U `thrift:\"1\"`\n}\n\ntype U struct {\n\tY [2]int32\n\tZ int32 `thrift:\"1\"`\n}",
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			content := "package p\n\nimport \"time\"\n\nvar _ time.Time\n\n" + src + "\n"
+			if err := ioutil.WriteFile(filepath.Join(dir, "p.go"), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Generate(dir, nil, defaultOutput); err == nil {
+				t.Fatal("expected error for unsupported field")
+			}
+		})
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/b1avk/thrift/cmd/thrift-golden-gen
+
+go 1.23
+
+require (
+	github.com/apache/thrift v0.22.0
+	github.com/b1avk/thrift v0.0.0
+)
+
+replace github.com/b1avk/thrift => ../..
@@ -0,0 +1,326 @@
+// Command thrift-golden-gen regenerates golden wire-format files of
+// thrifttest.GoldenVectors with the Go library of Apache Thrift, so bytes of
+// this package are checked against bytes of a reference implementation.
+// it is a separate module, so package thrift does not depend on Apache Thrift.
+//
+// Usage:
+//
+//	thrift-golden-gen [-output dir]
+//
+// files are written to <dir>/<protocol>/<vector>.hex,
+// the default dir is the corpus of package thrift.
+// vectors with types which are not in Apache Thrift have no files.
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/b1avk/thrift/pkg/thrifttest"
+)
+
+const defaultOutput = "../../pkg/thrift/testdata/golden"
+
+var protocols = []struct {
+	name string
+	new  func(t thrift.TTransport) thrift.TProtocol
+}{
+	{"binary", func(t thrift.TTransport) thrift.TProtocol {
+		return thrift.NewTBinaryProtocolConf(t, &thrift.TConfiguration{TBinaryStrictWrite: thrift.BoolPtr(true)})
+	}},
+	{"compact", func(t thrift.TTransport) thrift.TProtocol {
+		return thrift.NewTCompactProtocolConf(t, nil)
+	}},
+	{"json", func(t thrift.TTransport) thrift.TProtocol {
+		return thrift.NewTJSONProtocol(t)
+	}},
+}
+
+func main() {
+	output := flag.String("output", defaultOutput, "output directory")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: thrift-golden-gen [-output dir]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if err := Generate(*output); err != nil {
+		fmt.Fprintln(os.Stderr, "thrift-golden-gen:", err)
+		os.Exit(1)
+	}
+}
+
+// Generate writes golden files of every vector and protocol to dir.
+func Generate(dir string) error {
+	for _, p := range protocols {
+		if err := os.MkdirAll(filepath.Join(dir, p.name), 0755); err != nil {
+			return err
+		}
+		for _, v := range vectors() {
+			b := thrift.NewTMemoryBuffer()
+			if err := v.write(context.Background(), p.new(b)); err != nil {
+				return fmt.Errorf("%s of %s: %w", v.name, p.name, err)
+			}
+			var out bytes.Buffer
+			comment := fmt.Sprintf("%s, %s protocol.\ngenerated by thrift-golden-gen with Apache Thrift, see README.md.", v.name, p.name)
+			if err := thrifttest.WriteGolden(&out, comment, b.Bytes()); err != nil {
+				return err
+			}
+			if err := ioutil.WriteFile(filepath.Join(dir, p.name, v.name+".hex"), out.Bytes(), 0644); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// vector is same as vector of thrifttest.GoldenVectors with same name.
+type vector struct {
+	name    string
+	message *message
+	value   structValue
+}
+
+type message struct {
+	name     string
+	typeID   thrift.TMessageType
+	identity int32
+}
+
+type field struct {
+	id    int16
+	value interface{}
+}
+
+type structValue []field
+
+type listValue struct {
+	elem   thrift.TType
+	values []interface{}
+}
+
+type setValue listValue
+
+type mapValue struct {
+	key, value thrift.TType
+	entries    [][2]interface{}
+}
+
+func vectors() []vector {
+	nan := math.Float64frombits(0x7ff8000000000000)
+	return []vector{
+		{
+			name:    "message_call",
+			message: &message{"ping", thrift.CALL, 1},
+			value:   structValue{},
+		},
+		{
+			name:    "message_reply",
+			message: &message{"add", thrift.REPLY, 42},
+			value:   structValue{{0, int32(3)}},
+		},
+		{
+			name:    "message_exception",
+			message: &message{"ping", thrift.EXCEPTION, -1},
+			value:   structValue{{1, "boom"}, {2, int32(thrift.INTERNAL_ERROR)}},
+		},
+		{
+			name:    "message_oneway",
+			message: &message{"log", thrift.ONEWAY, 1 << 20},
+			value:   structValue{{1, "hello"}},
+		},
+		{
+			name: "struct_all_types",
+			value: structValue{
+				{1, true},
+				{2, false},
+				{3, int8(-128)},
+				{4, int16(-2)},
+				{5, int32(100000)},
+				{6, int64(-1 << 40)},
+				{7, 1.5},
+				{8, "héllo"},
+				{9, []byte{0, 1, 0xfe, 0xff}},
+				{10, structValue{{1, int32(1)}}},
+				{11, &listValue{thrift.I32, []interface{}{int32(1), int32(2), int32(3)}}},
+				{12, &setValue{thrift.STRING, []interface{}{"a", "b"}}},
+				{13, &mapValue{thrift.STRING, thrift.I64, [][2]interface{}{{"x", int64(1)}}}},
+			},
+		},
+		{
+			name: "struct_field_ids",
+			value: structValue{
+				{1, true},
+				{16, int8(1)},
+				{17, false},
+				{300, int32(-300)},
+				{2, true},
+				{-1, "negative"},
+				{math.MaxInt16, false},
+				{math.MinInt16, int16(math.MinInt16)},
+			},
+		},
+		{
+			name: "struct_doubles",
+			value: structValue{
+				{1, 1.5},
+				{2, -0.25},
+				{3, math.Inf(1)},
+				{4, math.Inf(-1)},
+				{5, nan},
+			},
+		},
+		{
+			name: "nested_containers",
+			value: structValue{
+				{1, &listValue{thrift.MAP, []interface{}{
+					&mapValue{thrift.STRING, thrift.SET, [][2]interface{}{
+						{"k", &setValue{thrift.I16, []interface{}{int16(-1), int16(1)}}},
+					}},
+				}}},
+				{2, &mapValue{thrift.I32, thrift.LIST, [][2]interface{}{
+					{int32(7), &listValue{thrift.STRUCT, []interface{}{
+						structValue{{1, true}},
+						structValue{},
+					}}},
+				}}},
+				{3, &listValue{thrift.LIST, []interface{}{
+					&listValue{thrift.BOOL, []interface{}{true, false}},
+					&listValue{thrift.BOOL, nil},
+				}}},
+				{4, &listValue{thrift.BYTE, []interface{}{
+					int8(0), int8(1), int8(2), int8(3), int8(4), int8(5), int8(6), int8(7),
+					int8(8), int8(9), int8(10), int8(11), int8(12), int8(13), int8(14),
+				}}},
+				{5, &mapValue{thrift.STRING, thrift.STRING, nil}},
+				{6, &setValue{thrift.STRING, nil}},
+			},
+		},
+	}
+}
+
+// write writes v to p and flushes p.
+func (v vector) write(ctx context.Context, p thrift.TProtocol) (err error) {
+	if v.message != nil {
+		if err = p.WriteMessageBegin(ctx, v.message.name, v.message.typeID, v.message.identity); err != nil {
+			return
+		}
+	}
+	if err = writeValue(ctx, p, v.value); err != nil {
+		return
+	}
+	if v.message != nil {
+		if err = p.WriteMessageEnd(ctx); err != nil {
+			return
+		}
+	}
+	return p.Flush(ctx)
+}
+
+func typeOf(v interface{}) thrift.TType {
+	switch v.(type) {
+	case bool:
+		return thrift.BOOL
+	case int8:
+		return thrift.BYTE
+	case int16:
+		return thrift.I16
+	case int32:
+		return thrift.I32
+	case int64:
+		return thrift.I64
+	case float64:
+		return thrift.DOUBLE
+	case string, []byte:
+		return thrift.STRING
+	case structValue:
+		return thrift.STRUCT
+	case *listValue:
+		return thrift.LIST
+	case *setValue:
+		return thrift.SET
+	case *mapValue:
+		return thrift.MAP
+	}
+	panic(fmt.Sprintf("unexpected value %T", v))
+}
+
+func writeValue(ctx context.Context, p thrift.TProtocol, v interface{}) (err error) {
+	switch v := v.(type) {
+	case bool:
+		return p.WriteBool(ctx, v)
+	case int8:
+		return p.WriteByte(ctx, v)
+	case int16:
+		return p.WriteI16(ctx, v)
+	case int32:
+		return p.WriteI32(ctx, v)
+	case int64:
+		return p.WriteI64(ctx, v)
+	case float64:
+		return p.WriteDouble(ctx, v)
+	case string:
+		return p.WriteString(ctx, v)
+	case []byte:
+		return p.WriteBinary(ctx, v)
+	case structValue:
+		if err = p.WriteStructBegin(ctx, ""); err != nil {
+			return
+		}
+		for _, f := range v {
+			if err = p.WriteFieldBegin(ctx, "", typeOf(f.value), f.id); err != nil {
+				return
+			}
+			if err = writeValue(ctx, p, f.value); err != nil {
+				return
+			}
+			if err = p.WriteFieldEnd(ctx); err != nil {
+				return
+			}
+		}
+		if err = p.WriteFieldStop(ctx); err != nil {
+			return
+		}
+		return p.WriteStructEnd(ctx)
+	case *listValue:
+		if err = p.WriteListBegin(ctx, v.elem, len(v.values)); err != nil {
+			return
+		}
+		for _, e := range v.values {
+			if err = writeValue(ctx, p, e); err != nil {
+				return
+			}
+		}
+		return p.WriteListEnd(ctx)
+	case *setValue:
+		if err = p.WriteSetBegin(ctx, v.elem, len(v.values)); err != nil {
+			return
+		}
+		for _, e := range v.values {
+			if err = writeValue(ctx, p, e); err != nil {
+				return
+			}
+		}
+		return p.WriteSetEnd(ctx)
+	case *mapValue:
+		if err = p.WriteMapBegin(ctx, v.key, v.value, len(v.entries)); err != nil {
+			return
+		}
+		for _, e := range v.entries {
+			if err = writeValue(ctx, p, e[0]); err != nil {
+				return
+			}
+			if err = writeValue(ctx, p, e[1]); err != nil {
+				return
+			}
+		}
+		return p.WriteMapEnd(ctx)
+	}
+	panic(fmt.Sprintf("unexpected value %T", v))
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerateUpToDate(t *testing.T) {
+	dir := t.TempDir()
+	if err := Generate(dir); err != nil {
+		t.Fatal(err)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*", "*.hex"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range files {
+		rel, _ := filepath.Rel(dir, name)
+		src, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		old, err := ioutil.ReadFile(filepath.Join(defaultOutput, rel))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(src, old) {
+			t.Fatalf("golden file %s is out of date, run thrift-golden-gen and review the diff", rel)
+		}
+	}
+}
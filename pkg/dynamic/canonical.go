@@ -0,0 +1,64 @@
+package dynamic
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+
+	"github.com/b1avk/thrift/pkg/thrift"
+)
+
+// canonicalTConfiguration configuration of protocol which encodes sort keys.
+var canonicalTConfiguration = &thrift.TConfiguration{Canonical: true}
+
+func isCanonical(p thrift.TProtocol) bool {
+	return thrift.TConfigurationOf(p).IsCanonical()
+}
+
+// canonicalOrder returns indexes of n values sorted by their binary encoding by e.
+func canonicalOrder(e InternalEncoder, n int, at func(i int) reflect.Value) ([]int, error) {
+	return CanonicalOrder(n, func(i int, p thrift.TProtocol) error {
+		return e.Encode(at(i), p)
+	})
+}
+
+// CanonicalOrder returns indexes of n values sorted by their canonical binary
+// encoding, which write writes to p, as set elements and map entries are sorted
+// on canonical output. it is used by code generated by thrift-dynamic-gen.
+func CanonicalOrder(n int, write func(i int, p thrift.TProtocol) error) ([]int, error) {
+	keys := make([][]byte, n)
+	order := make([]int, n)
+	b := thrift.NewTMemoryBuffer()
+	p := thrift.NewTBinaryProtocol(b, canonicalTConfiguration)
+	for i := 0; i < n; i++ {
+		b.Reset()
+		if err := write(i, p); err != nil {
+			return nil, err
+		}
+		keys[i] = append([]byte(nil), b.Bytes()...)
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return bytes.Compare(keys[order[i]], keys[order[j]]) < 0
+	})
+	return order, nil
+}
+
+// sortedFields returns fields of e sorted by identity.
+func sortedFields(fields []*fieldEncoder) []*fieldEncoder {
+	r := append([]*fieldEncoder(nil), fields...)
+	sort.SliceStable(r, func(i, j int) bool {
+		return r[i].header.Identity < r[j].header.Identity
+	})
+	return r
+}
+
+// Sorted returns copy of u sorted by identity, as unknown fields are merged
+// in identity order on canonical output.
+func (u UnknownFields) Sorted() UnknownFields {
+	r := append(UnknownFields(nil), u...)
+	sort.SliceStable(r, func(i, j int) bool {
+		return r[i].Identity < r[j].Identity
+	})
+	return r
+}
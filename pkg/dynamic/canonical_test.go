@@ -0,0 +1,77 @@
+package dynamic_test
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/b1avk/thrift/pkg/dynamic"
+	"github.com/b1avk/thrift/pkg/thrift"
+)
+
+type CanonicalStruct struct {
+	Map     map[string]int32      `thrift:"3"`
+	Tags    []string              `thrift:"1,set"`
+	Set     map[int64]struct{}    `thrift:"2"`
+	Nested  map[int32][]string    `thrift:"5,list,set"`
+	Unknown dynamic.UnknownFields `thrift:"-,unknown"`
+}
+
+func newCanonicalStruct(reverse bool) CanonicalStruct {
+	v := CanonicalStruct{
+		Map:    make(map[string]int32),
+		Set:    make(map[int64]struct{}),
+		Nested: make(map[int32][]string),
+		Unknown: dynamic.UnknownFields{
+			{Type: thrift.BYTE, Identity: 6, Data: []byte{6}},
+			{Type: thrift.BYTE, Identity: 4, Data: []byte{4}},
+		},
+	}
+	for i := 0; i < 50; i++ {
+		v.Map[strconv.Itoa(i)] = int32(i)
+		v.Set[int64(i)] = struct{}{}
+		v.Nested[int32(i)] = []string{"b", "a", strconv.Itoa(i)}
+		v.Tags = append(v.Tags, strconv.Itoa(i))
+	}
+	if reverse {
+		for i, j := 0, len(v.Tags)-1; i < j; i, j = i+1, j-1 {
+			v.Tags[i], v.Tags[j] = v.Tags[j], v.Tags[i]
+		}
+	}
+	return v
+}
+
+func TestCanonical(t *testing.T) {
+	cfg := &thrift.TConfiguration{Canonical: true}
+	for name, newProtocol := range map[string]func(thrift.TTransport, *thrift.TConfiguration) thrift.TProtocol{
+		"Binary":  thrift.NewTBinaryProtocol,
+		"Compact": thrift.NewTCompactProtocol,
+	} {
+		t.Run(name, func(t *testing.T) {
+			var first []byte
+			for i := 0; i < 10; i++ {
+				v := newCanonicalStruct(i%2 == 1)
+				b := thrift.NewTMemoryBuffer()
+				p := newProtocol(b, cfg)
+				if err := dynamic.ValueEncoderOf(reflect.TypeOf(v)).Encode(v, p); err != nil {
+					t.Fatal(err)
+				}
+				if first == nil {
+					first = append([]byte(nil), b.Bytes()...)
+					var s dynamic.Struct
+					if err := s.Read(p); err != nil {
+						t.Fatal(err)
+					}
+					for j := 1; j < len(s.Fields); j++ {
+						if s.Fields[j-1].Identity >= s.Fields[j].Identity {
+							t.Fatal("fields must be in ascending identity order")
+						}
+					}
+				} else if !bytes.Equal(first, b.Bytes()) {
+					t.Fatal("canonical output must be identical")
+				}
+			}
+		})
+	}
+}
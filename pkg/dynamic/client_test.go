@@ -8,6 +8,7 @@ import (
 
 	"github.com/b1avk/thrift/pkg/dynamic"
 	"github.com/b1avk/thrift/pkg/thrift"
+	"github.com/b1avk/thrift/pkg/thrifttest"
 )
 
 type GreetArgs struct {
@@ -76,3 +77,19 @@ func TestDynamicGreeterServiceGreetCtxRetErr(t *testing.T) {
 		t.Fatal(`GreetCtxRetErr(ctx, "World") must returns ("Hello World !", nil)`)
 	}
 }
+
+func TestDynamicGreeterServiceHarness(t *testing.T) {
+	args := dynamic.NewTStruct(reflect.TypeOf(GreetArgs{}))
+	p := thrift.NewTStandardProcessor()
+	p.Handle("greet", func() thrift.TStruct {
+		return args.Copy()
+	}, func(ctx context.Context, a thrift.TStruct) (thrift.TStruct, error) {
+		av := a.(*dynamic.TStruct).Value().Interface().(GreetArgs)
+		return dynamic.TStructOf(&GreetResult{Text: fmt.Sprintf("Hello %s !", av.Name)}), nil
+	})
+	h := thrifttest.NewHarness(t, p, thrift.NewTCompactProtocolFactory(nil))
+	s := dynamic.WrapServiceClient(new(GreeterService), h.Client).(*GreeterService)
+	if res, err := s.GreetCtxRetErr(context.Background(), "World"); !(res == "Hello World !" && err == nil) {
+		t.Fatal(`GreetCtxRetErr(ctx, "World") must returns ("Hello World !", nil)`, res, err)
+	}
+}
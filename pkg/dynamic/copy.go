@@ -0,0 +1,150 @@
+package dynamic
+
+import (
+	"reflect"
+
+	"github.com/b1avk/thrift/pkg/thrift"
+)
+
+// DeepCopy returns deep copy of v, tagged fields, containers, pointers and
+// unknown fields are copied, untagged fields are copied shallowly.
+// types implementing thrift.TStruct are copied by encoding and decoding.
+func DeepCopy(v interface{}) (interface{}, error) {
+	x := reflect.ValueOf(v)
+	if !x.IsValid() {
+		return nil, nil
+	}
+	r := reflect.New(x.Type()).Elem()
+	if err := deepCopy(InternalEncoderOf(x.Type()), r, x); err != nil {
+		return nil, err
+	}
+	return r.Interface(), nil
+}
+
+// deepCopy copies src to dst, dst must be a zero value.
+func deepCopy(e InternalEncoder, dst, src reflect.Value) (err error) {
+	switch e := e.(type) {
+	case *enumEncoder:
+		return deepCopy(e.InternalEncoder, dst, src)
+	case *ptrEncoder:
+		if !src.IsNil() {
+			dst.Set(reflect.New(e.valueType))
+			err = deepCopy(e.InternalEncoder, dst.Elem(), src.Elem())
+		}
+	case *interfaceEncoder:
+		if !src.IsNil() {
+			t := src.Elem().Type()
+			x := reflect.New(t).Elem()
+			if err = deepCopy(InternalEncoderOf(t), x, src.Elem()); err == nil {
+				dst.Set(x)
+			}
+		}
+	case *structEncoder:
+		dst.Set(src)
+		if e.err != nil {
+			return e.err
+		}
+		for _, fe := range e.fields {
+			if s, d, ok := copyFieldOf(dst, src, fe.index); ok {
+				d.Set(reflect.Zero(d.Type()))
+				if err = deepCopy(fe.InternalEncoder, d, s); err != nil {
+					return
+				}
+			}
+		}
+		if e.unknownIndex != nil {
+			if s, d, ok := copyFieldOf(dst, src, e.unknownIndex); ok && !s.IsNil() {
+				u := s.Interface().(UnknownFields)
+				c := make(UnknownFields, len(u))
+				for i, f := range u {
+					c[i] = f
+					c[i].Data = append([]byte(nil), f.Data...)
+				}
+				d.Set(reflect.ValueOf(c))
+			}
+		}
+	case *tStructEncoder:
+		b := thrift.NewTMemoryBuffer()
+		p := thrift.NewTBinaryProtocol(b, nil)
+		if err = e.Encode(src, p); err == nil {
+			err = e.Decode(dst, p)
+		}
+	case *listEncoder:
+		err = copySlice(e.elementEncoder, dst, src)
+	case *setEncoder:
+		err = copySlice(e.elementEncoder, dst, src)
+	case *arrayEncoder:
+		for i := 0; i < src.Len(); i++ {
+			if err = deepCopy(e.elementEncoder, dst.Index(i), src.Index(i)); err != nil {
+				return
+			}
+		}
+	case *mapSetEncoder:
+		err = copyMap(e.keyEncoder, nil, dst, src)
+	case *mapEncoder:
+		err = copyMap(e.keyEncoder, e.valueEncoder, dst, src)
+	case *binaryEncoder:
+		if !src.IsNil() {
+			dst.Set(reflect.AppendSlice(reflect.MakeSlice(src.Type(), 0, src.Len()), src))
+		}
+	default:
+		dst.Set(src)
+	}
+	return
+}
+
+// copyFieldOf returns field index of src and dst, embedded pointers of dst
+// which are shared with src are replaced by copies.
+func copyFieldOf(dst, src reflect.Value, index []int) (reflect.Value, reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && src.Kind() == reflect.Ptr {
+			if src.IsNil() {
+				return src, dst, false
+			}
+			if dst.Pointer() == src.Pointer() {
+				dst.Set(reflect.New(src.Type().Elem()))
+				dst.Elem().Set(src.Elem())
+			}
+			src, dst = src.Elem(), dst.Elem()
+		}
+		src, dst = src.Field(x), dst.Field(x)
+	}
+	return src, dst, true
+}
+
+func copySlice(e InternalEncoder, dst, src reflect.Value) (err error) {
+	if src.IsNil() {
+		return
+	}
+	dst.Set(reflect.MakeSlice(src.Type(), src.Len(), src.Len()))
+	for i := 0; i < src.Len(); i++ {
+		if err = deepCopy(e, dst.Index(i), src.Index(i)); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// copyMap copies map src to dst, values are copied shallowly if valueEncoder is nil.
+func copyMap(keyEncoder, valueEncoder InternalEncoder, dst, src reflect.Value) (err error) {
+	if src.IsNil() {
+		return
+	}
+	t := src.Type()
+	dst.Set(reflect.MakeMapWithSize(t, src.Len()))
+	for iter := src.MapRange(); iter.Next(); {
+		k := reflect.New(t.Key()).Elem()
+		if err = deepCopy(keyEncoder, k, iter.Key()); err != nil {
+			return
+		}
+		v := iter.Value()
+		if valueEncoder != nil {
+			v = reflect.New(t.Elem()).Elem()
+			if err = deepCopy(valueEncoder, v, iter.Value()); err != nil {
+				return
+			}
+		}
+		dst.SetMapIndex(k, v)
+	}
+	return
+}
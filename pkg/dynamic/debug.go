@@ -0,0 +1,169 @@
+package dynamic
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Sprint formats v for debugging output.
+// fields of tagged structs are formatted by name,
+// registered enums by name and unknown fields by identity.
+func Sprint(v interface{}) string {
+	var b strings.Builder
+	sprint(&b, reflect.ValueOf(v))
+	return b.String()
+}
+
+func sprint(b *strings.Builder, v reflect.Value) {
+	if !v.IsValid() {
+		b.WriteString("nil")
+		return
+	}
+	if isIntegerKind(v.Kind()) {
+		if e := EnumOf(v.Type()); e != nil {
+			if n, ok := enumValueOf(v); ok {
+				b.WriteString(e.String(n))
+				return
+			}
+		}
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			b.WriteString("nil")
+		} else {
+			sprint(b, v.Elem())
+		}
+	case reflect.String:
+		b.WriteString(strconv.Quote(v.String()))
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			b.WriteString(strconv.Quote(string(v.Bytes())))
+			return
+		}
+		b.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			sprint(b, v.Index(i))
+		}
+		b.WriteByte(']')
+	case reflect.Map:
+		sprintMap(b, v)
+	case reflect.Struct:
+		sprintStruct(b, v)
+	default:
+		fmt.Fprint(b, v.Interface())
+	}
+}
+
+// sprintMap formats v with keys sorted by formatted text,
+// map[T]struct{} is formatted as set.
+func sprintMap(b *strings.Builder, v reflect.Value) {
+	set := isEmptyStruct(v.Type().Elem())
+	entries := make([][2]string, 0, v.Len())
+	for iter := v.MapRange(); iter.Next(); {
+		var k, e strings.Builder
+		sprint(&k, iter.Key())
+		if !set {
+			sprint(&e, iter.Value())
+		}
+		entries = append(entries, [2]string{k.String(), e.String()})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i][0] < entries[j][0]
+	})
+	b.WriteByte('{')
+	for i, e := range entries {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(e[0])
+		if !set {
+			b.WriteString(": ")
+			b.WriteString(e[1])
+		}
+	}
+	b.WriteByte('}')
+}
+
+func sprintStruct(b *strings.Builder, v reflect.Value) {
+	t := v.Type()
+	if !hasThriftTag(t) {
+		// untagged struct, such as time.Time.
+		fmt.Fprintf(b, "%+v", v.Interface())
+		return
+	}
+	b.WriteString(t.Name())
+	b.WriteByte('{')
+	n := 0
+	sprintFields(b, v, &n)
+	b.WriteByte('}')
+}
+
+// sprintFields formats tagged fields of v, n is number of formatted fields,
+// fields of untagged embedded structs are formatted as the ones of v.
+func sprintFields(b *strings.Builder, v reflect.Value, n *int) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, ok := f.Tag.Lookup("thrift")
+		if !ok {
+			if embeddedStructOf(f) != nil {
+				if x := v.Field(i); x.Kind() != reflect.Ptr {
+					sprintFields(b, x, n)
+				} else if !x.IsNil() {
+					sprintFields(b, x.Elem(), n)
+				}
+			}
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		unknown := isUnknownFieldsTag(tag)
+		if unknown && v.Field(i).Len() == 0 {
+			continue
+		}
+		if *n > 0 {
+			b.WriteString(", ")
+		}
+		*n++
+		if unknown {
+			sprintUnknown(b, v.Field(i).Interface().(UnknownFields))
+			continue
+		}
+		b.WriteString(f.Name)
+		b.WriteString(": ")
+		sprint(b, v.Field(i))
+	}
+}
+
+func hasThriftTag(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if _, ok := t.Field(i).Tag.Lookup("thrift"); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func sprintUnknown(b *strings.Builder, u UnknownFields) {
+	b.WriteString("Unknown: [")
+	for i, f := range u {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(b, "#%d: ", f.Identity)
+		if x, err := f.Value(); err == nil {
+			fmt.Fprint(b, ToInterface(x))
+		} else {
+			b.WriteString("?")
+		}
+	}
+	b.WriteByte(']')
+}
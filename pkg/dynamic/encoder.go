@@ -2,6 +2,7 @@ package dynamic
 
 import (
 	"fmt"
+	"math"
 	"reflect"
 	"sort"
 	"strconv"
@@ -56,8 +57,12 @@ func InternalEncoderOf(v reflect.Type) (e InternalEncoder) {
 }
 
 func internalEncoderOf(v reflect.Type, f *fieldTag) (e InternalEncoder) {
-	if e := getValueEncoderOf(v); e != nil {
-		return e.InternalEncoder
+	// encoder built with pending list or set hints is not shared by cache.
+	hinted := f != nil && f.nextListIndex < len(f.nextList)
+	if !hinted {
+		if e := getValueEncoderOf(v); e != nil {
+			return e.InternalEncoder
+		}
 	}
 	switch v.Kind() {
 	case reflect.Bool:
@@ -83,9 +88,30 @@ func internalEncoderOf(v reflect.Type, f *fieldTag) (e InternalEncoder) {
 	case reflect.String:
 		e = new(stringEncoder)
 	case reflect.Struct:
-		e = newStructEncoder(v)
+		if reflect.PtrTo(v).Implements(generatedStructType) {
+			e = &tStructEncoder{v}
+		} else {
+			e = newStructEncoder(v, &cache)
+		}
 	case reflect.Map:
-		e = newMapEncoder(v, f)
+		if isEmptyStruct(v.Elem()) {
+			e = &mapSetEncoder{
+				mapType:    v,
+				keyType:    v.Key(),
+				keyEncoder: internalEncoderOf(v.Key(), f),
+			}
+		} else {
+			e = newMapEncoder(v, f)
+		}
+	case reflect.Array:
+		list := f == nil || f.nextIsList()
+		e = &arrayEncoder{
+			arrayType:      v,
+			list:           list,
+			elementEncoder: internalEncoderOf(v.Elem(), f),
+		}
+	case reflect.Interface:
+		e = &interfaceEncoder{v}
 	case reflect.Slice:
 		if v.Elem().Kind() == reflect.Uint8 {
 			e = new(binaryEncoder)
@@ -109,9 +135,14 @@ func internalEncoderOf(v reflect.Type, f *fieldTag) (e InternalEncoder) {
 			InternalEncoder: internalEncoderOf(valueType, f),
 		}
 	default:
-		panic(fmt.Errorf("unexpected Type: %v", v.Kind()))
+		e = &unsupportedEncoder{fmt.Errorf("unsupported type: %v", v)}
+	}
+	if isIntegerKind(v.Kind()) && v.PkgPath() != "" {
+		e = &enumEncoder{v, e}
+	}
+	if !hinted {
+		cache.Store(v, e)
 	}
-	cache.Store(v, e)
 	return
 }
 
@@ -369,6 +400,8 @@ func (t *fieldTag) nextIsList() bool {
 type fieldEncoder struct {
 	header *thrift.TFieldHeader
 	tag    *fieldTag
+	// index of field, it has more than one element for fields of embedded struct.
+	index []int
 	InternalEncoder
 }
 
@@ -376,73 +409,197 @@ func (e *fieldEncoder) Header() thrift.TFieldHeader {
 	return *e.header
 }
 
+// valueOf returns field of struct v, it returns false if the field is not written,
+// zero optional fields and zero struct, pointer or interface fields are not written.
+func (e *fieldEncoder) valueOf(v reflect.Value) (reflect.Value, bool) {
+	f, ok := fieldOf(v, e.index, false)
+	if !ok || e.tag.optional && f.IsZero() {
+		return f, false
+	}
+	if k := f.Kind(); (k == reflect.Struct || k == reflect.Ptr || k == reflect.Interface) && f.IsZero() {
+		return f, false
+	}
+	return f, true
+}
+
 type structEncoder struct {
-	fieldEncoderLists    []int
-	fieldEncoderByIndex  map[int]fieldEncoder
-	fieldIndexByIdentity map[int16]int
+	fields          []*fieldEncoder
+	fieldByIdentity map[int16]*fieldEncoder
+	// sorted fields by identity for canonical output.
+	sorted       []*fieldEncoder
+	unknownIndex []int
+	err          error
+}
+
+// StructEncoderOf returns reflection based InternalEncoder of struct v,
+// unlike InternalEncoderOf it ignores Read and Write methods of v.
+func StructEncoderOf(v reflect.Type) InternalEncoder {
+	if e, ok := structCache.Load(v); ok {
+		return e.(InternalEncoder)
+	}
+	mustBe(v, reflect.Struct)
+	return newStructEncoder(v, &structCache)
 }
 
-func newStructEncoder(v reflect.Type) *structEncoder {
+var structCache sync.Map
+
+func newStructEncoder(v reflect.Type, cache *sync.Map) *structEncoder {
 	e := &structEncoder{
-		fieldEncoderByIndex:  make(map[int]fieldEncoder),
-		fieldIndexByIdentity: make(map[int16]int),
+		fieldByIdentity: make(map[int16]*fieldEncoder),
 	}
 	// prevent recursion on nested struct
 	cache.Store(v, e)
+	e.err = e.addFields(v, nil, make(map[reflect.Type]bool))
+	e.sorted = sortedFields(e.fields)
+	return e
+}
+
+// addFields adds fields of v with index prefix,
+// untagged embedded structs are flattened into e.
+func (e *structEncoder) addFields(v reflect.Type, prefix []int, visiting map[reflect.Type]bool) error {
+	if visiting[v] {
+		return fmt.Errorf("embedded struct %v is recursive", v)
+	}
+	visiting[v] = true
+	defer delete(visiting, v)
 	n := v.NumField()
 	for i := 0; i < n; i++ {
 		f := v.Field(i)
-		if t, err := parseFieldTag(f.Tag.Get("thrift")); err == nil {
+		index := append(append([]int(nil), prefix...), i)
+		tag, ok := f.Tag.Lookup("thrift")
+		if !ok {
+			if t := embeddedStructOf(f); t != nil {
+				if f.Type.Kind() == reflect.Ptr && f.PkgPath != "" {
+					return fmt.Errorf("embedded field %v must be exported", f.Name)
+				}
+				if err := e.addFields(t, index, visiting); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if isUnknownFieldsTag(tag) {
+			if f.Type != unknownFieldsType {
+				return fmt.Errorf("field %v must be UnknownFields", f.Name)
+			}
+			if e.unknownIndex != nil {
+				return fmt.Errorf("field %v: unknown fields already defined", f.Name)
+			}
+			e.unknownIndex = index
+			continue
+		}
+		if t, err := parseFieldTag(tag); err == nil {
+			if t.identity < math.MinInt16 || t.identity > math.MaxInt16 {
+				return fmt.Errorf("field %v: identity %v out of range", f.Name, t.identity)
+			}
 			fh := &thrift.TFieldHeader{
 				Name:     f.Name,
 				Identity: int16(t.identity),
 			}
-			fe := fieldEncoder{
+			fe := &fieldEncoder{
 				header:          fh,
 				tag:             &t,
+				index:           index,
 				InternalEncoder: internalEncoderOf(f.Type, &t),
 			}
+			if u, ok := fe.InternalEncoder.(*unsupportedEncoder); ok {
+				return fmt.Errorf("field %v: %w", f.Name, u.err)
+			}
 			fh.Type = fe.Kind()
-			if _, ok := e.fieldIndexByIdentity[fh.Identity]; ok {
-				panic(fmt.Errorf("field %v already defined", fh.Identity))
+			if other, ok := e.fieldByIdentity[fh.Identity]; ok {
+				return fmt.Errorf("field %v already defined by %v", fh.Identity, other.header.Name)
 			}
-			e.fieldEncoderLists = append(e.fieldEncoderLists, i)
-			e.fieldEncoderByIndex[i] = fe
-			e.fieldIndexByIdentity[fh.Identity] = i
+			e.fields = append(e.fields, fe)
+			e.fieldByIdentity[fh.Identity] = fe
 		}
 	}
-	return e
+	return nil
 }
 
+// embeddedStructOf returns struct type of embedded field f, or nil.
+func embeddedStructOf(f reflect.StructField) reflect.Type {
+	if !f.Anonymous {
+		return nil
+	}
+	t := f.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	return t
+}
+
+// fieldOf returns field of v by index, nil embedded pointers are allocated
+// if alloc is true, otherwise it returns false.
+func fieldOf(v reflect.Value, index []int, alloc bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !alloc {
+					return v, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// FieldHeader returns headers of fields by field index,
+// fields of embedded structs are not included.
 func (e *structEncoder) FieldHeader() map[int]thrift.TFieldHeader {
 	r := make(map[int]thrift.TFieldHeader)
-	for i, f := range e.fieldEncoderByIndex {
-		r[i] = *f.header
+	for _, f := range e.fields {
+		if len(f.index) == 1 {
+			r[f.index[0]] = *f.header
+		}
 	}
 	return r
 }
 
 func (e *structEncoder) Encode(v reflect.Value, p thrift.TProtocol) (err error) {
+	if e.err != nil {
+		return e.err
+	}
 	if err = p.WriteStructBegin(thrift.TStructHeader{}); err == nil {
-		for i := range e.fieldEncoderLists {
-			if fe, ok := e.fieldEncoderByIndex[i]; ok {
-				f := v.Field(i)
-				if fe.tag.optional && f.IsZero() {
-					continue
-				} else {
-					if (f.Kind() == reflect.Struct || f.Kind() == reflect.Ptr) && f.IsZero() {
-						continue
-					}
-					if err = p.WriteFieldBegin(*fe.header); err != nil {
-						return
-					}
-					if err = fe.Encode(f, p); err != nil {
-						return
-					}
-					if err = p.WriteFieldEnd(); err != nil {
-						return
-					}
+		fields := e.fields
+		var unknown UnknownFields
+		if e.unknownIndex != nil {
+			unknown = unknownOf(e, v)
+		}
+		canonical := isCanonical(p)
+		if canonical {
+			fields, unknown = e.sorted, unknown.Sorted()
+		}
+		for _, fe := range fields {
+			f, ok := fe.valueOf(v)
+			if !ok {
+				continue
+			}
+			// unknown fields are merged in identity order on canonical output.
+			for canonical && len(unknown) != 0 && unknown[0].Identity < fe.header.Identity {
+				if err = unknown[0].Write(p); err != nil {
+					return
 				}
+				unknown = unknown[1:]
+			}
+			if err = p.WriteFieldBegin(*fe.header); err != nil {
+				return
+			}
+			if err = fe.Encode(f, p); err != nil {
+				return
+			}
+			if err = p.WriteFieldEnd(); err != nil {
+				return
+			}
+		}
+		for _, f := range unknown {
+			if err = f.Write(p); err != nil {
+				return
 			}
 		}
 		if err = p.WriteFieldStop(); err == nil {
@@ -453,7 +610,21 @@ func (e *structEncoder) Encode(v reflect.Value, p thrift.TProtocol) (err error)
 }
 
 func (e *structEncoder) Decode(v reflect.Value, p thrift.TProtocol) (err error) {
+	if e.err != nil {
+		return e.err
+	}
 	if _, err = p.ReadStructBegin(); err == nil {
+		var unknown UnknownFields
+		if e.unknownIndex != nil {
+			defer func() {
+				if unknown != nil {
+					f, _ := fieldOf(v, e.unknownIndex, true)
+					f.Set(reflect.ValueOf(unknown))
+				} else if f, ok := fieldOf(v, e.unknownIndex, false); ok {
+					f.Set(reflect.ValueOf(unknown))
+				}
+			}()
+		}
 		var h thrift.TFieldHeader
 		for {
 			if h, err = p.ReadFieldBegin(); err != nil {
@@ -462,16 +633,26 @@ func (e *structEncoder) Decode(v reflect.Value, p thrift.TProtocol) (err error)
 			if h.Type == thrift.STOP {
 				break
 			}
-			if i, ok := e.fieldIndexByIdentity[h.Identity]; ok {
-				if f, ok := e.fieldEncoderByIndex[i]; ok && f.header.Type == h.Type {
-					if err = f.Decode(v.Field(i), p); err != nil {
-						return
-					}
-					if err = p.ReadFieldEnd(); err != nil {
-						return
-					}
-					continue
+			if f, ok := e.fieldByIdentity[h.Identity]; ok && f.header.Type == h.Type {
+				x, _ := fieldOf(v, f.index, true)
+				if err = f.Decode(x, p); err != nil {
+					return
 				}
+				if err = p.ReadFieldEnd(); err != nil {
+					return
+				}
+				continue
+			}
+			if e.unknownIndex != nil {
+				var f UnknownField
+				if f, err = ReadUnknownField(h, p); err != nil {
+					return
+				}
+				unknown = append(unknown, f)
+				if err = p.ReadFieldEnd(); err != nil {
+					return
+				}
+				continue
 			}
 			if err = p.Skip(h.Type); err != nil {
 				return
@@ -489,6 +670,41 @@ func (e *structEncoder) Kind() thrift.TType {
 	return thrift.STRUCT
 }
 
+// GeneratedStruct is implemented by pointer of struct with methods
+// generated by thrift-dynamic-gen, encoders use its Read and Write methods.
+// other implementations of thrift.TStruct are encoded by reflection,
+// since their methods may delegate to encoders of this package.
+type GeneratedStruct interface {
+	thrift.TStruct
+
+	// ThriftGenerated marks methods generated by thrift-dynamic-gen.
+	ThriftGenerated()
+}
+
+var generatedStructType = reflect.TypeOf((*GeneratedStruct)(nil)).Elem()
+
+// tStructEncoder an encoder of struct which implements GeneratedStruct.
+type tStructEncoder struct {
+	structType reflect.Type
+}
+
+func (e *tStructEncoder) Encode(v reflect.Value, p thrift.TProtocol) error {
+	if !v.CanAddr() {
+		r := reflect.New(e.structType)
+		r.Elem().Set(v)
+		v = r.Elem()
+	}
+	return v.Addr().Interface().(thrift.TStruct).Write(p)
+}
+
+func (e *tStructEncoder) Decode(v reflect.Value, p thrift.TProtocol) error {
+	return v.Addr().Interface().(thrift.TStruct).Read(p)
+}
+
+func (e *tStructEncoder) Kind() thrift.TType {
+	return thrift.STRUCT
+}
+
 type mapEncoder struct {
 	mapType, keyType, valueType reflect.Type
 	keyEncoder, valueEncoder    InternalEncoder
@@ -511,15 +727,31 @@ func (e *mapEncoder) Encode(v reflect.Value, p thrift.TProtocol) (err error) {
 		Size:  l,
 	}
 	if err = p.WriteMapBegin(h); err == nil {
-		for iter := v.MapRange(); iter.Next(); {
-			if err = e.keyEncoder.Encode(iter.Key(), p); err != nil {
+		if isCanonical(p) {
+			keys := v.MapKeys()
+			var order []int
+			if order, err = canonicalOrder(e.keyEncoder, l, func(i int) reflect.Value { return keys[i] }); err != nil {
 				return
 			}
-			if err = e.valueEncoder.Encode(iter.Value(), p); err != nil {
-				return
+			for _, i := range order {
+				if err = e.keyEncoder.Encode(keys[i], p); err != nil {
+					return
+				}
+				if err = e.valueEncoder.Encode(v.MapIndex(keys[i]), p); err != nil {
+					return
+				}
+			}
+		} else {
+			for iter := v.MapRange(); iter.Next(); {
+				if err = e.keyEncoder.Encode(iter.Key(), p); err != nil {
+					return
+				}
+				if err = e.valueEncoder.Encode(iter.Value(), p); err != nil {
+					return
+				}
 			}
 		}
-		err = p.WriteListEnd()
+		err = p.WriteMapEnd()
 	}
 	return
 }
@@ -561,6 +793,77 @@ func (e *mapEncoder) Kind() thrift.TType {
 	return thrift.MAP
 }
 
+func isEmptyStruct(v reflect.Type) bool {
+	return v.Kind() == reflect.Struct && v.NumField() == 0
+}
+
+// mapSetEncoder an encoder of map[T]struct{} as set,
+// duplicate elements are merged on decode.
+type mapSetEncoder struct {
+	mapType, keyType reflect.Type
+	keyEncoder       InternalEncoder
+}
+
+func (e *mapSetEncoder) Encode(v reflect.Value, p thrift.TProtocol) (err error) {
+	h := thrift.TSetHeader{
+		Element: e.keyEncoder.Kind(),
+		Size:    v.Len(),
+	}
+	if err = p.WriteSetBegin(h); err == nil {
+		if isCanonical(p) {
+			keys := v.MapKeys()
+			var order []int
+			if order, err = canonicalOrder(e.keyEncoder, len(keys), func(i int) reflect.Value { return keys[i] }); err != nil {
+				return
+			}
+			for _, i := range order {
+				if err = e.keyEncoder.Encode(keys[i], p); err != nil {
+					return
+				}
+			}
+		} else {
+			for iter := v.MapRange(); iter.Next(); {
+				if err = e.keyEncoder.Encode(iter.Key(), p); err != nil {
+					return
+				}
+			}
+		}
+		err = p.WriteSetEnd()
+	}
+	return
+}
+
+func (e *mapSetEncoder) Decode(v reflect.Value, p thrift.TProtocol) (err error) {
+	var h thrift.TSetHeader
+	if h, err = p.ReadSetBegin(); err == nil {
+		if h.Element != e.keyEncoder.Kind() {
+			for i := 0; i < h.Size; i++ {
+				if err = p.Skip(h.Element); err != nil {
+					return
+				}
+			}
+		} else {
+			if v.IsNil() {
+				v.Set(reflect.MakeMapWithSize(e.mapType, h.Size))
+			}
+			empty := reflect.New(e.mapType.Elem()).Elem()
+			for i := 0; i < h.Size; i++ {
+				key := reflect.New(e.keyType).Elem()
+				if err = e.keyEncoder.Decode(key, p); err != nil {
+					return
+				}
+				v.SetMapIndex(key, empty)
+			}
+		}
+		err = p.ReadSetEnd()
+	}
+	return
+}
+
+func (e *mapSetEncoder) Kind() thrift.TType {
+	return thrift.SET
+}
+
 type setEncoder struct {
 	sliceType      reflect.Type
 	elementEncoder InternalEncoder
@@ -573,8 +876,19 @@ func (e *setEncoder) Encode(v reflect.Value, p thrift.TProtocol) (err error) {
 		Size:    l,
 	}
 	if err = p.WriteSetBegin(h); err == nil {
+		var order []int
+		if isCanonical(p) {
+			if order, err = canonicalOrder(e.elementEncoder, l, v.Index); err != nil {
+				return
+			}
+		}
 		for i := 0; i < l; i++ {
-			if err = e.elementEncoder.Encode(v.Index(i), p); err != nil {
+			if order != nil {
+				err = e.elementEncoder.Encode(v.Index(order[i]), p)
+			} else {
+				err = e.elementEncoder.Encode(v.Index(i), p)
+			}
+			if err != nil {
 				return
 			}
 		}
@@ -595,6 +909,8 @@ func (e *setEncoder) Decode(v reflect.Value, p thrift.TProtocol) (err error) {
 		} else {
 			if h.Size > v.Len() || v.IsNil() {
 				v.Set(reflect.MakeSlice(e.sliceType, h.Size, h.Size))
+			} else {
+				v.Set(v.Slice(0, h.Size))
 			}
 			for i := 0; i < h.Size; i++ {
 				if err = e.elementEncoder.Decode(v.Index(i), p); err != nil {
@@ -645,6 +961,8 @@ func (e *listEncoder) Decode(v reflect.Value, p thrift.TProtocol) (err error) {
 		} else {
 			if h.Size > v.Len() || v.IsNil() {
 				v.Set(reflect.MakeSlice(e.sliceType, h.Size, h.Size))
+			} else {
+				v.Set(v.Slice(0, h.Size))
 			}
 			for i := 0; i < h.Size; i++ {
 				if err = e.elementEncoder.Decode(v.Index(i), p); err != nil {
@@ -661,6 +979,111 @@ func (e *listEncoder) Kind() thrift.TType {
 	return thrift.LIST
 }
 
+// arrayEncoder an encoder of fixed-size array as list or set,
+// decode fails if size mismatch length of array.
+type arrayEncoder struct {
+	arrayType      reflect.Type
+	list           bool
+	elementEncoder InternalEncoder
+}
+
+func (e *arrayEncoder) Encode(v reflect.Value, p thrift.TProtocol) (err error) {
+	l := v.Len()
+	if e.list {
+		err = p.WriteListBegin(thrift.TListHeader{Element: e.elementEncoder.Kind(), Size: l})
+	} else {
+		err = p.WriteSetBegin(thrift.TSetHeader{Element: e.elementEncoder.Kind(), Size: l})
+	}
+	if err == nil {
+		var order []int
+		if !e.list && isCanonical(p) {
+			if order, err = canonicalOrder(e.elementEncoder, l, v.Index); err != nil {
+				return
+			}
+		}
+		for i := 0; i < l; i++ {
+			if order != nil {
+				err = e.elementEncoder.Encode(v.Index(order[i]), p)
+			} else {
+				err = e.elementEncoder.Encode(v.Index(i), p)
+			}
+			if err != nil {
+				return
+			}
+		}
+		if e.list {
+			err = p.WriteListEnd()
+		} else {
+			err = p.WriteSetEnd()
+		}
+	}
+	return
+}
+
+func (e *arrayEncoder) Decode(v reflect.Value, p thrift.TProtocol) (err error) {
+	var element thrift.TType
+	var size int
+	if e.list {
+		var h thrift.TListHeader
+		h, err = p.ReadListBegin()
+		element, size = h.Element, h.Size
+	} else {
+		var h thrift.TSetHeader
+		h, err = p.ReadSetBegin()
+		element, size = h.Element, h.Size
+	}
+	if err != nil {
+		return
+	}
+	if element != e.elementEncoder.Kind() {
+		for i := 0; i < size; i++ {
+			if err = p.Skip(element); err != nil {
+				return
+			}
+		}
+	} else {
+		if size != v.Len() {
+			return thrift.NewTProtocolException(thrift.TProtocolErrorInvalidData,
+				fmt.Sprintf("size %v mismatch length of %v", size, e.arrayType))
+		}
+		for i := 0; i < size; i++ {
+			if err = e.elementEncoder.Decode(v.Index(i), p); err != nil {
+				return
+			}
+		}
+	}
+	if e.list {
+		err = p.ReadListEnd()
+	} else {
+		err = p.ReadSetEnd()
+	}
+	return
+}
+
+func (e *arrayEncoder) Kind() thrift.TType {
+	if e.list {
+		return thrift.LIST
+	}
+	return thrift.SET
+}
+
+// unsupportedEncoder an encoder of unsupported type, it returns err on use.
+type unsupportedEncoder struct {
+	err error
+}
+
+func (e *unsupportedEncoder) Encode(v reflect.Value, p thrift.TProtocol) error {
+	return e.err
+}
+
+func (e *unsupportedEncoder) Decode(v reflect.Value, p thrift.TProtocol) error {
+	return e.err
+}
+
+func (e *unsupportedEncoder) Kind() thrift.TType {
+	return thrift.STOP
+}
+
 type ptrEncoder struct {
 	valueType reflect.Type
 	InternalEncoder
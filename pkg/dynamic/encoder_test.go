@@ -1,6 +1,7 @@
 package dynamic_test
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 
@@ -185,3 +186,172 @@ func toPTR(s interface{}) interface{} {
 	r.Elem().Set(reflect.ValueOf(s))
 	return r.Interface()
 }
+
+type Figure interface {
+	Area() float64
+}
+
+type Rect struct {
+	Side float64 `thrift:"1"`
+}
+
+func (s Rect) Area() float64 { return s.Side * s.Side }
+
+type Round struct {
+	Radius float64 `thrift:"1"`
+}
+
+func (c *Round) Area() float64 { return 3 * c.Radius * c.Radius }
+
+type EmbeddedBase struct {
+	ID   int64  `thrift:"1"`
+	Name string `thrift:"2"`
+}
+
+type EmbeddedExtra struct {
+	Note string `thrift:"4"`
+}
+
+type ExtendedStruct struct {
+	EmbeddedBase
+	*EmbeddedExtra
+	Point   [2]int32 `thrift:"3"`
+	Figure  Figure   `thrift:"5"`
+	Figures []Figure `thrift:"6"`
+}
+
+type ConflictStruct struct {
+	EmbeddedBase
+	Other string `thrift:"2"`
+}
+
+type UnsupportedStruct struct {
+	Complex complex64 `thrift:"1"`
+}
+
+func init() {
+	dynamic.RegisterInterface((*Figure)(nil), map[int16]interface{}{
+		1: Rect{},
+		2: (*Round)(nil),
+	})
+}
+
+func testExtendedStruct(t *testing.T, getProtocol GetProtocol) {
+	v := ExtendedStruct{
+		EmbeddedBase:  EmbeddedBase{ID: 1, Name: "Hello"},
+		EmbeddedExtra: &EmbeddedExtra{Note: "World"},
+		Point:         [2]int32{3, 4},
+		Figure:        &Round{Radius: 2},
+		Figures:       []Figure{Rect{Side: 1}, &Round{Radius: 1}},
+	}
+	p := getProtocol()
+	e := dynamic.ValueEncoderOf(reflect.TypeOf(v))
+	if err := e.Encode(v, p); err != nil {
+		t.Fatal(err)
+	}
+	var r ExtendedStruct
+	if err := e.Decode(&r, p); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v, r) {
+		t.Fatal("value obtained for encode and decode mismatch")
+	}
+	var b struct {
+		ID   int64  `thrift:"1"`
+		Name string `thrift:"2"`
+		Note string `thrift:"4"`
+	}
+	if err := e.Encode(v, p); err != nil {
+		t.Fatal(err)
+	}
+	if err := dynamic.ValueEncoderOf(reflect.TypeOf(b)).Decode(&b, p); err != nil {
+		t.Fatal(err)
+	}
+	if b.ID != 1 || b.Name != "Hello" || b.Note != "World" {
+		t.Fatal("fields of embedded structs must be flattened")
+	}
+}
+
+func TestExtendedStructBinaryProtocol(t *testing.T) {
+	testExtendedStruct(t, func() thrift.TProtocol {
+		return thrift.NewTBinaryProtocol(thrift.NewTMemoryBuffer(), nil)
+	})
+}
+
+func TestExtendedStructCompactProtocol(t *testing.T) {
+	testExtendedStruct(t, func() thrift.TProtocol {
+		return thrift.NewTCompactProtocol(thrift.NewTMemoryBuffer(), nil)
+	})
+}
+
+func TestArraySizeMismatch(t *testing.T) {
+	p := thrift.NewTBinaryProtocol(thrift.NewTMemoryBuffer(), nil)
+	if err := dynamic.ValueEncoderOf(reflect.TypeOf([]int32{})).Encode([]int32{1, 2, 3}, p); err != nil {
+		t.Fatal(err)
+	}
+	var r [2]int32
+	if err := dynamic.ValueEncoderOf(reflect.TypeOf(r)).Decode(&r, p); err == nil {
+		t.Fatal("expected error for size mismatch")
+	}
+}
+
+func TestDecodeDepthLimit(t *testing.T) {
+	b := thrift.NewTMemoryBuffer()
+	for i := 0; i < 10000; i++ {
+		b.Write([]byte{thrift.STRUCT, 0, 8}) // Nested
+	}
+	b.Write(make([]byte, 10001))
+	var r BasicStruct
+	err := dynamic.ValueEncoderOf(reflect.TypeOf(r)).Decode(&r, thrift.NewTBinaryProtocol(b, nil))
+	var e *thrift.TProtocolException
+	if !errors.As(err, &e) || e.Kind() != thrift.TProtocolErrorDepthLimit {
+		t.Fatal("expected depth limit error, got", err)
+	}
+}
+
+func TestEncoderError(t *testing.T) {
+	p := thrift.NewTBinaryProtocol(thrift.NewTMemoryBuffer(), nil)
+	for _, v := range []interface{}{
+		ConflictStruct{},
+		UnsupportedStruct{},
+		complex128(1),
+		struct {
+			Value error `thrift:"1"`
+		}{errors.New("unregistered")},
+	} {
+		if err := dynamic.ValueEncoderOf(reflect.TypeOf(v)).Encode(v, p); err == nil {
+			t.Fatalf("expected error for %T", v)
+		}
+	}
+}
+
+// DelegatingStruct implements thrift.TStruct by hand with ValueEncoder of itself.
+type DelegatingStruct struct {
+	Name string `thrift:"1"`
+}
+
+var delegatingEncoder = dynamic.ValueEncoderOf(reflect.TypeOf(DelegatingStruct{}))
+
+func (v *DelegatingStruct) Write(p thrift.TProtocol) error {
+	return delegatingEncoder.Encode(*v, p)
+}
+
+func (v *DelegatingStruct) Read(p thrift.TProtocol) error {
+	return delegatingEncoder.Decode(v, p)
+}
+
+func TestDelegatingTStruct(t *testing.T) {
+	b := thrift.NewTMemoryBuffer()
+	p := thrift.NewTBinaryProtocol(b, nil)
+	v := &DelegatingStruct{Name: "Hello"}
+	if err := v.Write(p); err != nil {
+		t.Fatal(err)
+	}
+	var r DelegatingStruct
+	if err := dynamic.TStructOf(&r).Read(p); err != nil {
+		t.Fatal(err)
+	}
+	if r != *v {
+		t.Fatal("value mismatch", r)
+	}
+}
@@ -0,0 +1,206 @@
+package dynamic
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+
+	"github.com/b1avk/thrift/pkg/thrift"
+)
+
+// Enum value and name mapping of a registered enum type.
+type Enum struct {
+	typ    reflect.Type
+	strict bool
+	names  map[int32]string
+	values map[string]int32
+}
+
+var enums sync.Map
+
+// RegisterEnum registers names of enum type of v, v must be an integer.
+// decoding accepts values that are not in names.
+func RegisterEnum(v interface{}, names map[int32]string) *Enum {
+	return registerEnum(v, names, false)
+}
+
+// RegisterStrictEnum same as RegisterEnum but decoding rejects
+// values that are not in names.
+func RegisterStrictEnum(v interface{}, names map[int32]string) *Enum {
+	return registerEnum(v, names, true)
+}
+
+func registerEnum(v interface{}, names map[int32]string, strict bool) *Enum {
+	t := reflect.TypeOf(v)
+	if !isIntegerKind(t.Kind()) {
+		panic(fmt.Errorf("enum %v must be integer", t))
+	}
+	e := &Enum{
+		typ:    t,
+		strict: strict,
+		names:  make(map[int32]string, len(names)),
+		values: make(map[string]int32, len(names)),
+	}
+	for value, name := range names {
+		if _, ok := e.values[name]; ok {
+			panic(fmt.Errorf("enum %v: name %v already defined", t, name))
+		}
+		e.names[value] = name
+		e.values[name] = value
+	}
+	enums.Store(t, e)
+	return e
+}
+
+// EnumOf returns registered Enum of t, or nil if t is not registered.
+func EnumOf(t reflect.Type) *Enum {
+	if e, ok := enums.Load(t); ok {
+		return e.(*Enum)
+	}
+	return nil
+}
+
+// CheckEnum returns error if v is an unknown value of strict enum.
+func CheckEnum(v interface{}) error {
+	if e := EnumOf(reflect.TypeOf(v)); e != nil {
+		return e.check(reflect.ValueOf(v))
+	}
+	return nil
+}
+
+// Type returns enum type.
+func (e *Enum) Type() reflect.Type {
+	return e.typ
+}
+
+// Strict returns true if unknown values are rejected.
+func (e *Enum) Strict() bool {
+	return e.strict
+}
+
+// Name returns name of value.
+func (e *Enum) Name(value int32) (string, bool) {
+	name, ok := e.names[value]
+	return name, ok
+}
+
+// Value returns value of name.
+func (e *Enum) Value(name string) (int32, bool) {
+	value, ok := e.values[name]
+	return value, ok
+}
+
+// String returns name of value, or T(value) if value is unknown.
+func (e *Enum) String(value int32) string {
+	if name, ok := e.names[value]; ok {
+		return name
+	}
+	return e.typ.String() + "(" + strconv.FormatInt(int64(value), 10) + ")"
+}
+
+func (e *Enum) check(v reflect.Value) error {
+	if !e.strict {
+		return nil
+	}
+	if n, ok := enumValueOf(v); ok {
+		if _, ok := e.names[n]; ok {
+			return nil
+		}
+	}
+	return thrift.NewTProtocolException(thrift.TProtocolErrorInvalidData, fmt.Sprintf("unknown %v value %v", e.typ, v))
+}
+
+// enumValueOf returns v as int32, it returns false if v is out of range.
+func enumValueOf(v reflect.Value) (int32, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n := v.Int()
+		return int32(n), int64(int32(n)) == n
+	default:
+		n := v.Uint()
+		return int32(n), n <= 1<<31-1
+	}
+}
+
+func isIntegerKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// enumEncoder an encoder of named integer type,
+// it checks registered Enum of enumType on decode.
+// values of I16 and I32 enums are carried by name if p is thrift.TEnumProtocol
+// with thrift.TConfiguration.JSONEnumNames.
+type enumEncoder struct {
+	enumType reflect.Type
+	InternalEncoder
+}
+
+func (e *enumEncoder) Encode(v reflect.Value, p thrift.TProtocol) error {
+	if _, ok := p.(thrift.TEnumProtocol); ok && e.named() && thrift.TConfigurationOf(p).IsJSONEnumNames() {
+		if r := EnumOf(e.enumType); r != nil {
+			if n, ok := enumValueOf(v); ok {
+				if name, ok := r.Name(n); ok {
+					return p.WriteString(name)
+				}
+			}
+		}
+	}
+	return e.InternalEncoder.Encode(v, p)
+}
+
+func (e *enumEncoder) Decode(v reflect.Value, p thrift.TProtocol) (err error) {
+	r := EnumOf(e.enumType)
+	if ep, ok := p.(thrift.TEnumProtocol); ok && e.named() {
+		var n int32
+		var name string
+		if n, name, err = ep.ReadEnum(); err != nil {
+			return
+		}
+		if name != "" {
+			if r == nil {
+				return thrift.NewTProtocolException(thrift.TProtocolErrorInvalidData, fmt.Sprintf("%v is not a registered enum: %s", e.enumType, name))
+			}
+			if n, ok = r.Value(name); !ok {
+				return thrift.NewTProtocolException(thrift.TProtocolErrorInvalidData, fmt.Sprintf("unknown %v name %s", e.enumType, name))
+			}
+		}
+		if err = setEnumValue(v, n); err != nil {
+			return
+		}
+	} else if err = e.InternalEncoder.Decode(v, p); err != nil {
+		return
+	}
+	if r != nil {
+		err = r.check(v)
+	}
+	return
+}
+
+// named returns true if values of e may be carried by name.
+func (e *enumEncoder) named() bool {
+	k := e.Kind()
+	return k == thrift.I16 || k == thrift.I32
+}
+
+// setEnumValue sets n to integer v, it returns error if n overflows v.
+func setEnumValue(v reflect.Value, n int32) error {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if !v.OverflowInt(int64(n)) {
+			v.SetInt(int64(n))
+			return nil
+		}
+	default:
+		if n >= 0 && !v.OverflowUint(uint64(n)) {
+			v.SetUint(uint64(n))
+			return nil
+		}
+	}
+	return thrift.NewTProtocolException(thrift.TProtocolErrorInvalidData, fmt.Sprintf("%v value out of range: %d", v.Type(), n))
+}
@@ -0,0 +1,126 @@
+package dynamic_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/b1avk/thrift/pkg/dynamic"
+	"github.com/b1avk/thrift/pkg/thrift"
+)
+
+type Color int32
+
+type Shape int16
+
+type EnumStruct struct {
+	Color  Color               `thrift:"1"`
+	Shapes map[Shape]struct{}  `thrift:"2"`
+	Tags   map[string]struct{} `thrift:"3"`
+}
+
+func init() {
+	dynamic.RegisterStrictEnum(Color(0), map[int32]string{0: "RED", 1: "GREEN", 2: "BLUE"})
+	dynamic.RegisterEnum(Shape(0), map[int32]string{1: "CIRCLE", 2: "SQUARE"})
+}
+
+func TestEnumRegistry(t *testing.T) {
+	e := dynamic.EnumOf(reflect.TypeOf(Color(0)))
+	if e == nil || !e.Strict() {
+		t.Fatal("Color must be registered as strict")
+	}
+	if name, ok := e.Name(1); !ok || name != "GREEN" {
+		t.Fatal("name of 1 must be GREEN")
+	}
+	if value, ok := e.Value("BLUE"); !ok || value != 2 {
+		t.Fatal("value of BLUE must be 2")
+	}
+	if s := e.String(7); s != "dynamic_test.Color(7)" {
+		t.Fatalf("unexpected String of unknown value: %v", s)
+	}
+	if dynamic.CheckEnum(Color(3)) == nil {
+		t.Fatal("expected error for unknown Color")
+	}
+	if dynamic.CheckEnum(Shape(3)) != nil {
+		t.Fatal("unexpected error for unknown Shape")
+	}
+}
+
+func TestEnumDecode(t *testing.T) {
+	e := dynamic.ValueEncoderOf(reflect.TypeOf(EnumStruct{}))
+	p := thrift.NewTBinaryProtocol(thrift.NewTMemoryBuffer(), nil)
+	if err := e.Encode(EnumStruct{Color: 5, Shapes: map[Shape]struct{}{9: {}}}, p); err != nil {
+		t.Fatal(err)
+	}
+	var v EnumStruct
+	if err := e.Decode(&v, p); err == nil {
+		t.Fatal("expected error for unknown Color")
+	}
+}
+
+func TestMapSet(t *testing.T) {
+	e := dynamic.InternalEncoderOf(reflect.TypeOf(map[string]struct{}{}))
+	if e.Kind() != thrift.SET {
+		t.Fatal("map[string]struct{} must be SET")
+	}
+	p := thrift.NewTCompactProtocol(thrift.NewTMemoryBuffer(), nil)
+	if err := p.WriteSetBegin(thrift.TSetHeader{Element: thrift.STRING, Size: 3}); err != nil {
+		t.Fatal(err)
+	}
+	for _, x := range []string{"a", "b", "a"} {
+		if err := p.WriteString(x); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.WriteSetEnd(); err != nil {
+		t.Fatal(err)
+	}
+	var r map[string]struct{}
+	if err := dynamic.ValueEncoderOf(reflect.TypeOf(r)).Decode(&r, p); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r, map[string]struct{}{"a": {}, "b": {}}) {
+		t.Fatal("duplicate elements must be merged")
+	}
+}
+
+func TestSprint(t *testing.T) {
+	v := EnumStruct{
+		Color:  1,
+		Shapes: map[Shape]struct{}{2: {}, 1: {}, 5: {}},
+		Tags:   map[string]struct{}{"x": {}},
+	}
+	const expected = `EnumStruct{Color: GREEN, Shapes: {CIRCLE, SQUARE, dynamic_test.Shape(5)}, Tags: {"x"}}`
+	if s := dynamic.Sprint(&v); s != expected {
+		t.Fatalf("unexpected Sprint: %v", s)
+	}
+}
+
+func TestEnumJSONNames(t *testing.T) {
+	e := dynamic.ValueEncoderOf(reflect.TypeOf(EnumStruct{}))
+	v := EnumStruct{Color: 2, Shapes: map[Shape]struct{}{1: {}, 7: {}}}
+	for _, names := range []bool{false, true} {
+		b := thrift.NewTMemoryBuffer()
+		p := thrift.NewTJSONProtocol(b, &thrift.TConfiguration{JSONEnumNames: names})
+		if err := e.Encode(v, p); err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(b.String(), `"BLUE"`) != names || strings.Contains(b.String(), `"CIRCLE"`) != names {
+			t.Fatalf("names %v: unexpected encoding %s", names, b.String())
+		}
+		// names are always accepted on read.
+		var r EnumStruct
+		if err := e.Decode(&r, thrift.NewTJSONProtocol(b, nil)); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(r, v) {
+			t.Fatalf("names %v: expected %v, got %v", names, v, r)
+		}
+	}
+	b := thrift.NewTMemoryBuffer()
+	b.WriteString(`{"1":{"i32":"PURPLE"}}`)
+	var r EnumStruct
+	if err := e.Decode(&r, thrift.NewTJSONProtocol(b, nil)); err == nil {
+		t.Fatal("expected error for unknown Color name")
+	}
+}
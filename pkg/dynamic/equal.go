@@ -0,0 +1,283 @@
+package dynamic
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// Equal returns true if a and b have same type and are equal in thrift semantics:
+// fields that are not written are equal to absent fields, set elements
+// are compared unordered and doubles are compared by their bits.
+// types implementing thrift.TStruct are compared by canonical encoding.
+func Equal(a, b interface{}) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if !va.IsValid() || !vb.IsValid() {
+		return va.IsValid() == vb.IsValid()
+	}
+	if va.Type() != vb.Type() {
+		return false
+	}
+	return equal(InternalEncoderOf(va.Type()), va, vb)
+}
+
+func equal(e InternalEncoder, a, b reflect.Value) bool {
+	switch e := e.(type) {
+	case *enumEncoder:
+		return equal(e.InternalEncoder, a, b)
+	case *ptrEncoder:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() && b.IsNil()
+		}
+		return equal(e.InternalEncoder, a.Elem(), b.Elem())
+	case *interfaceEncoder:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() && b.IsNil()
+		}
+		if a.Elem().Type() != b.Elem().Type() {
+			return false
+		}
+		return equal(InternalEncoderOf(a.Elem().Type()), a.Elem(), b.Elem())
+	case *structEncoder:
+		if e.err != nil {
+			return reflect.DeepEqual(a.Interface(), b.Interface())
+		}
+		for _, fe := range e.fields {
+			fa, pa := fe.valueOf(a)
+			fb, pb := fe.valueOf(b)
+			if pa != pb || pa && !equal(fe.InternalEncoder, fa, fb) {
+				return false
+			}
+		}
+		return e.unknownIndex == nil || unknownEqual(unknownOf(e, a), unknownOf(e, b))
+	case *tStructEncoder:
+		ba, errA := canonicalBytes(a)
+		bb, errB := canonicalBytes(b)
+		return errA == nil && errB == nil && bytes.Equal(ba, bb)
+	case *listEncoder:
+		return orderedEqual(e.elementEncoder, a, b)
+	case *setEncoder:
+		return unorderedEqual(e.elementEncoder, a, b)
+	case *arrayEncoder:
+		if e.list {
+			return orderedEqual(e.elementEncoder, a, b)
+		}
+		return unorderedEqual(e.elementEncoder, a, b)
+	case *mapSetEncoder:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for iter := a.MapRange(); iter.Next(); {
+			if !b.MapIndex(iter.Key()).IsValid() {
+				return false
+			}
+		}
+		return true
+	case *mapEncoder:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for iter := a.MapRange(); iter.Next(); {
+			x := b.MapIndex(iter.Key())
+			if !x.IsValid() || !equal(e.valueEncoder, iter.Value(), x) {
+				return false
+			}
+		}
+		return true
+	case *binaryEncoder:
+		return bytes.Equal(a.Bytes(), b.Bytes())
+	}
+	switch a.Kind() {
+	case reflect.Bool:
+		return a.Bool() == b.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() == b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return a.Uint() == b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return math.Float64bits(a.Float()) == math.Float64bits(b.Float())
+	case reflect.String:
+		return a.String() == b.String()
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+func orderedEqual(e InternalEncoder, a, b reflect.Value) bool {
+	if a.Len() != b.Len() {
+		return false
+	}
+	for i := 0; i < a.Len(); i++ {
+		if !equal(e, a.Index(i), b.Index(i)) {
+			return false
+		}
+	}
+	return true
+}
+
+// unorderedEqual returns true if elements of a and b are equal as multiset.
+func unorderedEqual(e InternalEncoder, a, b reflect.Value) bool {
+	n := a.Len()
+	if n != b.Len() {
+		return false
+	}
+	used := make([]bool, n)
+next:
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if !used[j] && equal(e, a.Index(i), b.Index(j)) {
+				used[j] = true
+				continue next
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func unknownOf(e *structEncoder, v reflect.Value) UnknownFields {
+	if f, ok := fieldOf(v, e.unknownIndex, false); ok {
+		return f.Interface().(UnknownFields)
+	}
+	return nil
+}
+
+// unknownByIdentity returns unknown fields by identity, the last one wins.
+func unknownByIdentity(u UnknownFields) map[int16]UnknownField {
+	r := make(map[int16]UnknownField, len(u))
+	for _, f := range u {
+		r[f.Identity] = f
+	}
+	return r
+}
+
+func unknownEqual(a, b UnknownFields) bool {
+	ma, mb := unknownByIdentity(a), unknownByIdentity(b)
+	if len(ma) != len(mb) {
+		return false
+	}
+	for id, fa := range ma {
+		if fb, ok := mb[id]; !ok || fa.Type != fb.Type || !bytes.Equal(fa.Data, fb.Data) {
+			return false
+		}
+	}
+	return true
+}
+
+// FieldDiff a field changed between two values reported by Diff.
+type FieldDiff struct {
+	// Path Go field path such as "Nested.Name", unknown fields are named as "#3".
+	Path string
+
+	// Identity field identities from root struct to the field.
+	Identity []int16
+
+	// Old and New values of the field, it is nil if the field is not written.
+	Old, New interface{}
+}
+
+// Diff returns fields that differ between a and b, which must be structs
+// or pointers to structs of same type. nested structs are compared field by field.
+func Diff(a, b interface{}) ([]FieldDiff, error) {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if !va.IsValid() || !vb.IsValid() || va.Type() != vb.Type() {
+		return nil, fmt.Errorf("cannot diff %T and %T", a, b)
+	}
+	t := va.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		va, vb = derefOrZero(va), derefOrZero(vb)
+	}
+	e, ok := InternalEncoderOf(t).(*structEncoder)
+	if !ok {
+		return nil, fmt.Errorf("cannot diff %v, it must be tagged struct", t)
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	var r []FieldDiff
+	diffStruct(e, va, vb, "", nil, &r)
+	return r, nil
+}
+
+func derefOrZero(v reflect.Value) reflect.Value {
+	if v.IsNil() {
+		return reflect.Zero(v.Type().Elem())
+	}
+	return v.Elem()
+}
+
+func diffStruct(e *structEncoder, a, b reflect.Value, path string, ids []int16, r *[]FieldDiff) {
+	for _, fe := range e.fields {
+		fa, pa := fe.valueOf(a)
+		fb, pb := fe.valueOf(b)
+		if !pa && !pb {
+			continue
+		}
+		fpath := path + fe.header.Name
+		fids := append(append([]int16(nil), ids...), fe.header.Identity)
+		if pa && pb {
+			if s, ok := nestedStructEncoder(fe.InternalEncoder); ok && s.err == nil {
+				if fa.Kind() == reflect.Ptr {
+					fa, fb = fa.Elem(), fb.Elem()
+				}
+				diffStruct(s, fa, fb, fpath+".", fids, r)
+				continue
+			}
+			if equal(fe.InternalEncoder, fa, fb) {
+				continue
+			}
+		}
+		d := FieldDiff{Path: fpath, Identity: fids}
+		if pa {
+			d.Old = fa.Interface()
+		}
+		if pb {
+			d.New = fb.Interface()
+		}
+		*r = append(*r, d)
+	}
+	if e.unknownIndex == nil {
+		return
+	}
+	ma, mb := unknownByIdentity(unknownOf(e, a)), unknownByIdentity(unknownOf(e, b))
+	ua := make([]int, 0, len(ma)+len(mb))
+	for id := range ma {
+		ua = append(ua, int(id))
+	}
+	for id := range mb {
+		if _, ok := ma[id]; !ok {
+			ua = append(ua, int(id))
+		}
+	}
+	sort.Ints(ua)
+	for _, id := range ua {
+		fa, pa := ma[int16(id)]
+		fb, pb := mb[int16(id)]
+		if pa && pb && fa.Type == fb.Type && bytes.Equal(fa.Data, fb.Data) {
+			continue
+		}
+		d := FieldDiff{
+			Path:     path + "#" + strconv.Itoa(id),
+			Identity: append(append([]int16(nil), ids...), int16(id)),
+		}
+		if pa {
+			d.Old = fa
+		}
+		if pb {
+			d.New = fb
+		}
+		*r = append(*r, d)
+	}
+}
+
+// nestedStructEncoder returns structEncoder of struct or pointer to struct encoder e.
+func nestedStructEncoder(e InternalEncoder) (*structEncoder, bool) {
+	if p, ok := e.(*ptrEncoder); ok {
+		e = p.InternalEncoder
+	}
+	s, ok := e.(*structEncoder)
+	return s, ok
+}
@@ -0,0 +1,125 @@
+package dynamic_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/b1avk/thrift/pkg/dynamic"
+)
+
+type EqualStruct struct {
+	Name     string                `thrift:"1"`
+	Tags     []string              `thrift:"2,set"`
+	Optional []int32               `thrift:"3"`
+	List     []int32               `thrift:"4,required"`
+	Nested   *EqualStruct          `thrift:"5"`
+	Set      map[string]struct{}   `thrift:"6"`
+	Map      map[int32]string      `thrift:"7"`
+	Figure   Figure                `thrift:"8"`
+	Unknown  dynamic.UnknownFields `thrift:"-,unknown"`
+}
+
+func newEqualStruct() *EqualStruct {
+	return &EqualStruct{
+		Name:   "Hello",
+		Tags:   []string{"a", "b", "c"},
+		List:   []int32{1, 2},
+		Nested: &EqualStruct{Name: "World", Map: map[int32]string{1: "x"}},
+		Set:    map[string]struct{}{"x": {}, "y": {}},
+		Map:    map[int32]string{1: "a", 2: "b", 3: "c"},
+		Figure: &Round{Radius: 1},
+	}
+}
+
+func TestEqual(t *testing.T) {
+	a, b := newEqualStruct(), newEqualStruct()
+	b.Tags = []string{"c", "a", "b"}
+	b.List = []int32{}
+	a.List = nil
+	if !dynamic.Equal(a, b) {
+		t.Fatal("sets must be compared unordered and nil required list must equal empty list")
+	}
+	b.Optional = []int32{}
+	if dynamic.Equal(a, b) {
+		t.Fatal("empty optional list must not equal absent list")
+	}
+	b.Optional = nil
+	b.Tags = []string{"a", "a", "b"}
+	if dynamic.Equal(a, b) {
+		t.Fatal("sets with different elements must not be equal")
+	}
+	if dynamic.Equal(a, *b) {
+		t.Fatal("values of different types must not be equal")
+	}
+}
+
+func TestDeepCopy(t *testing.T) {
+	a := newEqualStruct()
+	a.Unknown = dynamic.UnknownFields{{Identity: 9, Data: []byte{1}}}
+	r, err := dynamic.DeepCopy(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := r.(*EqualStruct)
+	if !reflect.DeepEqual(a, b) || !dynamic.Equal(a, b) {
+		t.Fatal("copy must be equal to original")
+	}
+	b.Nested.Map[1] = "y"
+	b.Tags[0] = "z"
+	b.Unknown[0].Data[0] = 2
+	b.Figure.(*Round).Radius = 2
+	if a.Nested.Map[1] != "x" || a.Tags[0] != "a" || a.Unknown[0].Data[0] != 1 || a.Figure.(*Round).Radius != 1 {
+		t.Fatal("copy must not share memory with original")
+	}
+}
+
+func TestHash(t *testing.T) {
+	a, b := newEqualStruct(), newEqualStruct()
+	b.Tags = []string{"b", "c", "a"}
+	ha, err := dynamic.Hash(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		hb, err := dynamic.Hash(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ha != hb {
+			t.Fatal("hash of equal values must be same")
+		}
+	}
+	b.Nested.Name = "Mars"
+	if hb, _ := dynamic.Hash(b); ha == hb {
+		t.Fatal("hash of different values must differ")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a, b := newEqualStruct(), newEqualStruct()
+	b.Nested.Name = "Mars"
+	b.Map[4] = "d"
+	b.Figure = nil
+	b.Unknown = dynamic.UnknownFields{{Identity: 9, Data: []byte{1}}}
+	d, err := dynamic.Diff(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, f := range d {
+		paths = append(paths, f.Path)
+	}
+	expected := []string{"Nested.Name", "Map", "Figure", "#9"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Fatalf("unexpected paths: %v", paths)
+	}
+	if !reflect.DeepEqual(d[0].Identity, []int16{5, 1}) || d[0].Old != "World" || d[0].New != "Mars" {
+		t.Fatalf("unexpected diff: %+v", d[0])
+	}
+	if d[2].New != nil || d[3].Old != nil {
+		t.Fatal("absent field must be nil")
+	}
+	if _, err = dynamic.Diff(a, *b); err == nil {
+		t.Fatal("expected error for different types")
+	}
+}
@@ -0,0 +1,70 @@
+//go:build go1.18
+// +build go1.18
+
+package dynamic_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/b1avk/thrift/pkg/dynamic"
+	"github.com/b1avk/thrift/pkg/thrift"
+)
+
+type FuzzStruct struct {
+	Name     string                `thrift:"1"`
+	Data     []byte                `thrift:"2"`
+	List     []*FuzzStruct         `thrift:"3"`
+	Map      map[string][]int64    `thrift:"4"`
+	Set      map[int32]struct{}    `thrift:"5"`
+	Nested   *FuzzStruct           `thrift:"6"`
+	Tags     []string              `thrift:"7,set"`
+	Double   float64               `thrift:"8"`
+	Unsigned uint64                `thrift:"9"`
+	Unknown  dynamic.UnknownFields `thrift:"-,unknown"`
+}
+
+var fuzzProtocolFactories = []thrift.TProtocolFactory{
+	thrift.NewTBinaryProtocolFactory(nil),
+	thrift.NewTCompactProtocolFactory(nil),
+	thrift.NewTJSONProtocolFactory(nil),
+}
+
+// FuzzDecode decodes untrusted bytes to FuzzStruct, first byte selects protocol.
+func FuzzDecode(f *testing.F) {
+	seeds := []FuzzStruct{
+		{},
+		{Name: "Hello", Data: []byte{0, 0xff}, Double: 0.5, Unsigned: 1 << 63},
+		{
+			List:   []*FuzzStruct{{Name: "a"}, {Tags: []string{"x", "y"}}},
+			Map:    map[string][]int64{"k": {1, -1}},
+			Set:    map[int32]struct{}{7: {}},
+			Nested: &FuzzStruct{Nested: &FuzzStruct{Name: "deep"}},
+		},
+	}
+	e := dynamic.ValueEncoderOf(reflect.TypeOf(FuzzStruct{}))
+	for i, pf := range fuzzProtocolFactories {
+		for _, v := range seeds {
+			b := thrift.NewTMemoryBuffer()
+			if err := e.Encode(v, pf.GetProtocol(b)); err != nil {
+				f.Fatal(err)
+			}
+			f.Add(append([]byte{byte(i)}, b.Bytes()...))
+		}
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		if len(b) == 0 {
+			return
+		}
+		pf := fuzzProtocolFactories[int(b[0])%len(fuzzProtocolFactories)]
+		in := thrift.NewTMemoryBuffer()
+		in.Write(b[1:])
+		var v FuzzStruct
+		if err := e.Decode(&v, pf.GetProtocol(in)); err != nil {
+			return
+		}
+		if err := e.Encode(v, pf.GetProtocol(thrift.NewTMemoryBuffer())); err != nil {
+			t.Fatal("fail to encode decoded value:", err)
+		}
+	})
+}
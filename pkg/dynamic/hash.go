@@ -0,0 +1,28 @@
+package dynamic
+
+import (
+	"hash/fnv"
+	"reflect"
+
+	"github.com/b1avk/thrift/pkg/thrift"
+)
+
+// Hash returns stable 64-bit FNV-1a hash of canonical binary encoding of v.
+func Hash(v interface{}) (uint64, error) {
+	b, err := canonicalBytes(reflect.ValueOf(v))
+	if err != nil {
+		return 0, err
+	}
+	h := fnv.New64a()
+	h.Write(b)
+	return h.Sum64(), nil
+}
+
+// canonicalBytes returns canonical binary encoding of v.
+func canonicalBytes(v reflect.Value) ([]byte, error) {
+	b := thrift.NewTMemoryBuffer()
+	if err := InternalEncoderOf(v.Type()).Encode(v, thrift.NewTBinaryProtocol(b, canonicalTConfiguration)); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
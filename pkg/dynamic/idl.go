@@ -0,0 +1,622 @@
+package dynamic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+
+	"github.com/b1avk/thrift/pkg/idl"
+	"github.com/b1avk/thrift/pkg/thrift"
+)
+
+// IDLClient a dynamic client of service defined in idl.Document.
+//
+// values are exchanged as Go values:
+// structs, unions and exceptions are map[string]interface{} keyed by field name,
+// enums are their name (number is also accepted on write),
+// lists and sets are []interface{} (any slice is accepted on write),
+// maps are map[string]interface{} for string and binary keys,
+// []IDLMapEntry for list, set, map, struct, union and exception keys,
+// or map[interface{}]interface{} otherwise,
+// binary is []byte (string is also accepted on write) and the rest are their Go primitive.
+type IDLClient struct {
+	client    thrift.TClient
+	functions map[string]idlFunction
+}
+
+type idlFunction struct {
+	doc *idl.Document
+	*idl.Function
+}
+
+// IDLMapEntry an entry of map of which keys are not hashable in Go.
+type IDLMapEntry struct {
+	Key, Value interface{}
+}
+
+// IDLException a declared exception returned by IDLClient.Invoke.
+type IDLException struct {
+	// Name is name of exception type.
+	Name string
+	// Field is name of throws field.
+	Field  string
+	Fields map[string]interface{}
+}
+
+// Error returns error message.
+func (e *IDLException) Error() string {
+	return fmt.Sprintf("%s: %v", e.Name, e.Fields)
+}
+
+// NewIDLClient returns new IDLClient of service in doc,
+// it returns error if a function uses a type that has no thrift.TType, such as uuid.
+func NewIDLClient(doc *idl.Document, service string, c thrift.TClient) (*IDLClient, error) {
+	ic := &IDLClient{c, make(map[string]idlFunction)}
+	for name := service; name != ""; {
+		d, _ := doc.Lookup(name)
+		s := doc.Service(name)
+		if s == nil {
+			return nil, fmt.Errorf("dynamic: service %s not found", name)
+		}
+		for _, f := range s.Functions {
+			if _, ok := ic.functions[f.Name]; !ok {
+				if err := checkIDLFunction(d, f); err != nil {
+					return nil, fmt.Errorf("dynamic: %s.%s: %w", s.Name, f.Name, err)
+				}
+				ic.functions[f.Name] = idlFunction{d, f}
+			}
+		}
+		doc, name = d, s.Extends
+	}
+	return ic, nil
+}
+
+// Invoke calls method with args keyed by argument name.
+// it returns result keyed by "success" for non-void method
+// or *IDLException if a declared exception is thrown.
+func (c *IDLClient) Invoke(ctx context.Context, method string, args map[string]interface{}) (map[string]interface{}, error) {
+	f, ok := c.functions[method]
+	if !ok {
+		return nil, fmt.Errorf("dynamic: method %s not found", method)
+	}
+	for name := range args {
+		if findIDLField(f.Arguments, name) == nil {
+			return nil, fmt.Errorf("dynamic: %s has no argument %s", method, name)
+		}
+	}
+	a := &idlStruct{f.doc, f.Arguments, args}
+	if f.Oneway {
+		return nil, c.client.Call(ctx, method, a, nil)
+	}
+	resultFields := f.Exceptions
+	if f.ReturnType != nil {
+		resultFields = append([]*idl.Field{{Name: "success", Type: f.ReturnType}}, resultFields...)
+	}
+	r := &idlStruct{f.doc, resultFields, nil}
+	if err := c.client.Call(ctx, method, a, r); err != nil {
+		return nil, err
+	}
+	for _, e := range f.Exceptions {
+		if v, ok := r.values[e.Name]; ok {
+			return nil, &IDLException{e.Type.Name, e.Name, v.(map[string]interface{})}
+		}
+	}
+	return r.values, nil
+}
+
+// checkIDLFunction returns error if f uses a type that has no thrift.TType, such as uuid.
+func checkIDLFunction(doc *idl.Document, f *idl.Function) error {
+	seen := make(map[*idl.Struct]bool)
+	if f.ReturnType != nil {
+		if err := checkIDLType(doc, f.ReturnType, seen); err != nil {
+			return err
+		}
+	}
+	for _, fields := range [][]*idl.Field{f.Arguments, f.Exceptions} {
+		for _, a := range fields {
+			if err := checkIDLType(doc, a.Type, seen); err != nil {
+				return fmt.Errorf("field %s: %w", a.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func checkIDLType(doc *idl.Document, t *idl.Type, seen map[*idl.Struct]bool) error {
+	if _, err := idlTTypeOf(doc, t); err != nil {
+		return err
+	}
+	doc, t = doc.Resolve(t)
+	for _, x := range []*idl.Type{t.KeyType, t.ValType} {
+		if x == nil {
+			continue
+		}
+		if err := checkIDLType(doc, x, seen); err != nil {
+			return err
+		}
+	}
+	if s := doc.Struct(t.Name); s != nil && !seen[s] {
+		seen[s] = true
+		d, _ := doc.Lookup(t.Name)
+		for _, f := range s.Fields {
+			if err := checkIDLType(d, f.Type, seen); err != nil {
+				return fmt.Errorf("%s.%s: %w", t.Name, f.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func findIDLField(fields []*idl.Field, name string) *idl.Field {
+	for _, f := range fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// idlStruct an implementation of thrift.TStruct driven by idl.Field.
+type idlStruct struct {
+	doc    *idl.Document
+	fields []*idl.Field
+	values map[string]interface{}
+}
+
+func (s *idlStruct) Write(p thrift.TProtocol) (err error) {
+	if err = p.WriteStructBegin(thrift.TStructHeader{}); err != nil {
+		return
+	}
+	for _, f := range s.fields {
+		v, ok := s.values[f.Name]
+		if !ok || v == nil {
+			if f.Requiredness == idl.RequirednessRequired {
+				return fmt.Errorf("dynamic: required field %s is missing", f.Name)
+			}
+			continue
+		}
+		var t thrift.TType
+		if t, err = idlTTypeOf(s.doc, f.Type); err != nil {
+			return
+		}
+		if err = p.WriteFieldBegin(thrift.TFieldHeader{Name: f.Name, Type: t, Identity: f.ID}); err != nil {
+			return
+		}
+		if err = writeIDLValue(s.doc, f.Type, v, p); err != nil {
+			return fmt.Errorf("dynamic: field %s: %w", f.Name, err)
+		}
+		if err = p.WriteFieldEnd(); err != nil {
+			return
+		}
+	}
+	if err = p.WriteFieldStop(); err == nil {
+		err = p.WriteStructEnd()
+	}
+	return
+}
+
+func (s *idlStruct) Read(p thrift.TProtocol) (err error) {
+	s.values = make(map[string]interface{})
+	if _, err = p.ReadStructBegin(); err != nil {
+		return
+	}
+	var h thrift.TFieldHeader
+	for {
+		if h, err = p.ReadFieldBegin(); err != nil {
+			return
+		}
+		if h.Type == thrift.STOP {
+			break
+		}
+		if f := s.field(h); f != nil {
+			if s.values[f.Name], err = readIDLValue(s.doc, f.Type, p); err != nil {
+				return
+			}
+		} else if err = p.Skip(h.Type); err != nil {
+			return
+		}
+		if err = p.ReadFieldEnd(); err != nil {
+			return
+		}
+	}
+	err = p.ReadStructEnd()
+	return
+}
+
+// field returns field matching identity and type of h.
+func (s *idlStruct) field(h thrift.TFieldHeader) *idl.Field {
+	for _, f := range s.fields {
+		if f.ID == h.Identity {
+			if t, err := idlTTypeOf(s.doc, f.Type); err == nil && t == h.Type {
+				return f
+			}
+			break
+		}
+	}
+	return nil
+}
+
+func idlTTypeOf(doc *idl.Document, t *idl.Type) (thrift.TType, error) {
+	if doc, t = doc.Resolve(t); t == nil {
+		return thrift.STOP, fmt.Errorf("dynamic: missing type")
+	}
+	switch t.Name {
+	case "bool":
+		return thrift.BOOL, nil
+	case "byte", "i8":
+		return thrift.BYTE, nil
+	case "i16":
+		return thrift.I16, nil
+	case "i32":
+		return thrift.I32, nil
+	case "i64":
+		return thrift.I64, nil
+	case "double":
+		return thrift.DOUBLE, nil
+	case "string", "binary":
+		return thrift.STRING, nil
+	case "map":
+		return thrift.MAP, nil
+	case "set":
+		return thrift.SET, nil
+	case "list":
+		return thrift.LIST, nil
+	}
+	if doc.Enum(t.Name) != nil {
+		return thrift.I32, nil
+	}
+	if doc.Struct(t.Name) != nil {
+		return thrift.STRUCT, nil
+	}
+	return thrift.STOP, fmt.Errorf("dynamic: unsupported type %s", t.Name)
+}
+
+func writeIDLValue(doc *idl.Document, t *idl.Type, v interface{}, p thrift.TProtocol) error {
+	doc, t = doc.Resolve(t)
+	switch t.Name {
+	case "bool":
+		b, ok := v.(bool)
+		if !ok {
+			return fmt.Errorf("expected bool, got %T", v)
+		}
+		return p.WriteBool(b)
+	case "byte", "i8":
+		i, err := idlInt(v, math.MinInt8, math.MaxInt8)
+		if err != nil {
+			return err
+		}
+		return p.WriteByte(byte(i))
+	case "i16":
+		i, err := idlInt(v, math.MinInt16, math.MaxInt16)
+		if err != nil {
+			return err
+		}
+		return p.WriteI16(int16(i))
+	case "i32":
+		i, err := idlInt(v, math.MinInt32, math.MaxInt32)
+		if err != nil {
+			return err
+		}
+		return p.WriteI32(int32(i))
+	case "i64":
+		i, err := idlInt(v, math.MinInt64, math.MaxInt64)
+		if err != nil {
+			return err
+		}
+		return p.WriteI64(i)
+	case "double":
+		f, err := idlFloat(v)
+		if err != nil {
+			return err
+		}
+		return p.WriteDouble(f)
+	case "string", "binary":
+		switch v := v.(type) {
+		case string:
+			return p.WriteString(v)
+		case []byte:
+			return p.WriteBinary(v)
+		}
+		return fmt.Errorf("expected string, got %T", v)
+	case "map":
+		return writeIDLMap(doc, t, v, p)
+	case "set", "list":
+		return writeIDLList(doc, t, v, p)
+	}
+	if e := doc.Enum(t.Name); e != nil {
+		if name, ok := v.(string); ok {
+			i, ok := e.ValueOf(name)
+			if !ok {
+				return fmt.Errorf("unknown %s value %s", e.Name, name)
+			}
+			return p.WriteI32(i)
+		}
+		i, err := idlInt(v, math.MinInt32, math.MaxInt32)
+		if err != nil {
+			return err
+		}
+		return p.WriteI32(int32(i))
+	}
+	if s := doc.Struct(t.Name); s != nil {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("expected map[string]interface{}, got %T", v)
+		}
+		d, _ := doc.Lookup(t.Name)
+		return (&idlStruct{d, s.Fields, m}).Write(p)
+	}
+	return fmt.Errorf("unsupported type %s", t.Name)
+}
+
+func writeIDLList(doc *idl.Document, t *idl.Type, v interface{}, p thrift.TProtocol) (err error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return fmt.Errorf("expected slice, got %T", v)
+	}
+	var e thrift.TType
+	if e, err = idlTTypeOf(doc, t.ValType); err != nil {
+		return
+	}
+	n := rv.Len()
+	if t.Name == "set" {
+		err = p.WriteSetBegin(thrift.TSetHeader{Element: e, Size: n})
+	} else {
+		err = p.WriteListBegin(thrift.TListHeader{Element: e, Size: n})
+	}
+	if err != nil {
+		return
+	}
+	for i := 0; i < n; i++ {
+		if err = writeIDLValue(doc, t.ValType, rv.Index(i).Interface(), p); err != nil {
+			return
+		}
+	}
+	if t.Name == "set" {
+		return p.WriteSetEnd()
+	}
+	return p.WriteListEnd()
+}
+
+func writeIDLMap(doc *idl.Document, t *idl.Type, v interface{}, p thrift.TProtocol) (err error) {
+	var h thrift.TMapHeader
+	if h.Key, err = idlTTypeOf(doc, t.KeyType); err != nil {
+		return
+	}
+	if h.Value, err = idlTTypeOf(doc, t.ValType); err != nil {
+		return
+	}
+	entries, ok := v.([]IDLMapEntry)
+	if !ok {
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Map {
+			return fmt.Errorf("expected map or []IDLMapEntry, got %T", v)
+		}
+		entries = make([]IDLMapEntry, 0, rv.Len())
+		for iter := rv.MapRange(); iter.Next(); {
+			k := iter.Key().Interface()
+			if s, ok := k.(string); ok && h.Key != thrift.STRING {
+				k = idlParseKey(doc, t.KeyType, s)
+			}
+			entries = append(entries, IDLMapEntry{k, iter.Value().Interface()})
+		}
+	}
+	h.Size = len(entries)
+	if err = p.WriteMapBegin(h); err != nil {
+		return
+	}
+	for _, e := range entries {
+		if err = writeIDLValue(doc, t.KeyType, e.Key, p); err != nil {
+			return
+		}
+		if err = writeIDLValue(doc, t.ValType, e.Value, p); err != nil {
+			return
+		}
+	}
+	return p.WriteMapEnd()
+}
+
+// idlParseKey parses string key of JSON object for non-string key type.
+func idlParseKey(doc *idl.Document, t *idl.Type, s string) interface{} {
+	_, t = doc.Resolve(t)
+	switch t.Name {
+	case "bool":
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	case "double":
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	default:
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i
+		}
+	}
+	return s
+}
+
+func idlInt(v interface{}, min, max int64) (i int64, err error) {
+	switch v := v.(type) {
+	case json.Number:
+		i, err = v.Int64()
+	case float32, float64:
+		f := reflect.ValueOf(v).Float()
+		if f != math.Trunc(f) {
+			return 0, fmt.Errorf("expected integer, got %v", f)
+		}
+		i = int64(f)
+	default:
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			i = rv.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if rv.Uint() > math.MaxInt64 {
+				return 0, fmt.Errorf("integer %v out of range", v)
+			}
+			i = int64(rv.Uint())
+		default:
+			return 0, fmt.Errorf("expected integer, got %T", v)
+		}
+	}
+	if err == nil && (i < min || i > max) {
+		err = fmt.Errorf("integer %v out of range", i)
+	}
+	return
+}
+
+func idlFloat(v interface{}) (float64, error) {
+	if n, ok := v.(json.Number); ok {
+		return n.Float64()
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	}
+	i, err := idlInt(v, math.MinInt64, math.MaxInt64)
+	return float64(i), err
+}
+
+func readIDLValue(doc *idl.Document, t *idl.Type, p thrift.TProtocol) (v interface{}, err error) {
+	doc, t = doc.Resolve(t)
+	switch t.Name {
+	case "bool":
+		return p.ReadBool()
+	case "byte", "i8":
+		var b byte
+		b, err = p.ReadByte()
+		return int8(b), err
+	case "i16":
+		return p.ReadI16()
+	case "i32":
+		return p.ReadI32()
+	case "i64":
+		return p.ReadI64()
+	case "double":
+		return p.ReadDouble()
+	case "string":
+		return p.ReadString()
+	case "binary":
+		return p.ReadBinary()
+	case "map":
+		return readIDLMap(doc, t, p)
+	case "set":
+		var h thrift.TSetHeader
+		if h, err = p.ReadSetBegin(); err != nil {
+			return
+		}
+		if v, err = readIDLList(doc, t, h.Element, h.Size, p); err == nil {
+			err = p.ReadSetEnd()
+		}
+		return
+	case "list":
+		var h thrift.TListHeader
+		if h, err = p.ReadListBegin(); err != nil {
+			return
+		}
+		if v, err = readIDLList(doc, t, h.Element, h.Size, p); err == nil {
+			err = p.ReadListEnd()
+		}
+		return
+	}
+	if e := doc.Enum(t.Name); e != nil {
+		var i int32
+		if i, err = p.ReadI32(); err != nil {
+			return
+		}
+		if name, ok := e.NameOf(i); ok {
+			return name, nil
+		}
+		return i, nil
+	}
+	if s := doc.Struct(t.Name); s != nil {
+		d, _ := doc.Lookup(t.Name)
+		r := &idlStruct{d, s.Fields, nil}
+		err = r.Read(p)
+		return r.values, err
+	}
+	return nil, fmt.Errorf("dynamic: unsupported type %s", t.Name)
+}
+
+func readIDLList(doc *idl.Document, t *idl.Type, e thrift.TType, n int, p thrift.TProtocol) (v interface{}, err error) {
+	r := make([]interface{}, 0, n)
+	if et, _ := idlTTypeOf(doc, t.ValType); et != e {
+		for i := 0; i < n; i++ {
+			if err = p.Skip(e); err != nil {
+				return
+			}
+		}
+		return r, nil
+	}
+	for i := 0; i < n; i++ {
+		var x interface{}
+		if x, err = readIDLValue(doc, t.ValType, p); err != nil {
+			return
+		}
+		r = append(r, x)
+	}
+	return r, nil
+}
+
+func readIDLMap(doc *idl.Document, t *idl.Type, p thrift.TProtocol) (v interface{}, err error) {
+	var h thrift.TMapHeader
+	if h, err = p.ReadMapBegin(); err != nil {
+		return
+	}
+	kt, _ := idlTTypeOf(doc, t.KeyType)
+	vt, _ := idlTTypeOf(doc, t.ValType)
+	skip := h.Size != 0 && (kt != h.Key || vt != h.Value)
+	var sm map[string]interface{}
+	var im map[interface{}]interface{}
+	var entries []IDLMapEntry
+	kd, kr := doc.Resolve(t.KeyType)
+	switch {
+	case kr.Name == "string" || kr.Name == "binary":
+		sm = make(map[string]interface{}, h.Size)
+		v = sm
+	case kr.Name == "list" || kr.Name == "set" || kr.Name == "map" || kd.Struct(kr.Name) != nil:
+		entries = make([]IDLMapEntry, 0, h.Size)
+	default:
+		im = make(map[interface{}]interface{}, h.Size)
+		v = im
+	}
+	for i := 0; i < h.Size; i++ {
+		if skip {
+			if err = p.Skip(h.Key); err == nil {
+				err = p.Skip(h.Value)
+			}
+			if err != nil {
+				return
+			}
+			continue
+		}
+		var key, value interface{}
+		if key, err = readIDLValue(doc, t.KeyType, p); err != nil {
+			return
+		}
+		if value, err = readIDLValue(doc, t.ValType, p); err != nil {
+			return
+		}
+		switch key := key.(type) {
+		case string:
+			sm[key] = value
+		case []byte:
+			sm[string(key)] = value
+		default:
+			if entries != nil {
+				entries = append(entries, IDLMapEntry{key, value})
+			} else {
+				im[key] = value
+			}
+		}
+	}
+	if entries != nil {
+		v = entries
+	}
+	err = p.ReadMapEnd()
+	return
+}
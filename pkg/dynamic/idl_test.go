@@ -0,0 +1,315 @@
+package dynamic_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/b1avk/thrift/pkg/dynamic"
+	"github.com/b1avk/thrift/pkg/idl"
+	"github.com/b1avk/thrift/pkg/thrift"
+	"github.com/b1avk/thrift/pkg/thrifttest"
+)
+
+const itemsIDL = `
+enum Status {
+	ACTIVE = 1,
+	DISABLED = 2
+}
+
+typedef list<i32> Scores
+
+struct Item {
+	1: required string name
+	2: Status status
+	3: optional Item child
+	4: Scores scores
+	5: map<i32, string> labels
+}
+
+exception NotFound {
+	1: string message
+}
+
+service Base {
+	string ping()
+}
+
+service Items extends Base {
+	Item put(1: Item item) throws (1: NotFound notFound)
+}
+`
+
+// FakeItemsClient echos item of put and replies "pong" to ping.
+type FakeItemsClient struct{}
+
+func (*FakeItemsClient) Call(ctx context.Context, method string, args, result thrift.TStruct) (err error) {
+	p := thrift.NewTBinaryProtocol(thrift.NewTMemoryBuffer(), nil)
+	if err = args.Write(p); err != nil {
+		return
+	}
+	a := new(dynamic.Struct)
+	if err = a.Read(p); err != nil {
+		return
+	}
+	r := new(dynamic.Struct)
+	switch method {
+	case "ping":
+		r.SetField(0, dynamic.String("pong"))
+	case "put":
+		item, _ := a.Get("1")
+		if name, _ := a.Get("1.1"); name == dynamic.String("missing") {
+			r.SetField(1, &dynamic.Struct{Fields: []dynamic.Field{{Identity: 1, Value: dynamic.String("not found")}}})
+		} else {
+			r.SetField(0, item)
+		}
+	}
+	if err = r.Write(p); err != nil {
+		return
+	}
+	return result.Read(p)
+}
+
+func newItemsClient(t *testing.T) *dynamic.IDLClient {
+	doc, err := idl.Parse(strings.NewReader(itemsIDL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := dynamic.NewIDLClient(doc, "Items", new(FakeItemsClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestIDLClientInvoke(t *testing.T) {
+	c := newItemsClient(t)
+	item := map[string]interface{}{
+		"name":   "Hello",
+		"status": "DISABLED",
+		"child": map[string]interface{}{
+			"name":   "World",
+			"status": 1,
+		},
+		"scores": []int{1, 2},
+		"labels": map[string]interface{}{"1": "one"},
+	}
+	r, err := c.Invoke(context.Background(), "put", map[string]interface{}{"item": item})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"success": map[string]interface{}{
+			"name":   "Hello",
+			"status": "DISABLED",
+			"child": map[string]interface{}{
+				"name":   "World",
+				"status": "ACTIVE",
+			},
+			"scores": []interface{}{int32(1), int32(2)},
+			"labels": map[interface{}]interface{}{int32(1): "one"},
+		},
+	}
+	if !reflect.DeepEqual(expected, r) {
+		t.Fatal("result mismatch", r)
+	}
+}
+
+func TestIDLClientInvokeExtends(t *testing.T) {
+	c := newItemsClient(t)
+	r, err := c.Invoke(context.Background(), "ping", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r["success"] != "pong" {
+		t.Fatal(`ping must returns "pong"`)
+	}
+}
+
+func TestIDLClientInvokeException(t *testing.T) {
+	c := newItemsClient(t)
+	_, err := c.Invoke(context.Background(), "put", map[string]interface{}{
+		"item": map[string]interface{}{"name": "missing"},
+	})
+	var e *dynamic.IDLException
+	if !errors.As(err, &e) {
+		t.Fatal("must returns IDLException", err)
+	}
+	if e.Name != "NotFound" || e.Field != "notFound" || e.Fields["message"] != "not found" {
+		t.Fatal("exception mismatch", e)
+	}
+}
+
+const loggerIDL = `
+service Logger {
+	oneway void log(1: string message)
+	string ping()
+}
+`
+
+func TestIDLClientInvokeOneway(t *testing.T) {
+	logs := make(chan string, 1)
+	processor := thrift.NewTStandardProcessor()
+	processor.Handle("log", func() thrift.TStruct { return new(dynamic.Struct) }, func(ctx context.Context, args thrift.TStruct) (thrift.TStruct, error) {
+		message, _ := args.(*dynamic.Struct).Get("1")
+		logs <- string(message.(dynamic.String))
+		return nil, nil
+	})
+	processor.Handle("ping", func() thrift.TStruct { return new(dynamic.Struct) }, func(ctx context.Context, args thrift.TStruct) (thrift.TStruct, error) {
+		r := new(dynamic.Struct)
+		r.SetField(0, dynamic.String("pong"))
+		return r, nil
+	})
+	h := thrifttest.NewHarness(t, processor, nil)
+	doc, err := idl.Parse(strings.NewReader(loggerIDL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := dynamic.NewIDLClient(doc, "Logger", h.Client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a reply written to oneway call blocks the pipe, fail instead of hanging.
+	h.Conn.SetDeadline(time.Now().Add(5 * time.Second))
+	ctx := context.Background()
+	if r, err := c.Invoke(ctx, "log", map[string]interface{}{"message": "Hello"}); r != nil || err != nil {
+		t.Fatal("oneway must returns nothing", r, err)
+	}
+	if message := <-logs; message != "Hello" {
+		t.Fatal("message mismatch", message)
+	}
+	r, err := c.Invoke(ctx, "ping", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r["success"] != "pong" {
+		t.Fatal(`ping must returns "pong"`)
+	}
+}
+
+func TestIDLClientInvokeInvalid(t *testing.T) {
+	c := newItemsClient(t)
+	cases := []map[string]interface{}{
+		{"unknown": 1},
+		{"item": map[string]interface{}{"status": "ACTIVE"}},
+		{"item": map[string]interface{}{"name": "Hello", "status": "UNKNOWN"}},
+		{"item": map[string]interface{}{"name": 1}},
+		{"item": map[string]interface{}{"name": "Hello", "scores": []interface{}{1 << 40}}},
+	}
+	for _, args := range cases {
+		if _, err := c.Invoke(context.Background(), "put", args); err == nil {
+			t.Fatal("must error", args)
+		}
+	}
+	if _, err := c.Invoke(context.Background(), "unknown", nil); err == nil {
+		t.Fatal("must error on unknown method")
+	}
+}
+
+func TestNewIDLClientUnsupportedType(t *testing.T) {
+	for _, src := range []string{
+		"service S { void f(1: uuid id) }",
+		"service S { uuid f() }",
+		"typedef uuid ID\nstruct Item { 1: map<string, list<ID>> ids }\nservice S { void f(1: Item item) }",
+		"struct Item { 1: Item child, 2: Missing missing }\nservice S { void f(1: Item item) }",
+	} {
+		doc, err := idl.Parse(strings.NewReader(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = dynamic.NewIDLClient(doc, "S", new(FakeEchoClient)); err == nil || !strings.Contains(err.Error(), "unsupported type") {
+			t.Fatalf("%q: must error on unsupported type, got %v", src, err)
+		}
+	}
+}
+
+const mapsIDL = `
+struct Key {
+	1: i32 id
+}
+
+service Maps {
+	map<binary, i32> binaryKeys(1: map<binary, i32> m)
+	map<list<i32>, i32> listKeys(1: map<list<i32>, i32> m)
+	map<set<string>, i32> setKeys(1: map<set<string>, i32> m)
+	map<map<i32, i32>, i32> mapKeys(1: map<map<i32, i32>, i32> m)
+	map<Key, i32> structKeys(1: map<Key, i32> m)
+}
+`
+
+// FakeEchoClient replies first argument as success.
+type FakeEchoClient struct{}
+
+func (*FakeEchoClient) Call(ctx context.Context, method string, args, result thrift.TStruct) (err error) {
+	p := thrift.NewTBinaryProtocol(thrift.NewTMemoryBuffer(), nil)
+	if err = args.Write(p); err != nil {
+		return
+	}
+	a := new(dynamic.Struct)
+	if err = a.Read(p); err != nil {
+		return
+	}
+	r := new(dynamic.Struct)
+	v, _ := a.Get("1")
+	r.SetField(0, v)
+	if err = r.Write(p); err != nil {
+		return
+	}
+	return result.Read(p)
+}
+
+func TestIDLClientInvokeMapKeys(t *testing.T) {
+	doc, err := idl.Parse(strings.NewReader(mapsIDL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := dynamic.NewIDLClient(doc, "Maps", new(FakeEchoClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		method   string
+		arg      interface{}
+		expected interface{}
+	}{
+		{
+			"binaryKeys",
+			map[string]interface{}{"\x00\xff": 1},
+			map[string]interface{}{"\x00\xff": int32(1)},
+		},
+		{
+			"listKeys",
+			[]dynamic.IDLMapEntry{{[]interface{}{1, 2}, 1}},
+			[]dynamic.IDLMapEntry{{[]interface{}{int32(1), int32(2)}, int32(1)}},
+		},
+		{
+			"setKeys",
+			[]dynamic.IDLMapEntry{{[]interface{}{"a"}, 1}},
+			[]dynamic.IDLMapEntry{{[]interface{}{"a"}, int32(1)}},
+		},
+		{
+			"mapKeys",
+			[]dynamic.IDLMapEntry{{map[interface{}]interface{}{1: 2}, 1}},
+			[]dynamic.IDLMapEntry{{map[interface{}]interface{}{int32(1): int32(2)}, int32(1)}},
+		},
+		{
+			"structKeys",
+			[]dynamic.IDLMapEntry{{map[string]interface{}{"id": 1}, 1}},
+			[]dynamic.IDLMapEntry{{map[string]interface{}{"id": int32(1)}, int32(1)}},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.method, func(t *testing.T) {
+			r, err := c.Invoke(context.Background(), tc.method, map[string]interface{}{"m": tc.arg})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(r["success"], tc.expected) {
+				t.Fatalf("expected %#v, got %#v", tc.expected, r["success"])
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package dynamic
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/b1avk/thrift/pkg/thrift"
+)
+
+// interfaceTypes concrete types of a registered interface.
+type interfaceTypes struct {
+	byIdentity map[int16]reflect.Type
+	identityOf map[reflect.Type]int16
+}
+
+var interfaces sync.Map
+
+// RegisterInterface registers concrete types of interface iface by field identity,
+// iface must be a pointer to interface such as (*Shape)(nil).
+// values of the interface are encoded as union struct with a single field
+// whose identity is the one of the concrete type.
+func RegisterInterface(iface interface{}, types map[int16]interface{}) {
+	t := reflect.TypeOf(iface)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Interface {
+		panic(fmt.Errorf("%v must be pointer to interface", t))
+	}
+	t = t.Elem()
+	r := &interfaceTypes{
+		byIdentity: make(map[int16]reflect.Type, len(types)),
+		identityOf: make(map[reflect.Type]int16, len(types)),
+	}
+	for id, v := range types {
+		c := reflect.TypeOf(v)
+		if c == nil || !c.Implements(t) {
+			panic(fmt.Errorf("%v does not implement %v", c, t))
+		}
+		if _, ok := r.identityOf[c]; ok {
+			panic(fmt.Errorf("%v already registered", c))
+		}
+		r.byIdentity[id] = c
+		r.identityOf[c] = id
+	}
+	interfaces.Store(t, r)
+}
+
+func interfaceTypesOf(t reflect.Type) (*interfaceTypes, error) {
+	if r, ok := interfaces.Load(t); ok {
+		return r.(*interfaceTypes), nil
+	}
+	return nil, fmt.Errorf("interface %v is not registered", t)
+}
+
+// interfaceEncoder an encoder of registered interface type.
+type interfaceEncoder struct {
+	interfaceType reflect.Type
+}
+
+func (e *interfaceEncoder) Encode(v reflect.Value, p thrift.TProtocol) (err error) {
+	var r *interfaceTypes
+	if r, err = interfaceTypesOf(e.interfaceType); err != nil {
+		return
+	}
+	if err = p.WriteStructBegin(thrift.TStructHeader{}); err != nil {
+		return
+	}
+	if !v.IsNil() {
+		x := v.Elem()
+		id, ok := r.identityOf[x.Type()]
+		if !ok {
+			return fmt.Errorf("%v is not registered for %v", x.Type(), e.interfaceType)
+		}
+		c := InternalEncoderOf(x.Type())
+		if err = p.WriteFieldBegin(thrift.TFieldHeader{Name: x.Type().Name(), Type: c.Kind(), Identity: id}); err != nil {
+			return
+		}
+		if err = c.Encode(x, p); err != nil {
+			return
+		}
+		if err = p.WriteFieldEnd(); err != nil {
+			return
+		}
+	}
+	if err = p.WriteFieldStop(); err == nil {
+		err = p.WriteStructEnd()
+	}
+	return
+}
+
+func (e *interfaceEncoder) Decode(v reflect.Value, p thrift.TProtocol) (err error) {
+	var r *interfaceTypes
+	if r, err = interfaceTypesOf(e.interfaceType); err != nil {
+		return
+	}
+	if _, err = p.ReadStructBegin(); err != nil {
+		return
+	}
+	var h thrift.TFieldHeader
+	for {
+		if h, err = p.ReadFieldBegin(); err != nil {
+			return
+		}
+		if h.Type == thrift.STOP {
+			break
+		}
+		if t, ok := r.byIdentity[h.Identity]; ok {
+			if c := InternalEncoderOf(t); c.Kind() == h.Type {
+				x := reflect.New(t).Elem()
+				if err = c.Decode(x, p); err != nil {
+					return
+				}
+				v.Set(x)
+				if err = p.ReadFieldEnd(); err != nil {
+					return
+				}
+				continue
+			}
+		}
+		if err = p.Skip(h.Type); err != nil {
+			return
+		}
+		if err = p.ReadFieldEnd(); err != nil {
+			return
+		}
+	}
+	return p.ReadStructEnd()
+}
+
+func (e *interfaceEncoder) Kind() thrift.TType {
+	return thrift.STRUCT
+}
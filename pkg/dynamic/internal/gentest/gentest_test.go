@@ -0,0 +1,209 @@
+package gentest_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/b1avk/thrift/pkg/dynamic"
+	"github.com/b1avk/thrift/pkg/dynamic/internal/gentest"
+	"github.com/b1avk/thrift/pkg/thrift"
+)
+
+// reflectInner and reflectBench mirror gentest types without generated methods.
+type reflectInner struct {
+	Name   string  `thrift:"1"`
+	Scores []int32 `thrift:"2"`
+}
+
+type reflectBench struct {
+	Flag      bool                          `thrift:"1"`
+	Byte      int8                          `thrift:"2"`
+	I16       int16                         `thrift:"3"`
+	I32       int32                         `thrift:"4"`
+	I64       int64                         `thrift:"5"`
+	U32       uint32                        `thrift:"6"`
+	Double    float64                       `thrift:"7"`
+	String    string                        `thrift:"8,required"`
+	Binary    []byte                        `thrift:"9"`
+	Status    gentest.Status                `thrift:"10"`
+	List      []string                      `thrift:"11"`
+	Set       []int64                       `thrift:"12,set"`
+	Map       map[string]*reflectInner      `thrift:"13"`
+	Inner     reflectInner                  `thrift:"14"`
+	Ptr       *reflectInner                 `thrift:"15"`
+	Nested    [][]int32                     `thrift:"16,list,set"`
+	Exception *thrift.TApplicationException `thrift:"17"`
+	Unknown   dynamic.UnknownFields         `thrift:"-,unknown"`
+}
+
+var generatedValue = gentest.Bench{
+	Flag:      true,
+	Byte:      -1,
+	I16:       -16,
+	I32:       32,
+	I64:       -64,
+	U32:       1 << 31,
+	Double:    0.123,
+	Binary:    []byte("Hello"),
+	Status:    gentest.Status(2),
+	List:      []string{"Hello", "World"},
+	Set:       []int64{1, 2, 3},
+	Map:       map[string]*gentest.Inner{"Hello": {Name: "World", Scores: []int32{1}}},
+	Inner:     gentest.Inner{Name: "Inner"},
+	Ptr:       &gentest.Inner{Scores: []int32{1, 2}},
+	Nested:    [][]int32{{1}, {2, 3}},
+	Exception: &thrift.TApplicationException{Message: "Hello", Type: 1},
+}
+
+var reflectValue = reflectBench{
+	Flag:      true,
+	Byte:      -1,
+	I16:       -16,
+	I32:       32,
+	I64:       -64,
+	U32:       1 << 31,
+	Double:    0.123,
+	Binary:    []byte("Hello"),
+	Status:    gentest.Status(2),
+	List:      []string{"Hello", "World"},
+	Set:       []int64{1, 2, 3},
+	Map:       map[string]*reflectInner{"Hello": {Name: "World", Scores: []int32{1}}},
+	Inner:     reflectInner{Name: "Inner"},
+	Ptr:       &reflectInner{Scores: []int32{1, 2}},
+	Nested:    [][]int32{{1}, {2, 3}},
+	Exception: &thrift.TApplicationException{Message: "Hello", Type: 1},
+}
+
+func encode(t testing.TB, v interface{}, newProtocol func(thrift.TTransport, *thrift.TConfiguration) thrift.TProtocol, cfg *thrift.TConfiguration) []byte {
+	b := thrift.NewTMemoryBuffer()
+	if err := dynamic.ValueEncoderOf(reflect.TypeOf(v)).Encode(v, newProtocol(b, cfg)); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestGeneratedMatchesReflection(t *testing.T) {
+	for name, newProtocol := range map[string]func(thrift.TTransport, *thrift.TConfiguration) thrift.TProtocol{
+		"Binary":  thrift.NewTBinaryProtocol,
+		"Compact": thrift.NewTCompactProtocol,
+	} {
+		t.Run(name, func(t *testing.T) {
+			g := encode(t, generatedValue, newProtocol, nil)
+			r := encode(t, reflectValue, newProtocol, nil)
+			if !bytes.Equal(g, r) {
+				t.Fatalf("encoded bytes mismatch\n%x\n%x", g, r)
+			}
+			b := thrift.NewTMemoryBuffer()
+			b.Write(r)
+			var v gentest.Bench
+			if err := v.Read(newProtocol(b, nil)); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(generatedValue, v) {
+				t.Fatal("value obtained for generated Read mismatch")
+			}
+		})
+	}
+}
+
+func BenchmarkEncodeGenerated(b *testing.B) {
+	benchmarkEncode(b, &generatedValue)
+}
+
+func BenchmarkEncodeReflection(b *testing.B) {
+	benchmarkEncode(b, &reflectValue)
+}
+
+func BenchmarkDecodeGenerated(b *testing.B) {
+	benchmarkDecode(b, &generatedValue, new(gentest.Bench))
+}
+
+func BenchmarkDecodeReflection(b *testing.B) {
+	benchmarkDecode(b, &reflectValue, new(reflectBench))
+}
+
+func benchmarkEncode(b *testing.B, v interface{}) {
+	e := dynamic.ValueEncoderOf(reflect.TypeOf(v))
+	buf := thrift.NewTMemoryBuffer()
+	p := thrift.NewTBinaryProtocol(buf, nil)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		if err := e.Encode(v, p); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkDecode(b *testing.B, v, r interface{}) {
+	e := dynamic.ValueEncoderOf(reflect.TypeOf(v))
+	buf := thrift.NewTMemoryBuffer()
+	p := thrift.NewTBinaryProtocol(buf, nil)
+	if err := e.Encode(v, p); err != nil {
+		b.Fatal(err)
+	}
+	data := append([]byte(nil), buf.Bytes()...)
+	d := dynamic.ValueEncoderOf(reflect.TypeOf(r).Elem())
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		buf.Write(data)
+		if err := d.Decode(r, p); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestGeneratedCanonical(t *testing.T) {
+	cfg := &thrift.TConfiguration{Canonical: true}
+	g, r := generatedValue, reflectValue
+	g.Map = map[string]*gentest.Inner{"a": {Name: "1"}, "b": {Name: "2"}, "c": {Name: "3"}}
+	r.Map = map[string]*reflectInner{"a": {Name: "1"}, "b": {Name: "2"}, "c": {Name: "3"}}
+	g.Set = []int64{3, 1, 2}
+	r.Set = []int64{2, 3, 1}
+	if !bytes.Equal(encode(t, &g, thrift.NewTCompactProtocol, cfg), encode(t, &r, thrift.NewTCompactProtocol, cfg)) {
+		t.Fatal("canonical encoded bytes mismatch")
+	}
+}
+
+type reflectUnordered struct {
+	B       int32                 `thrift:"3"`
+	A       []string              `thrift:"1,set"`
+	Unknown dynamic.UnknownFields `thrift:"-,unknown"`
+}
+
+func TestGeneratedCanonicalUnordered(t *testing.T) {
+	unknown := dynamic.UnknownFields{
+		{Type: thrift.I32, Identity: 4, Data: []byte{0, 0, 0, 4}},
+		{Type: thrift.I32, Identity: 2, Data: []byte{0, 0, 0, 2}},
+	}
+	g := gentest.Unordered{B: 3, A: []string{"b", "a"}, Unknown: unknown}
+	r := reflectUnordered{B: 3, A: []string{"b", "a"}, Unknown: unknown}
+	for _, cfg := range []*thrift.TConfiguration{nil, {Canonical: true}} {
+		if !bytes.Equal(encode(t, &g, thrift.NewTBinaryProtocol, cfg), encode(t, &r, thrift.NewTBinaryProtocol, cfg)) {
+			t.Fatalf("encoded bytes mismatch, canonical %v", cfg != nil)
+		}
+	}
+}
+
+func TestGeneratedUnknownTypeMismatch(t *testing.T) {
+	data := encode(t, &struct {
+		B string `thrift:"3"`
+	}{B: "3"}, thrift.NewTBinaryProtocol, nil)
+	var g gentest.Unordered
+	var r reflectUnordered
+	for _, v := range []interface{}{&g, &r} {
+		b := thrift.NewTMemoryBuffer()
+		b.Write(data)
+		if err := dynamic.ValueEncoderOf(reflect.TypeOf(v).Elem()).Decode(v, thrift.NewTBinaryProtocol(b, nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(g.Unknown) != 1 || g.Unknown[0].Identity != 3 || g.Unknown[0].Type != thrift.STRING {
+		t.Fatalf("must capture mismatched field, got %+v", g.Unknown)
+	}
+	if !reflect.DeepEqual(g.Unknown, r.Unknown) {
+		t.Fatalf("unknown fields mismatch: %+v, %+v", g.Unknown, r.Unknown)
+	}
+}
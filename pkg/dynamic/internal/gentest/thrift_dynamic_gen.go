@@ -0,0 +1,1109 @@
+// Code generated by thrift-dynamic-gen. DO NOT EDIT.
+
+package gentest
+
+import (
+	"github.com/b1avk/thrift/pkg/dynamic"
+	"github.com/b1avk/thrift/pkg/thrift"
+)
+
+// ThriftGenerated marks methods of Inner as generated by thrift-dynamic-gen.
+func (*Inner) ThriftGenerated() {}
+
+// Write writes v to p.
+func (v *Inner) Write(p thrift.TProtocol) (err error) {
+	if err = p.WriteStructBegin(thrift.TStructHeader{}); err != nil {
+		return
+	}
+	if v.Name != "" {
+		if err = p.WriteFieldBegin(thrift.TFieldHeader{Name: "Name", Type: thrift.STRING, Identity: 1}); err != nil {
+			return
+		}
+		if err = p.WriteString(v.Name); err != nil {
+			return
+		}
+		if err = p.WriteFieldEnd(); err != nil {
+			return
+		}
+	}
+	if v.Scores != nil {
+		if err = p.WriteFieldBegin(thrift.TFieldHeader{Name: "Scores", Type: thrift.LIST, Identity: 2}); err != nil {
+			return
+		}
+		if err = p.WriteListBegin(thrift.TListHeader{Element: thrift.I32, Size: len(v.Scores)}); err != nil {
+			return
+		}
+		for i1 := range v.Scores {
+			if err = p.WriteI32(v.Scores[i1]); err != nil {
+				return
+			}
+		}
+		if err = p.WriteListEnd(); err != nil {
+			return
+		}
+		if err = p.WriteFieldEnd(); err != nil {
+			return
+		}
+	}
+	if err = p.WriteFieldStop(); err != nil {
+		return
+	}
+	return p.WriteStructEnd()
+}
+
+// Read reads v from p.
+func (v *Inner) Read(p thrift.TProtocol) (err error) {
+	if _, err = p.ReadStructBegin(); err != nil {
+		return
+	}
+	var h thrift.TFieldHeader
+	for {
+		if h, err = p.ReadFieldBegin(); err != nil {
+			return
+		}
+		if h.Type == thrift.STOP {
+			break
+		}
+		switch h.Identity {
+		case 1:
+			if h.Type == thrift.STRING {
+				if v.Name, err = p.ReadString(); err != nil {
+					return
+				}
+			} else if err = p.Skip(h.Type); err != nil {
+				return
+			}
+		case 2:
+			if h.Type == thrift.LIST {
+				var h1 thrift.TListHeader
+				if h1, err = p.ReadListBegin(); err != nil {
+					return
+				}
+				if h1.Element != thrift.I32 {
+					for i2 := 0; i2 < h1.Size; i2++ {
+						if err = p.Skip(h1.Element); err != nil {
+							return
+						}
+					}
+				} else {
+					if h1.Size > len(v.Scores) || v.Scores == nil {
+						v.Scores = make([]int32, h1.Size)
+					} else {
+						v.Scores = v.Scores[:h1.Size]
+					}
+					for i2 := 0; i2 < h1.Size; i2++ {
+						if v.Scores[i2], err = p.ReadI32(); err != nil {
+							return
+						}
+					}
+				}
+				if err = p.ReadListEnd(); err != nil {
+					return
+				}
+			} else if err = p.Skip(h.Type); err != nil {
+				return
+			}
+		default:
+			if err = p.Skip(h.Type); err != nil {
+				return
+			}
+		}
+		if err = p.ReadFieldEnd(); err != nil {
+			return
+		}
+	}
+	return p.ReadStructEnd()
+}
+
+// ThriftGenerated marks methods of Bench as generated by thrift-dynamic-gen.
+func (*Bench) ThriftGenerated() {}
+
+// Write writes v to p.
+func (v *Bench) Write(p thrift.TProtocol) (err error) {
+	canonical := thrift.TConfigurationOf(p).IsCanonical()
+	if err = p.WriteStructBegin(thrift.TStructHeader{}); err != nil {
+		return
+	}
+	unknown := v.Unknown
+	if canonical {
+		unknown = unknown.Sorted()
+	}
+	if v.Flag {
+		for canonical && len(unknown) != 0 && unknown[0].Identity < 1 {
+			if err = unknown[0].Write(p); err != nil {
+				return
+			}
+			unknown = unknown[1:]
+		}
+		if err = p.WriteFieldBegin(thrift.TFieldHeader{Name: "Flag", Type: thrift.BOOL, Identity: 1}); err != nil {
+			return
+		}
+		if err = p.WriteBool(v.Flag); err != nil {
+			return
+		}
+		if err = p.WriteFieldEnd(); err != nil {
+			return
+		}
+	}
+	if v.Byte != 0 {
+		for canonical && len(unknown) != 0 && unknown[0].Identity < 2 {
+			if err = unknown[0].Write(p); err != nil {
+				return
+			}
+			unknown = unknown[1:]
+		}
+		if err = p.WriteFieldBegin(thrift.TFieldHeader{Name: "Byte", Type: thrift.BYTE, Identity: 2}); err != nil {
+			return
+		}
+		if err = p.WriteByte(byte(v.Byte)); err != nil {
+			return
+		}
+		if err = p.WriteFieldEnd(); err != nil {
+			return
+		}
+	}
+	if v.I16 != 0 {
+		for canonical && len(unknown) != 0 && unknown[0].Identity < 3 {
+			if err = unknown[0].Write(p); err != nil {
+				return
+			}
+			unknown = unknown[1:]
+		}
+		if err = p.WriteFieldBegin(thrift.TFieldHeader{Name: "I16", Type: thrift.I16, Identity: 3}); err != nil {
+			return
+		}
+		if err = p.WriteI16(v.I16); err != nil {
+			return
+		}
+		if err = p.WriteFieldEnd(); err != nil {
+			return
+		}
+	}
+	if v.I32 != 0 {
+		for canonical && len(unknown) != 0 && unknown[0].Identity < 4 {
+			if err = unknown[0].Write(p); err != nil {
+				return
+			}
+			unknown = unknown[1:]
+		}
+		if err = p.WriteFieldBegin(thrift.TFieldHeader{Name: "I32", Type: thrift.I32, Identity: 4}); err != nil {
+			return
+		}
+		if err = p.WriteI32(v.I32); err != nil {
+			return
+		}
+		if err = p.WriteFieldEnd(); err != nil {
+			return
+		}
+	}
+	if v.I64 != 0 {
+		for canonical && len(unknown) != 0 && unknown[0].Identity < 5 {
+			if err = unknown[0].Write(p); err != nil {
+				return
+			}
+			unknown = unknown[1:]
+		}
+		if err = p.WriteFieldBegin(thrift.TFieldHeader{Name: "I64", Type: thrift.I64, Identity: 5}); err != nil {
+			return
+		}
+		if err = p.WriteI64(v.I64); err != nil {
+			return
+		}
+		if err = p.WriteFieldEnd(); err != nil {
+			return
+		}
+	}
+	if v.U32 != 0 {
+		for canonical && len(unknown) != 0 && unknown[0].Identity < 6 {
+			if err = unknown[0].Write(p); err != nil {
+				return
+			}
+			unknown = unknown[1:]
+		}
+		if err = p.WriteFieldBegin(thrift.TFieldHeader{Name: "U32", Type: thrift.I32, Identity: 6}); err != nil {
+			return
+		}
+		if err = p.WriteU32(v.U32); err != nil {
+			return
+		}
+		if err = p.WriteFieldEnd(); err != nil {
+			return
+		}
+	}
+	if v.Double != 0 {
+		for canonical && len(unknown) != 0 && unknown[0].Identity < 7 {
+			if err = unknown[0].Write(p); err != nil {
+				return
+			}
+			unknown = unknown[1:]
+		}
+		if err = p.WriteFieldBegin(thrift.TFieldHeader{Name: "Double", Type: thrift.DOUBLE, Identity: 7}); err != nil {
+			return
+		}
+		if err = p.WriteDouble(v.Double); err != nil {
+			return
+		}
+		if err = p.WriteFieldEnd(); err != nil {
+			return
+		}
+	}
+	for canonical && len(unknown) != 0 && unknown[0].Identity < 8 {
+		if err = unknown[0].Write(p); err != nil {
+			return
+		}
+		unknown = unknown[1:]
+	}
+	if err = p.WriteFieldBegin(thrift.TFieldHeader{Name: "String", Type: thrift.STRING, Identity: 8}); err != nil {
+		return
+	}
+	if err = p.WriteString(v.String); err != nil {
+		return
+	}
+	if err = p.WriteFieldEnd(); err != nil {
+		return
+	}
+	if v.Binary != nil {
+		for canonical && len(unknown) != 0 && unknown[0].Identity < 9 {
+			if err = unknown[0].Write(p); err != nil {
+				return
+			}
+			unknown = unknown[1:]
+		}
+		if err = p.WriteFieldBegin(thrift.TFieldHeader{Name: "Binary", Type: thrift.STRING, Identity: 9}); err != nil {
+			return
+		}
+		if err = p.WriteBinary(v.Binary); err != nil {
+			return
+		}
+		if err = p.WriteFieldEnd(); err != nil {
+			return
+		}
+	}
+	if v.Status != 0 {
+		for canonical && len(unknown) != 0 && unknown[0].Identity < 10 {
+			if err = unknown[0].Write(p); err != nil {
+				return
+			}
+			unknown = unknown[1:]
+		}
+		if err = p.WriteFieldBegin(thrift.TFieldHeader{Name: "Status", Type: thrift.I32, Identity: 10}); err != nil {
+			return
+		}
+		if err = p.WriteI32(int32(v.Status)); err != nil {
+			return
+		}
+		if err = p.WriteFieldEnd(); err != nil {
+			return
+		}
+	}
+	if v.List != nil {
+		for canonical && len(unknown) != 0 && unknown[0].Identity < 11 {
+			if err = unknown[0].Write(p); err != nil {
+				return
+			}
+			unknown = unknown[1:]
+		}
+		if err = p.WriteFieldBegin(thrift.TFieldHeader{Name: "List", Type: thrift.LIST, Identity: 11}); err != nil {
+			return
+		}
+		if err = p.WriteListBegin(thrift.TListHeader{Element: thrift.STRING, Size: len(v.List)}); err != nil {
+			return
+		}
+		for i1 := range v.List {
+			if err = p.WriteString(v.List[i1]); err != nil {
+				return
+			}
+		}
+		if err = p.WriteListEnd(); err != nil {
+			return
+		}
+		if err = p.WriteFieldEnd(); err != nil {
+			return
+		}
+	}
+	if v.Set != nil {
+		for canonical && len(unknown) != 0 && unknown[0].Identity < 12 {
+			if err = unknown[0].Write(p); err != nil {
+				return
+			}
+			unknown = unknown[1:]
+		}
+		if err = p.WriteFieldBegin(thrift.TFieldHeader{Name: "Set", Type: thrift.SET, Identity: 12}); err != nil {
+			return
+		}
+		if err = p.WriteSetBegin(thrift.TSetHeader{Element: thrift.I64, Size: len(v.Set)}); err != nil {
+			return
+		}
+		if canonical {
+			var o2 []int
+			if o2, err = dynamic.CanonicalOrder(len(v.Set), func(i3 int, p thrift.TProtocol) (err error) {
+				if err = p.WriteI64(v.Set[i3]); err != nil {
+					return
+				}
+				return
+			}); err != nil {
+				return
+			}
+			for _, i3 := range o2 {
+				if err = p.WriteI64(v.Set[i3]); err != nil {
+					return
+				}
+			}
+		} else {
+			for i4 := range v.Set {
+				if err = p.WriteI64(v.Set[i4]); err != nil {
+					return
+				}
+			}
+		}
+		if err = p.WriteSetEnd(); err != nil {
+			return
+		}
+		if err = p.WriteFieldEnd(); err != nil {
+			return
+		}
+	}
+	if v.Map != nil {
+		for canonical && len(unknown) != 0 && unknown[0].Identity < 13 {
+			if err = unknown[0].Write(p); err != nil {
+				return
+			}
+			unknown = unknown[1:]
+		}
+		if err = p.WriteFieldBegin(thrift.TFieldHeader{Name: "Map", Type: thrift.MAP, Identity: 13}); err != nil {
+			return
+		}
+		if err = p.WriteMapBegin(thrift.TMapHeader{Key: thrift.STRING, Value: thrift.STRUCT, Size: len(v.Map)}); err != nil {
+			return
+		}
+		if canonical {
+			keys5 := make([]string, 0, len(v.Map))
+			for k8 := range v.Map {
+				keys5 = append(keys5, k8)
+			}
+			var o6 []int
+			if o6, err = dynamic.CanonicalOrder(len(keys5), func(i7 int, p thrift.TProtocol) (err error) {
+				if err = p.WriteString(keys5[i7]); err != nil {
+					return
+				}
+				return
+			}); err != nil {
+				return
+			}
+			for _, i7 := range o6 {
+				v9 := v.Map[keys5[i7]]
+				if err = p.WriteString(keys5[i7]); err != nil {
+					return
+				}
+				if err = v9.Write(p); err != nil {
+					return
+				}
+			}
+		} else {
+			for k10, v11 := range v.Map {
+				if err = p.WriteString(k10); err != nil {
+					return
+				}
+				if err = v11.Write(p); err != nil {
+					return
+				}
+			}
+		}
+		if err = p.WriteMapEnd(); err != nil {
+			return
+		}
+		if err = p.WriteFieldEnd(); err != nil {
+			return
+		}
+	}
+	if !v.Inner.thriftIsZero() {
+		for canonical && len(unknown) != 0 && unknown[0].Identity < 14 {
+			if err = unknown[0].Write(p); err != nil {
+				return
+			}
+			unknown = unknown[1:]
+		}
+		if err = p.WriteFieldBegin(thrift.TFieldHeader{Name: "Inner", Type: thrift.STRUCT, Identity: 14}); err != nil {
+			return
+		}
+		if err = v.Inner.Write(p); err != nil {
+			return
+		}
+		if err = p.WriteFieldEnd(); err != nil {
+			return
+		}
+	}
+	if v.Ptr != nil {
+		for canonical && len(unknown) != 0 && unknown[0].Identity < 15 {
+			if err = unknown[0].Write(p); err != nil {
+				return
+			}
+			unknown = unknown[1:]
+		}
+		if err = p.WriteFieldBegin(thrift.TFieldHeader{Name: "Ptr", Type: thrift.STRUCT, Identity: 15}); err != nil {
+			return
+		}
+		if err = v.Ptr.Write(p); err != nil {
+			return
+		}
+		if err = p.WriteFieldEnd(); err != nil {
+			return
+		}
+	}
+	if v.Nested != nil {
+		for canonical && len(unknown) != 0 && unknown[0].Identity < 16 {
+			if err = unknown[0].Write(p); err != nil {
+				return
+			}
+			unknown = unknown[1:]
+		}
+		if err = p.WriteFieldBegin(thrift.TFieldHeader{Name: "Nested", Type: thrift.LIST, Identity: 16}); err != nil {
+			return
+		}
+		if err = p.WriteListBegin(thrift.TListHeader{Element: thrift.SET, Size: len(v.Nested)}); err != nil {
+			return
+		}
+		for i12 := range v.Nested {
+			if err = p.WriteSetBegin(thrift.TSetHeader{Element: thrift.I32, Size: len(v.Nested[i12])}); err != nil {
+				return
+			}
+			if canonical {
+				var o13 []int
+				if o13, err = dynamic.CanonicalOrder(len(v.Nested[i12]), func(i14 int, p thrift.TProtocol) (err error) {
+					if err = p.WriteI32(v.Nested[i12][i14]); err != nil {
+						return
+					}
+					return
+				}); err != nil {
+					return
+				}
+				for _, i14 := range o13 {
+					if err = p.WriteI32(v.Nested[i12][i14]); err != nil {
+						return
+					}
+				}
+			} else {
+				for i15 := range v.Nested[i12] {
+					if err = p.WriteI32(v.Nested[i12][i15]); err != nil {
+						return
+					}
+				}
+			}
+			if err = p.WriteSetEnd(); err != nil {
+				return
+			}
+		}
+		if err = p.WriteListEnd(); err != nil {
+			return
+		}
+		if err = p.WriteFieldEnd(); err != nil {
+			return
+		}
+	}
+	if v.Exception != nil {
+		for canonical && len(unknown) != 0 && unknown[0].Identity < 17 {
+			if err = unknown[0].Write(p); err != nil {
+				return
+			}
+			unknown = unknown[1:]
+		}
+		if err = p.WriteFieldBegin(thrift.TFieldHeader{Name: "Exception", Type: thrift.STRUCT, Identity: 17}); err != nil {
+			return
+		}
+		if err = v.Exception.Write(p); err != nil {
+			return
+		}
+		if err = p.WriteFieldEnd(); err != nil {
+			return
+		}
+	}
+	for _, f := range unknown {
+		if err = f.Write(p); err != nil {
+			return
+		}
+	}
+	if err = p.WriteFieldStop(); err != nil {
+		return
+	}
+	return p.WriteStructEnd()
+}
+
+// Read reads v from p.
+func (v *Bench) Read(p thrift.TProtocol) (err error) {
+	if _, err = p.ReadStructBegin(); err != nil {
+		return
+	}
+	var unknown dynamic.UnknownFields
+	defer func() {
+		v.Unknown = unknown
+	}()
+	var h thrift.TFieldHeader
+	for {
+		if h, err = p.ReadFieldBegin(); err != nil {
+			return
+		}
+		if h.Type == thrift.STOP {
+			break
+		}
+		switch h.Identity {
+		case 1:
+			if h.Type == thrift.BOOL {
+				if v.Flag, err = p.ReadBool(); err != nil {
+					return
+				}
+			} else {
+				var f dynamic.UnknownField
+				if f, err = dynamic.ReadUnknownField(h, p); err != nil {
+					return
+				}
+				unknown = append(unknown, f)
+			}
+		case 2:
+			if h.Type == thrift.BYTE {
+				var r1 byte
+				if r1, err = p.ReadByte(); err != nil {
+					return
+				}
+				v.Byte = int8(r1)
+			} else {
+				var f dynamic.UnknownField
+				if f, err = dynamic.ReadUnknownField(h, p); err != nil {
+					return
+				}
+				unknown = append(unknown, f)
+			}
+		case 3:
+			if h.Type == thrift.I16 {
+				if v.I16, err = p.ReadI16(); err != nil {
+					return
+				}
+			} else {
+				var f dynamic.UnknownField
+				if f, err = dynamic.ReadUnknownField(h, p); err != nil {
+					return
+				}
+				unknown = append(unknown, f)
+			}
+		case 4:
+			if h.Type == thrift.I32 {
+				if v.I32, err = p.ReadI32(); err != nil {
+					return
+				}
+			} else {
+				var f dynamic.UnknownField
+				if f, err = dynamic.ReadUnknownField(h, p); err != nil {
+					return
+				}
+				unknown = append(unknown, f)
+			}
+		case 5:
+			if h.Type == thrift.I64 {
+				if v.I64, err = p.ReadI64(); err != nil {
+					return
+				}
+			} else {
+				var f dynamic.UnknownField
+				if f, err = dynamic.ReadUnknownField(h, p); err != nil {
+					return
+				}
+				unknown = append(unknown, f)
+			}
+		case 6:
+			if h.Type == thrift.I32 {
+				if v.U32, err = p.ReadU32(); err != nil {
+					return
+				}
+			} else {
+				var f dynamic.UnknownField
+				if f, err = dynamic.ReadUnknownField(h, p); err != nil {
+					return
+				}
+				unknown = append(unknown, f)
+			}
+		case 7:
+			if h.Type == thrift.DOUBLE {
+				if v.Double, err = p.ReadDouble(); err != nil {
+					return
+				}
+			} else {
+				var f dynamic.UnknownField
+				if f, err = dynamic.ReadUnknownField(h, p); err != nil {
+					return
+				}
+				unknown = append(unknown, f)
+			}
+		case 8:
+			if h.Type == thrift.STRING {
+				if v.String, err = p.ReadString(); err != nil {
+					return
+				}
+			} else {
+				var f dynamic.UnknownField
+				if f, err = dynamic.ReadUnknownField(h, p); err != nil {
+					return
+				}
+				unknown = append(unknown, f)
+			}
+		case 9:
+			if h.Type == thrift.STRING {
+				if v.Binary, err = p.ReadBinary(); err != nil {
+					return
+				}
+			} else {
+				var f dynamic.UnknownField
+				if f, err = dynamic.ReadUnknownField(h, p); err != nil {
+					return
+				}
+				unknown = append(unknown, f)
+			}
+		case 10:
+			if h.Type == thrift.I32 {
+				var r2 int32
+				if r2, err = p.ReadI32(); err != nil {
+					return
+				}
+				v.Status = Status(r2)
+				if err = dynamic.CheckEnum(v.Status); err != nil {
+					return
+				}
+			} else {
+				var f dynamic.UnknownField
+				if f, err = dynamic.ReadUnknownField(h, p); err != nil {
+					return
+				}
+				unknown = append(unknown, f)
+			}
+		case 11:
+			if h.Type == thrift.LIST {
+				var h3 thrift.TListHeader
+				if h3, err = p.ReadListBegin(); err != nil {
+					return
+				}
+				if h3.Element != thrift.STRING {
+					for i4 := 0; i4 < h3.Size; i4++ {
+						if err = p.Skip(h3.Element); err != nil {
+							return
+						}
+					}
+				} else {
+					if h3.Size > len(v.List) || v.List == nil {
+						v.List = make([]string, h3.Size)
+					} else {
+						v.List = v.List[:h3.Size]
+					}
+					for i4 := 0; i4 < h3.Size; i4++ {
+						if v.List[i4], err = p.ReadString(); err != nil {
+							return
+						}
+					}
+				}
+				if err = p.ReadListEnd(); err != nil {
+					return
+				}
+			} else {
+				var f dynamic.UnknownField
+				if f, err = dynamic.ReadUnknownField(h, p); err != nil {
+					return
+				}
+				unknown = append(unknown, f)
+			}
+		case 12:
+			if h.Type == thrift.SET {
+				var h5 thrift.TSetHeader
+				if h5, err = p.ReadSetBegin(); err != nil {
+					return
+				}
+				if h5.Element != thrift.I64 {
+					for i6 := 0; i6 < h5.Size; i6++ {
+						if err = p.Skip(h5.Element); err != nil {
+							return
+						}
+					}
+				} else {
+					if h5.Size > len(v.Set) || v.Set == nil {
+						v.Set = make([]int64, h5.Size)
+					} else {
+						v.Set = v.Set[:h5.Size]
+					}
+					for i6 := 0; i6 < h5.Size; i6++ {
+						if v.Set[i6], err = p.ReadI64(); err != nil {
+							return
+						}
+					}
+				}
+				if err = p.ReadSetEnd(); err != nil {
+					return
+				}
+			} else {
+				var f dynamic.UnknownField
+				if f, err = dynamic.ReadUnknownField(h, p); err != nil {
+					return
+				}
+				unknown = append(unknown, f)
+			}
+		case 13:
+			if h.Type == thrift.MAP {
+				var h7 thrift.TMapHeader
+				if h7, err = p.ReadMapBegin(); err != nil {
+					return
+				}
+				if h7.Key != thrift.STRING || h7.Value != thrift.STRUCT {
+					for i8 := 0; i8 < h7.Size; i8++ {
+						if err = p.Skip(h7.Key); err != nil {
+							return
+						}
+						if err = p.Skip(h7.Value); err != nil {
+							return
+						}
+					}
+				} else {
+					if v.Map == nil {
+						v.Map = make(map[string]*Inner, h7.Size)
+					}
+					for i8 := 0; i8 < h7.Size; i8++ {
+						var k9 string
+						if k9, err = p.ReadString(); err != nil {
+							return
+						}
+						var v10 *Inner
+						v10 = new(Inner)
+						if err = v10.Read(p); err != nil {
+							return
+						}
+						v.Map[k9] = v10
+					}
+				}
+				if err = p.ReadMapEnd(); err != nil {
+					return
+				}
+			} else {
+				var f dynamic.UnknownField
+				if f, err = dynamic.ReadUnknownField(h, p); err != nil {
+					return
+				}
+				unknown = append(unknown, f)
+			}
+		case 14:
+			if h.Type == thrift.STRUCT {
+				if err = v.Inner.Read(p); err != nil {
+					return
+				}
+			} else {
+				var f dynamic.UnknownField
+				if f, err = dynamic.ReadUnknownField(h, p); err != nil {
+					return
+				}
+				unknown = append(unknown, f)
+			}
+		case 15:
+			if h.Type == thrift.STRUCT {
+				v.Ptr = new(Inner)
+				if err = v.Ptr.Read(p); err != nil {
+					return
+				}
+			} else {
+				var f dynamic.UnknownField
+				if f, err = dynamic.ReadUnknownField(h, p); err != nil {
+					return
+				}
+				unknown = append(unknown, f)
+			}
+		case 16:
+			if h.Type == thrift.LIST {
+				var h11 thrift.TListHeader
+				if h11, err = p.ReadListBegin(); err != nil {
+					return
+				}
+				if h11.Element != thrift.SET {
+					for i12 := 0; i12 < h11.Size; i12++ {
+						if err = p.Skip(h11.Element); err != nil {
+							return
+						}
+					}
+				} else {
+					if h11.Size > len(v.Nested) || v.Nested == nil {
+						v.Nested = make([][]int32, h11.Size)
+					} else {
+						v.Nested = v.Nested[:h11.Size]
+					}
+					for i12 := 0; i12 < h11.Size; i12++ {
+						var h13 thrift.TSetHeader
+						if h13, err = p.ReadSetBegin(); err != nil {
+							return
+						}
+						if h13.Element != thrift.I32 {
+							for i14 := 0; i14 < h13.Size; i14++ {
+								if err = p.Skip(h13.Element); err != nil {
+									return
+								}
+							}
+						} else {
+							if h13.Size > len(v.Nested[i12]) || v.Nested[i12] == nil {
+								v.Nested[i12] = make([]int32, h13.Size)
+							} else {
+								v.Nested[i12] = v.Nested[i12][:h13.Size]
+							}
+							for i14 := 0; i14 < h13.Size; i14++ {
+								if v.Nested[i12][i14], err = p.ReadI32(); err != nil {
+									return
+								}
+							}
+						}
+						if err = p.ReadSetEnd(); err != nil {
+							return
+						}
+					}
+				}
+				if err = p.ReadListEnd(); err != nil {
+					return
+				}
+			} else {
+				var f dynamic.UnknownField
+				if f, err = dynamic.ReadUnknownField(h, p); err != nil {
+					return
+				}
+				unknown = append(unknown, f)
+			}
+		case 17:
+			if h.Type == thrift.STRUCT {
+				v.Exception = new(thrift.TApplicationException)
+				if err = v.Exception.Read(p); err != nil {
+					return
+				}
+			} else {
+				var f dynamic.UnknownField
+				if f, err = dynamic.ReadUnknownField(h, p); err != nil {
+					return
+				}
+				unknown = append(unknown, f)
+			}
+		default:
+			var f dynamic.UnknownField
+			if f, err = dynamic.ReadUnknownField(h, p); err != nil {
+				return
+			}
+			unknown = append(unknown, f)
+		}
+		if err = p.ReadFieldEnd(); err != nil {
+			return
+		}
+	}
+	return p.ReadStructEnd()
+}
+
+// ThriftGenerated marks methods of Unordered as generated by thrift-dynamic-gen.
+func (*Unordered) ThriftGenerated() {}
+
+// Write writes v to p.
+func (v *Unordered) Write(p thrift.TProtocol) (err error) {
+	canonical := thrift.TConfigurationOf(p).IsCanonical()
+	if err = p.WriteStructBegin(thrift.TStructHeader{}); err != nil {
+		return
+	}
+	unknown := v.Unknown
+	if canonical {
+		unknown = unknown.Sorted()
+	}
+	if canonical {
+		if v.A != nil {
+			for canonical && len(unknown) != 0 && unknown[0].Identity < 1 {
+				if err = unknown[0].Write(p); err != nil {
+					return
+				}
+				unknown = unknown[1:]
+			}
+			if err = p.WriteFieldBegin(thrift.TFieldHeader{Name: "A", Type: thrift.SET, Identity: 1}); err != nil {
+				return
+			}
+			if err = p.WriteSetBegin(thrift.TSetHeader{Element: thrift.STRING, Size: len(v.A)}); err != nil {
+				return
+			}
+			if canonical {
+				var o1 []int
+				if o1, err = dynamic.CanonicalOrder(len(v.A), func(i2 int, p thrift.TProtocol) (err error) {
+					if err = p.WriteString(v.A[i2]); err != nil {
+						return
+					}
+					return
+				}); err != nil {
+					return
+				}
+				for _, i2 := range o1 {
+					if err = p.WriteString(v.A[i2]); err != nil {
+						return
+					}
+				}
+			} else {
+				for i3 := range v.A {
+					if err = p.WriteString(v.A[i3]); err != nil {
+						return
+					}
+				}
+			}
+			if err = p.WriteSetEnd(); err != nil {
+				return
+			}
+			if err = p.WriteFieldEnd(); err != nil {
+				return
+			}
+		}
+		if v.B != 0 {
+			for canonical && len(unknown) != 0 && unknown[0].Identity < 3 {
+				if err = unknown[0].Write(p); err != nil {
+					return
+				}
+				unknown = unknown[1:]
+			}
+			if err = p.WriteFieldBegin(thrift.TFieldHeader{Name: "B", Type: thrift.I32, Identity: 3}); err != nil {
+				return
+			}
+			if err = p.WriteI32(v.B); err != nil {
+				return
+			}
+			if err = p.WriteFieldEnd(); err != nil {
+				return
+			}
+		}
+	} else {
+		if v.B != 0 {
+			if err = p.WriteFieldBegin(thrift.TFieldHeader{Name: "B", Type: thrift.I32, Identity: 3}); err != nil {
+				return
+			}
+			if err = p.WriteI32(v.B); err != nil {
+				return
+			}
+			if err = p.WriteFieldEnd(); err != nil {
+				return
+			}
+		}
+		if v.A != nil {
+			if err = p.WriteFieldBegin(thrift.TFieldHeader{Name: "A", Type: thrift.SET, Identity: 1}); err != nil {
+				return
+			}
+			if err = p.WriteSetBegin(thrift.TSetHeader{Element: thrift.STRING, Size: len(v.A)}); err != nil {
+				return
+			}
+			if canonical {
+				var o4 []int
+				if o4, err = dynamic.CanonicalOrder(len(v.A), func(i5 int, p thrift.TProtocol) (err error) {
+					if err = p.WriteString(v.A[i5]); err != nil {
+						return
+					}
+					return
+				}); err != nil {
+					return
+				}
+				for _, i5 := range o4 {
+					if err = p.WriteString(v.A[i5]); err != nil {
+						return
+					}
+				}
+			} else {
+				for i6 := range v.A {
+					if err = p.WriteString(v.A[i6]); err != nil {
+						return
+					}
+				}
+			}
+			if err = p.WriteSetEnd(); err != nil {
+				return
+			}
+			if err = p.WriteFieldEnd(); err != nil {
+				return
+			}
+		}
+	}
+	for _, f := range unknown {
+		if err = f.Write(p); err != nil {
+			return
+		}
+	}
+	if err = p.WriteFieldStop(); err != nil {
+		return
+	}
+	return p.WriteStructEnd()
+}
+
+// Read reads v from p.
+func (v *Unordered) Read(p thrift.TProtocol) (err error) {
+	if _, err = p.ReadStructBegin(); err != nil {
+		return
+	}
+	var unknown dynamic.UnknownFields
+	defer func() {
+		v.Unknown = unknown
+	}()
+	var h thrift.TFieldHeader
+	for {
+		if h, err = p.ReadFieldBegin(); err != nil {
+			return
+		}
+		if h.Type == thrift.STOP {
+			break
+		}
+		switch h.Identity {
+		case 3:
+			if h.Type == thrift.I32 {
+				if v.B, err = p.ReadI32(); err != nil {
+					return
+				}
+			} else {
+				var f dynamic.UnknownField
+				if f, err = dynamic.ReadUnknownField(h, p); err != nil {
+					return
+				}
+				unknown = append(unknown, f)
+			}
+		case 1:
+			if h.Type == thrift.SET {
+				var h1 thrift.TSetHeader
+				if h1, err = p.ReadSetBegin(); err != nil {
+					return
+				}
+				if h1.Element != thrift.STRING {
+					for i2 := 0; i2 < h1.Size; i2++ {
+						if err = p.Skip(h1.Element); err != nil {
+							return
+						}
+					}
+				} else {
+					if h1.Size > len(v.A) || v.A == nil {
+						v.A = make([]string, h1.Size)
+					} else {
+						v.A = v.A[:h1.Size]
+					}
+					for i2 := 0; i2 < h1.Size; i2++ {
+						if v.A[i2], err = p.ReadString(); err != nil {
+							return
+						}
+					}
+				}
+				if err = p.ReadSetEnd(); err != nil {
+					return
+				}
+			} else {
+				var f dynamic.UnknownField
+				if f, err = dynamic.ReadUnknownField(h, p); err != nil {
+					return
+				}
+				unknown = append(unknown, f)
+			}
+		default:
+			var f dynamic.UnknownField
+			if f, err = dynamic.ReadUnknownField(h, p); err != nil {
+				return
+			}
+			unknown = append(unknown, f)
+		}
+		if err = p.ReadFieldEnd(); err != nil {
+			return
+		}
+	}
+	return p.ReadStructEnd()
+}
+
+// thriftIsZero reports whether every field of v is zero.
+func (v *Inner) thriftIsZero() bool {
+	return v.Name == "" &&
+		v.Scores == nil
+}
@@ -0,0 +1,45 @@
+// Package gentest contains types with methods generated by thrift-dynamic-gen,
+// it is used to test and benchmark generated methods against reflection.
+package gentest
+
+import (
+	"github.com/b1avk/thrift/pkg/dynamic"
+	"github.com/b1avk/thrift/pkg/thrift"
+)
+
+//go:generate go run ../../../../cmd/thrift-dynamic-gen -type Inner,Bench,Unordered
+
+type Status int32
+
+type Inner struct {
+	Name   string  `thrift:"1"`
+	Scores []int32 `thrift:"2"`
+}
+
+type Bench struct {
+	Flag      bool                          `thrift:"1"`
+	Byte      int8                          `thrift:"2"`
+	I16       int16                         `thrift:"3"`
+	I32       int32                         `thrift:"4"`
+	I64       int64                         `thrift:"5"`
+	U32       uint32                        `thrift:"6"`
+	Double    float64                       `thrift:"7"`
+	String    string                        `thrift:"8,required"`
+	Binary    []byte                        `thrift:"9"`
+	Status    Status                        `thrift:"10"`
+	List      []string                      `thrift:"11"`
+	Set       []int64                       `thrift:"12,set"`
+	Map       map[string]*Inner             `thrift:"13"`
+	Inner     Inner                         `thrift:"14"`
+	Ptr       *Inner                        `thrift:"15"`
+	Nested    [][]int32                     `thrift:"16,list,set"`
+	Exception *thrift.TApplicationException `thrift:"17"`
+	Unknown   dynamic.UnknownFields         `thrift:"-,unknown"`
+}
+
+// Unordered has fields out of identity order, which are sorted on canonical output.
+type Unordered struct {
+	B       int32                 `thrift:"3"`
+	A       []string              `thrift:"1,set"`
+	Unknown dynamic.UnknownFields `thrift:"-,unknown"`
+}
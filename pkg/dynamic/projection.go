@@ -0,0 +1,178 @@
+package dynamic
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/b1avk/thrift/pkg/thrift"
+)
+
+// Projection a set of field identity paths to decode, other fields are skipped.
+type Projection struct {
+	// fields by identity, nil Projection selects the whole field.
+	fields map[int16]*Projection
+}
+
+// NewProjection returns Projection of paths,
+// path is dot separated list of field identity; for example "1" or "4.2".
+func NewProjection(paths ...string) (*Projection, error) {
+	r := &Projection{make(map[int16]*Projection)}
+	for _, path := range paths {
+		keys := splitPath(path)
+		if len(keys) == 0 {
+			return nil, fmt.Errorf("dynamic: empty path")
+		}
+		x := r
+		for i, k := range keys {
+			id, err := strconv.ParseInt(k, 10, 16)
+			if err != nil {
+				return nil, fmt.Errorf("dynamic: invalid field identity %q", k)
+			}
+			next, ok := x.fields[int16(id)]
+			if ok && next == nil {
+				// the whole field is already selected.
+				break
+			}
+			if i == len(keys)-1 {
+				x.fields[int16(id)] = nil
+				break
+			}
+			if next == nil {
+				next = &Projection{make(map[int16]*Projection)}
+				x.fields[int16(id)] = next
+			}
+			x = next
+		}
+	}
+	return r, nil
+}
+
+// DecodeProjection reads fields of proj from p to v, v must be pointer to struct.
+// fields which are not in proj are skipped and keep their values.
+func DecodeProjection(v interface{}, p thrift.TProtocol, proj *Projection) error {
+	x := reflect.ValueOf(v)
+	if x.Kind() != reflect.Ptr || x.IsNil() || x.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("dynamic: %T must be non-nil pointer to struct", v)
+	}
+	e, _ := projectedStructEncoder(InternalEncoderOf(x.Elem().Type()))
+	return decodeProjection(e, x.Elem(), p, proj)
+}
+
+func decodeProjection(e *structEncoder, v reflect.Value, p thrift.TProtocol, proj *Projection) (err error) {
+	if e.err != nil {
+		return e.err
+	}
+	if _, err = p.ReadStructBegin(); err != nil {
+		return
+	}
+	var h thrift.TFieldHeader
+	for {
+		if h, err = p.ReadFieldBegin(); err != nil {
+			return
+		}
+		if h.Type == thrift.STOP {
+			break
+		}
+		next, selected := proj.fields[h.Identity]
+		f, ok := e.fieldByIdentity[h.Identity]
+		if selected && ok && f.header.Type == h.Type {
+			x, _ := fieldOf(v, f.index, true)
+			if s, ok := projectedStructEncoder(f.InternalEncoder); ok && next != nil {
+				if x.Kind() == reflect.Ptr {
+					if x.IsNil() {
+						x.Set(reflect.New(x.Type().Elem()))
+					}
+					x = x.Elem()
+				}
+				err = decodeProjection(s, x, p, next)
+			} else {
+				err = f.Decode(x, p)
+			}
+		} else {
+			err = p.Skip(h.Type)
+		}
+		if err != nil {
+			return
+		}
+		if err = p.ReadFieldEnd(); err != nil {
+			return
+		}
+	}
+	return p.ReadStructEnd()
+}
+
+// projectedStructEncoder returns reflection based structEncoder of
+// struct or pointer to struct encoder e.
+func projectedStructEncoder(e InternalEncoder) (*structEncoder, bool) {
+	if p, ok := e.(*ptrEncoder); ok {
+		e = p.InternalEncoder
+	}
+	switch e := e.(type) {
+	case *structEncoder:
+		return e, true
+	case *tStructEncoder:
+		s, ok := StructEncoderOf(e.structType).(*structEncoder)
+		return s, ok
+	}
+	return nil, false
+}
+
+// ReadField reads Value at path from struct of p without decoding other fields,
+// path is same as Struct.Get. nested structs of path are read field by field,
+// the rest of path is resolved on the Value of the field.
+func ReadField(p thrift.TProtocol, path string) (Value, error) {
+	keys := splitPath(path)
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("dynamic: empty path")
+	}
+	v, ok, err := readField(p, keys)
+	if err == nil && !ok {
+		err = fmt.Errorf("dynamic: element %q not found", path)
+	}
+	return v, err
+}
+
+// readField reads field keys[0] of struct of p, the rest of the struct is skipped.
+func readField(p thrift.TProtocol, keys []string) (v Value, ok bool, err error) {
+	var id int64
+	if id, err = strconv.ParseInt(keys[0], 10, 16); err != nil {
+		return nil, false, fmt.Errorf("dynamic: invalid field identity %q", keys[0])
+	}
+	if _, err = p.ReadStructBegin(); err != nil {
+		return
+	}
+	var h thrift.TFieldHeader
+	found := false
+	for {
+		if h, err = p.ReadFieldBegin(); err != nil {
+			return
+		}
+		if h.Type == thrift.STOP {
+			break
+		}
+		if h.Identity == int16(id) && !found {
+			found = true
+			if len(keys) > 1 && h.Type == thrift.STRUCT {
+				v, ok, err = readField(p, keys[1:])
+			} else if v, err = ReadValue(h.Type, p); err == nil {
+				ok = true
+				for _, k := range keys[1:] {
+					if v, err = child(v, k); err != nil {
+						return
+					}
+				}
+			}
+		} else {
+			err = p.Skip(h.Type)
+		}
+		if err != nil {
+			return
+		}
+		if err = p.ReadFieldEnd(); err != nil {
+			return
+		}
+	}
+	err = p.ReadStructEnd()
+	return
+}
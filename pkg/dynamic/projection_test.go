@@ -0,0 +1,83 @@
+package dynamic_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/b1avk/thrift/pkg/dynamic"
+	"github.com/b1avk/thrift/pkg/thrift"
+)
+
+type ProjectionInner struct {
+	Name  string  `thrift:"1"`
+	Blob  []byte  `thrift:"2"`
+	Items []int32 `thrift:"3"`
+}
+
+type ProjectionStruct struct {
+	ID    int64                      `thrift:"1"`
+	Blob  []byte                     `thrift:"2"`
+	Inner *ProjectionInner           `thrift:"4"`
+	Map   map[string]ProjectionInner `thrift:"5"`
+}
+
+var projectionValue = ProjectionStruct{
+	ID:    1,
+	Blob:  make([]byte, 1024),
+	Inner: &ProjectionInner{Name: "Hello", Blob: make([]byte, 1024), Items: []int32{1, 2, 3}},
+	Map:   map[string]ProjectionInner{"a": {Name: "World"}},
+}
+
+func encodeProjectionValue(t *testing.T) thrift.TProtocol {
+	p := thrift.NewTCompactProtocol(thrift.NewTMemoryBuffer(), nil)
+	if err := dynamic.ValueEncoderOf(reflect.TypeOf(projectionValue)).Encode(projectionValue, p); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestDecodeProjection(t *testing.T) {
+	proj, err := dynamic.NewProjection("1", "4.1", "4.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v ProjectionStruct
+	if err = dynamic.DecodeProjection(&v, encodeProjectionValue(t), proj); err != nil {
+		t.Fatal(err)
+	}
+	expected := ProjectionStruct{
+		ID:    1,
+		Inner: &ProjectionInner{Name: "Hello", Items: []int32{1, 2, 3}},
+	}
+	if !reflect.DeepEqual(v, expected) {
+		t.Fatalf("unexpected value: %+v", v)
+	}
+	if _, err = dynamic.NewProjection("1.x"); err == nil {
+		t.Fatal("expected error for invalid path")
+	}
+}
+
+func TestReadField(t *testing.T) {
+	for path, expected := range map[string]dynamic.Value{
+		"1":      dynamic.I64(1),
+		"4.1":    dynamic.String("Hello"),
+		"4.3.2":  dynamic.I32(3),
+		"5.a.1":  dynamic.String("World"),
+		"4.3.10": nil,
+		"3":      nil,
+	} {
+		v, err := dynamic.ReadField(encodeProjectionValue(t), path)
+		if expected == nil {
+			if err == nil {
+				t.Fatalf("%v: expected error", path)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v: %v", path, err)
+		}
+		if !reflect.DeepEqual(v, expected) {
+			t.Fatalf("%v: unexpected value %v", path, v)
+		}
+	}
+}
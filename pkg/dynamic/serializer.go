@@ -0,0 +1,69 @@
+package dynamic
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/b1avk/thrift/pkg/thrift"
+)
+
+// Serializer is implemented by thrift.TSerializer and thrift.TSerializerPool.
+type Serializer interface {
+	Serialize(ctx context.Context, msg thrift.TStruct) ([]byte, error)
+}
+
+// Deserializer is implemented by thrift.TDeserializer and thrift.TDeserializerPool.
+type Deserializer interface {
+	Deserialize(ctx context.Context, b []byte, msg thrift.TStruct) error
+}
+
+// Serialize returns encoding of v by s, v is any value supported by ValueEncoder.
+func Serialize(ctx context.Context, s Serializer, v interface{}) ([]byte, error) {
+	x := reflect.ValueOf(v)
+	if !x.IsValid() {
+		return nil, fmt.Errorf("dynamic: cannot serialize nil")
+	}
+	return s.Serialize(ctx, &valueStruct{x, InternalEncoderOf(x.Type())})
+}
+
+// Deserialize reads v from b by d, v must be non-nil pointer.
+func Deserialize(ctx context.Context, d Deserializer, b []byte, v interface{}) error {
+	msg, err := tStructOf(v)
+	if err != nil {
+		return err
+	}
+	return d.Deserialize(ctx, b, msg)
+}
+
+// TStructOf returns thrift.TStruct which reads and writes v,
+// v must be non-nil pointer to value supported by ValueEncoder.
+func TStructOf(v interface{}) thrift.TStruct {
+	msg, err := tStructOf(v)
+	if err != nil {
+		panic(err)
+	}
+	return msg
+}
+
+func tStructOf(v interface{}) (*valueStruct, error) {
+	x := reflect.ValueOf(v)
+	if x.Kind() != reflect.Ptr || x.IsNil() {
+		return nil, fmt.Errorf("dynamic: %T must be non-nil pointer", v)
+	}
+	return &valueStruct{x.Elem(), InternalEncoderOf(x.Elem().Type())}, nil
+}
+
+// valueStruct thrift.TStruct implementation for value v.
+type valueStruct struct {
+	v reflect.Value
+	e InternalEncoder
+}
+
+func (s *valueStruct) Write(p thrift.TProtocol) error {
+	return s.e.Encode(s.v, p)
+}
+
+func (s *valueStruct) Read(p thrift.TProtocol) error {
+	return s.e.Decode(s.v, p)
+}
@@ -0,0 +1,29 @@
+package dynamic_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/b1avk/thrift/pkg/dynamic"
+	"github.com/b1avk/thrift/pkg/thrift"
+)
+
+func TestSerialize(t *testing.T) {
+	ctx := context.Background()
+	f := thrift.NewTCompactProtocolFactory(nil)
+	b, err := dynamic.Serialize(ctx, thrift.NewTSerializerPool(f), projectionValue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v ProjectionStruct
+	if err = dynamic.Deserialize(ctx, thrift.NewTDeserializer(f), b, &v); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v, projectionValue) {
+		t.Fatal("value obtained for serialize and deserialize mismatch")
+	}
+	if err = dynamic.Deserialize(ctx, thrift.NewTDeserializer(f), b, v); err == nil {
+		t.Fatal("expected error for non-pointer value")
+	}
+}
@@ -70,7 +70,7 @@ func parseDynamicField(t reflect.StructField) (f dynamicField, err error) {
 func (f dynamicField) newArgs(args []reflect.Value) *TStruct {
 	v := f.args.Copy()
 	v.New()
-	for i := range f.args.encoder.fieldEncoderLists {
+	for i := 0; i < f.args.typ.NumField(); i++ {
 		v.value.Field(i).Set(args[0])
 		args = args[1:]
 	}
@@ -83,7 +83,7 @@ func (f dynamicField) newResult() *TStruct {
 
 func (f dynamicField) returnResult(res *TStruct, err error) (results []reflect.Value) {
 	if res != nil {
-		for i := range res.encoder.fieldEncoderLists {
+		for i := 0; i < res.typ.NumField(); i++ {
 			results = append(results, res.value.Field(i))
 		}
 	}
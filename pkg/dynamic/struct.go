@@ -1,6 +1,8 @@
 package dynamic
 
 import (
+	"errors"
+	"fmt"
 	"reflect"
 
 	"github.com/b1avk/thrift/pkg/thrift"
@@ -10,16 +12,32 @@ import (
 type TStruct struct {
 	typ     reflect.Type
 	value   reflect.Value
-	encoder *structEncoder
+	encoder InternalEncoder
 }
 
-// NewTStruct returns new TStruct for v.
+// NewTStruct returns new TStruct for v, it panics if v is invalid.
 func NewTStruct(v reflect.Type) *TStruct {
-	mustBe(v, reflect.Struct)
+	s, err := NewTStructFor(v)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// NewTStructFor returns new TStruct for v,
+// it returns TypeErrors if v is not a valid struct.
+func NewTStructFor(v reflect.Type) (*TStruct, error) {
+	if v == nil || v.Kind() != reflect.Struct {
+		return nil, TypeErrors{{fmt.Sprint(v), errors.New("type must be struct")}}
+	}
+	e, err := ValueEncoderFor(v)
+	if err != nil {
+		return nil, err
+	}
 	return &TStruct{
 		typ:     v,
-		encoder: InternalEncoderOf(v).(*structEncoder),
-	}
+		encoder: e.InternalEncoder,
+	}, nil
 }
 
 // New initial value of e.
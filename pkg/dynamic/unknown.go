@@ -0,0 +1,204 @@
+package dynamic
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/b1avk/thrift/pkg/thrift"
+)
+
+// UnknownField a field that has no matching struct field.
+type UnknownField struct {
+	Type     thrift.TType
+	Identity int16
+
+	// Data is value encoded with binary protocol,
+	// which allow to write it back to any protocol.
+	Data []byte
+}
+
+// UnknownFields a list of UnknownField in the order they were read.
+//
+// a struct field of this type tagged with `thrift:"-,unknown"` captures
+// every field that has no matching struct field or a mismatched type on decode and
+// writes them back on encode.
+type UnknownFields []UnknownField
+
+var unknownFieldsType = reflect.TypeOf(UnknownFields(nil))
+
+func isUnknownFieldsTag(tag string) bool {
+	return tag == "-,unknown"
+}
+
+// ReadUnknownField reads value of field h from p.
+func ReadUnknownField(h thrift.TFieldHeader, p thrift.TProtocol) (f UnknownField, err error) {
+	b := thrift.NewTMemoryBuffer()
+	if err = copyValue(h.Type, thrift.NewTBinaryProtocol(b, nil), p); err == nil {
+		f = UnknownField{h.Type, h.Identity, b.Bytes()}
+	}
+	return
+}
+
+// Write writes f as a field to p.
+func (f UnknownField) Write(p thrift.TProtocol) (err error) {
+	b := thrift.NewTMemoryBuffer()
+	b.Write(f.Data)
+	if err = p.WriteFieldBegin(thrift.TFieldHeader{Type: f.Type, Identity: f.Identity}); err == nil {
+		if err = copyValue(f.Type, p, thrift.NewTBinaryProtocol(b, nil)); err == nil {
+			err = p.WriteFieldEnd()
+		}
+	}
+	return
+}
+
+// copyValue reads next v from src and writes it to dst.
+func copyValue(v thrift.TType, dst, src thrift.TProtocol) (err error) {
+	switch v {
+	case thrift.BOOL:
+		var r bool
+		if r, err = src.ReadBool(); err == nil {
+			err = dst.WriteBool(r)
+		}
+	case thrift.BYTE:
+		var r byte
+		if r, err = src.ReadByte(); err == nil {
+			err = dst.WriteByte(r)
+		}
+	case thrift.DOUBLE:
+		var r float64
+		if r, err = src.ReadDouble(); err == nil {
+			err = dst.WriteDouble(r)
+		}
+	case thrift.U16:
+		var r uint16
+		if r, err = src.ReadU16(); err == nil {
+			err = dst.WriteU16(r)
+		}
+	case thrift.I16:
+		var r int16
+		if r, err = src.ReadI16(); err == nil {
+			err = dst.WriteI16(r)
+		}
+	case thrift.U32:
+		var r uint32
+		if r, err = src.ReadU32(); err == nil {
+			err = dst.WriteU32(r)
+		}
+	case thrift.I32:
+		var r int32
+		if r, err = src.ReadI32(); err == nil {
+			err = dst.WriteI32(r)
+		}
+	case thrift.U64:
+		var r uint64
+		if r, err = src.ReadU64(); err == nil {
+			err = dst.WriteU64(r)
+		}
+	case thrift.I64:
+		var r int64
+		if r, err = src.ReadI64(); err == nil {
+			err = dst.WriteI64(r)
+		}
+	case thrift.STRING:
+		var r []byte
+		if r, err = src.ReadBinary(); err == nil {
+			err = dst.WriteBinary(r)
+		}
+	case thrift.STRUCT:
+		var h thrift.TStructHeader
+		if h, err = src.ReadStructBegin(); err != nil {
+			return
+		}
+		if err = dst.WriteStructBegin(h); err != nil {
+			return
+		}
+		var fh thrift.TFieldHeader
+		for {
+			if fh, err = src.ReadFieldBegin(); err != nil {
+				return
+			}
+			if fh.Type == thrift.STOP {
+				break
+			}
+			if err = dst.WriteFieldBegin(fh); err != nil {
+				return
+			}
+			if err = copyValue(fh.Type, dst, src); err != nil {
+				return
+			}
+			if err = src.ReadFieldEnd(); err != nil {
+				return
+			}
+			if err = dst.WriteFieldEnd(); err != nil {
+				return
+			}
+		}
+		if err = src.ReadStructEnd(); err != nil {
+			return
+		}
+		if err = dst.WriteFieldStop(); err == nil {
+			err = dst.WriteStructEnd()
+		}
+	case thrift.MAP:
+		var h thrift.TMapHeader
+		if h, err = src.ReadMapBegin(); err != nil {
+			return
+		}
+		if err = dst.WriteMapBegin(h); err != nil {
+			return
+		}
+		for i := 0; i < h.Size; i++ {
+			if err = copyValue(h.Key, dst, src); err != nil {
+				return
+			}
+			if err = copyValue(h.Value, dst, src); err != nil {
+				return
+			}
+		}
+		if err = src.ReadMapEnd(); err == nil {
+			err = dst.WriteMapEnd()
+		}
+	case thrift.SET:
+		var h thrift.TSetHeader
+		if h, err = src.ReadSetBegin(); err != nil {
+			return
+		}
+		if err = dst.WriteSetBegin(h); err != nil {
+			return
+		}
+		for i := 0; i < h.Size; i++ {
+			if err = copyValue(h.Element, dst, src); err != nil {
+				return
+			}
+		}
+		if err = src.ReadSetEnd(); err == nil {
+			err = dst.WriteSetEnd()
+		}
+	case thrift.LIST:
+		var h thrift.TListHeader
+		if h, err = src.ReadListBegin(); err != nil {
+			return
+		}
+		if err = dst.WriteListBegin(h); err != nil {
+			return
+		}
+		for i := 0; i < h.Size; i++ {
+			if err = copyValue(h.Element, dst, src); err != nil {
+				return
+			}
+		}
+		if err = src.ReadListEnd(); err == nil {
+			err = dst.WriteListEnd()
+		}
+	default:
+		err = thrift.NewTProtocolException(thrift.TProtocolErrorInvalidData, fmt.Sprintf("unexpected TType: %d", v))
+	}
+	return
+}
+
+// Value decodes f.Data to Value.
+func (f UnknownField) Value() (Value, error) {
+	b := thrift.NewTMemoryBuffer()
+	b.Write(f.Data)
+	return ReadValue(f.Type, thrift.NewTBinaryProtocol(b, nil))
+}
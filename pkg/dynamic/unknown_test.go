@@ -0,0 +1,111 @@
+package dynamic_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/b1avk/thrift/pkg/dynamic"
+	"github.com/b1avk/thrift/pkg/thrift"
+)
+
+type UnknownFullStruct struct {
+	Name   string            `thrift:"1"`
+	Flag   bool              `thrift:"2"`
+	Byte   byte              `thrift:"3"`
+	Count  int32             `thrift:"4"`
+	List   []int64           `thrift:"5"`
+	Map    map[string]string `thrift:"6"`
+	Nested *BasicStruct      `thrift:"7"`
+	Double float64           `thrift:"8"`
+}
+
+type UnknownPartialStruct struct {
+	Name    string                `thrift:"1"`
+	Unknown dynamic.UnknownFields `thrift:"-,unknown"`
+	Count   int32                 `thrift:"4"`
+}
+
+func testUnknownFields(t *testing.T, getProtocol GetProtocol) {
+	full := UnknownFullStruct{
+		Name:   "Hello",
+		Flag:   true,
+		Byte:   255,
+		Count:  123,
+		List:   []int64{1, -2, 3},
+		Map:    map[string]string{"Hello": "World"},
+		Nested: &BasicStruct{BooleanTrue: true, String: "Hello Mars"},
+		Double: 0.123,
+	}
+	p := getProtocol()
+	fe := dynamic.ValueEncoderOf(reflect.TypeOf(full))
+	if err := fe.Encode(full, p); err != nil {
+		t.Fatal(err)
+	}
+	var partial UnknownPartialStruct
+	pe := dynamic.ValueEncoderOf(reflect.TypeOf(partial))
+	if err := pe.Decode(&partial, p); err != nil {
+		t.Fatal(err)
+	}
+	if partial.Name != full.Name || partial.Count != full.Count {
+		t.Fatal("known fields mismatch")
+	}
+	if len(partial.Unknown) != 6 {
+		t.Fatalf("must capture 6 unknown fields, got %d", len(partial.Unknown))
+	}
+	if err := pe.Encode(partial, p); err != nil {
+		t.Fatal(err)
+	}
+	var r UnknownFullStruct
+	if err := fe.Decode(&r, p); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(full, r) {
+		t.Fatal("value obtained for round trip mismatch")
+	}
+}
+
+func TestUnknownFieldsBinaryProtocol(t *testing.T) {
+	testUnknownFields(t, func() thrift.TProtocol {
+		return thrift.NewTBinaryProtocol(thrift.NewTMemoryBuffer(), nil)
+	})
+}
+
+func TestUnknownFieldsCompactProtocol(t *testing.T) {
+	testUnknownFields(t, func() thrift.TProtocol {
+		return thrift.NewTCompactProtocol(thrift.NewTMemoryBuffer(), nil)
+	})
+}
+
+type UnknownMismatchStruct struct {
+	Name  string `thrift:"1"`
+	Count string `thrift:"4"`
+}
+
+func TestUnknownFieldsTypeMismatch(t *testing.T) {
+	p := thrift.NewTBinaryProtocol(thrift.NewTMemoryBuffer(), nil)
+	in := UnknownMismatchStruct{Name: "Hello", Count: "123"}
+	if err := dynamic.ValueEncoderOf(reflect.TypeOf(in)).Encode(in, p); err != nil {
+		t.Fatal(err)
+	}
+	var partial UnknownPartialStruct
+	pe := dynamic.ValueEncoderOf(reflect.TypeOf(partial))
+	if err := pe.Decode(&partial, p); err != nil {
+		t.Fatal(err)
+	}
+	if partial.Name != in.Name || partial.Count != 0 {
+		t.Fatalf("known fields mismatch: %+v", partial)
+	}
+	if len(partial.Unknown) != 1 || partial.Unknown[0].Identity != 4 || partial.Unknown[0].Type != thrift.STRING {
+		t.Fatalf("must capture mismatched field, got %+v", partial.Unknown)
+	}
+	if err := pe.Encode(UnknownPartialStruct{Name: partial.Name, Unknown: partial.Unknown}, p); err != nil {
+		t.Fatal(err)
+	}
+	var r UnknownMismatchStruct
+	if err := dynamic.ValueEncoderOf(reflect.TypeOf(r)).Decode(&r, p); err != nil {
+		t.Fatal(err)
+	}
+	if r != in {
+		t.Fatalf("value obtained for round trip mismatch: %+v", r)
+	}
+}
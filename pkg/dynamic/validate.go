@@ -0,0 +1,188 @@
+package dynamic
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// TypeError a problem of type, Path is Go path of the field such as "Foo.Bar[]".
+type TypeError struct {
+	Path string
+	Err  error
+}
+
+func (e *TypeError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e *TypeError) Unwrap() error {
+	return e.Err
+}
+
+// TypeErrors every problem of a type tree reported by Validate.
+type TypeErrors []*TypeError
+
+func (e TypeErrors) Error() string {
+	s := make([]string, len(e))
+	for i, err := range e {
+		s[i] = err.Error()
+	}
+	return strings.Join(s, "; ")
+}
+
+// ValueEncoderFor same as ValueEncoderOf but it validates v first,
+// it returns TypeErrors if v is invalid.
+func ValueEncoderFor(v reflect.Type) (*ValueEncoder, error) {
+	if err := Validate(v); err != nil {
+		return nil, err
+	}
+	return ValueEncoderOf(v), nil
+}
+
+// Validate returns TypeErrors of every problem of v and types it contains,
+// it returns nil if v is valid.
+func Validate(v reflect.Type) error {
+	if v == nil {
+		return TypeErrors{{"nil", errors.New("type is nil")}}
+	}
+	c := &validator{visited: make(map[reflect.Type]bool)}
+	c.validate(v, v.String(), nil)
+	if len(c.errs) != 0 {
+		return c.errs
+	}
+	return nil
+}
+
+// fieldTagOptions options allowed by field tag.
+var fieldTagOptions = map[string]bool{
+	"list":     true,
+	"set":      true,
+	"required": true,
+	"optional": true,
+}
+
+type validator struct {
+	errs    TypeErrors
+	visited map[reflect.Type]bool
+}
+
+func (c *validator) errorf(path, format string, args ...interface{}) {
+	c.errs = append(c.errs, &TypeError{path, fmt.Errorf(format, args...)})
+}
+
+// validate validates v at path, f is tag of the field which contains v.
+func (c *validator) validate(v reflect.Type, path string, f *fieldTag) {
+	switch v.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.String:
+	case reflect.Struct:
+		if !c.visited[v] {
+			c.visited[v] = true
+			c.validateStruct(v, path)
+		}
+	case reflect.Map:
+		c.validate(v.Key(), path+"[key]", f)
+		if !isEmptyStruct(v.Elem()) {
+			c.validate(v.Elem(), path+"[value]", f)
+		}
+	case reflect.Slice:
+		if v.Elem().Kind() != reflect.Uint8 {
+			if f != nil {
+				f.nextIsList()
+			}
+			c.validate(v.Elem(), path+"[]", f)
+		}
+	case reflect.Array:
+		if f != nil {
+			f.nextIsList()
+		}
+		c.validate(v.Elem(), path+"[]", f)
+	case reflect.Ptr:
+		c.validate(v.Elem(), path, f)
+	case reflect.Interface:
+		r, err := interfaceTypesOf(v)
+		if err != nil {
+			c.errorf(path, "%v", err)
+			return
+		}
+		for id, t := range r.byIdentity {
+			c.validate(t, path+"("+strconv.Itoa(int(id))+")", nil)
+		}
+	default:
+		c.errorf(path, "unsupported type %v", v)
+	}
+}
+
+func (c *validator) validateStruct(v reflect.Type, path string) {
+	ids := make(map[int16]string)
+	unknown := ""
+	var walk func(t reflect.Type, path string, visiting map[reflect.Type]bool)
+	walk = func(t reflect.Type, path string, visiting map[reflect.Type]bool) {
+		visiting[t] = true
+		defer delete(visiting, t)
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			fpath := path + "." + sf.Name
+			tag, ok := sf.Tag.Lookup("thrift")
+			if !ok {
+				if e := embeddedStructOf(sf); e != nil {
+					if visiting[e] {
+						c.errorf(fpath, "embedded struct %v is recursive", e)
+					} else if sf.Type.Kind() == reflect.Ptr && sf.PkgPath != "" {
+						c.errorf(fpath, "embedded field must be exported")
+					} else {
+						walk(e, fpath, visiting)
+					}
+				}
+				continue
+			}
+			if isUnknownFieldsTag(tag) {
+				if sf.Type != unknownFieldsType {
+					c.errorf(fpath, "field must be UnknownFields")
+				} else if unknown != "" {
+					c.errorf(fpath, "unknown fields already defined by %v", unknown)
+				}
+				unknown = fpath
+				continue
+			}
+			if sf.PkgPath != "" {
+				c.errorf(fpath, "tagged field must be exported")
+				continue
+			}
+			ft, err := parseFieldTag(tag)
+			if err != nil {
+				if strings.Split(tag, ",")[0] != "-" {
+					c.errorf(fpath, "bad tag %q: %v", tag, err)
+				}
+				continue
+			}
+			if ft.identity < math.MinInt16 || ft.identity > math.MaxInt16 {
+				c.errorf(fpath, "field identity %v out of range", ft.identity)
+				continue
+			}
+			for _, opt := range ft.contains {
+				if !fieldTagOptions[opt] {
+					c.errorf(fpath, "bad tag %q: unknown option %q", tag, opt)
+				}
+			}
+			if ft.contain("required") && ft.contain("optional") {
+				c.errorf(fpath, "bad tag %q: both required and optional", tag)
+			}
+			if other, ok := ids[int16(ft.identity)]; ok {
+				c.errorf(fpath, "field identity %v already used by %v", ft.identity, other)
+			} else {
+				ids[int16(ft.identity)] = fpath
+			}
+			c.validate(sf.Type, fpath, &ft)
+			if n := len(ft.nextList) - ft.nextListIndex; n > 0 {
+				c.errorf(fpath, "%v list or set hints do not match a list or set", n)
+			}
+		}
+	}
+	walk(v, path, make(map[reflect.Type]bool))
+}
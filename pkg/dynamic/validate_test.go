@@ -0,0 +1,74 @@
+package dynamic_test
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/b1avk/thrift/pkg/dynamic"
+)
+
+type InvalidNested struct {
+	Values []int32 `thrift:"1,list,set"`
+	Bytes  []byte  `thrift:"2,set"`
+}
+
+type InvalidStruct struct {
+	BadTag    string              `thrift:"x"`
+	Skipped   string              `thrift:"-"`
+	Range     int32               `thrift:"40000"`
+	Option    int32               `thrift:"3,sett"`
+	Both      int32               `thrift:"4,required,optional"`
+	First     int32               `thrift:"5"`
+	Duplicate int32               `thrift:"5"`
+	Complex   complex64           `thrift:"6"`
+	Nested    *InvalidNested      `thrift:"7"`
+	Map       map[string][]func() `thrift:"8"`
+	Error     error               `thrift:"9"`
+	EmbeddedBase
+	Name string `thrift:"2"`
+}
+
+func TestValidate(t *testing.T) {
+	err := dynamic.Validate(reflect.TypeOf(InvalidStruct{}))
+	var errs dynamic.TypeErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected TypeErrors, got %v", err)
+	}
+	var paths []string
+	for _, e := range errs {
+		paths = append(paths, e.Path)
+	}
+	sort.Strings(paths)
+	expected := []string{
+		"dynamic_test.InvalidStruct.BadTag",
+		"dynamic_test.InvalidStruct.Both",
+		"dynamic_test.InvalidStruct.Complex",
+		"dynamic_test.InvalidStruct.Duplicate",
+		"dynamic_test.InvalidStruct.Error",
+		"dynamic_test.InvalidStruct.Map[value][]",
+		"dynamic_test.InvalidStruct.Name",
+		"dynamic_test.InvalidStruct.Nested.Bytes",
+		"dynamic_test.InvalidStruct.Nested.Values",
+		"dynamic_test.InvalidStruct.Option",
+		"dynamic_test.InvalidStruct.Range",
+	}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Fatalf("unexpected paths:\n%v\n%v", paths, err)
+	}
+}
+
+func TestValueEncoderFor(t *testing.T) {
+	for _, v := range []interface{}{BasicStruct{}, ExtendedStruct{}, EnumStruct{}, UnknownFullStruct{}} {
+		if _, err := dynamic.ValueEncoderFor(reflect.TypeOf(v)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := dynamic.ValueEncoderFor(reflect.TypeOf(ConflictStruct{})); err == nil {
+		t.Fatal("expected error for ConflictStruct")
+	}
+	if _, err := dynamic.NewTStructFor(reflect.TypeOf(0)); err == nil {
+		t.Fatal("expected error for non-struct type")
+	}
+}
@@ -0,0 +1,607 @@
+package dynamic
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/b1avk/thrift/pkg/thrift"
+)
+
+// Value a schema-less thrift value.
+// it is one of Bool, Byte, Double, U16, I16, U32, I32, U64, I64, String,
+// *Struct, *Map, *Set or *List.
+type Value interface {
+	// Type returns thrift.TType of Value.
+	Type() thrift.TType
+
+	// Write writes value to p.
+	Write(p thrift.TProtocol) error
+}
+
+// Bool a BOOL Value.
+type Bool bool
+
+// Byte a BYTE Value.
+type Byte byte
+
+// Double a DOUBLE Value.
+type Double float64
+
+// U16 an U16 Value.
+type U16 uint16
+
+// I16 an I16 Value.
+type I16 int16
+
+// U32 an U32 Value.
+type U32 uint32
+
+// I32 an I32 Value.
+type I32 int32
+
+// U64 an U64 Value.
+type U64 uint64
+
+// I64 an I64 Value.
+type I64 int64
+
+// String a STRING Value, it also holds binary.
+type String string
+
+// Field a field of Struct.
+type Field struct {
+	Identity int16
+	Value    Value
+}
+
+// Struct a STRUCT Value, it keeps fields in the order they were read.
+// *Struct also implements thrift.TStruct.
+type Struct struct {
+	Fields []Field
+}
+
+// MapEntry an entry of Map.
+type MapEntry struct {
+	Key, Value Value
+}
+
+// Map a MAP Value, it keeps entries in the order they were read.
+type Map struct {
+	Key, Value thrift.TType
+	Entries    []MapEntry
+}
+
+// Set a SET Value.
+type Set struct {
+	Element thrift.TType
+	Values  []Value
+}
+
+// List a LIST Value.
+type List struct {
+	Element thrift.TType
+	Values  []Value
+}
+
+// ReadValue reads next v from p without schema.
+func ReadValue(v thrift.TType, p thrift.TProtocol) (r Value, err error) {
+	switch v {
+	case thrift.BOOL:
+		var x bool
+		x, err = p.ReadBool()
+		r = Bool(x)
+	case thrift.BYTE:
+		var x byte
+		x, err = p.ReadByte()
+		r = Byte(x)
+	case thrift.DOUBLE:
+		var x float64
+		x, err = p.ReadDouble()
+		r = Double(x)
+	case thrift.U16:
+		var x uint16
+		x, err = p.ReadU16()
+		r = U16(x)
+	case thrift.I16:
+		var x int16
+		x, err = p.ReadI16()
+		r = I16(x)
+	case thrift.U32:
+		var x uint32
+		x, err = p.ReadU32()
+		r = U32(x)
+	case thrift.I32:
+		var x int32
+		x, err = p.ReadI32()
+		r = I32(x)
+	case thrift.U64:
+		var x uint64
+		x, err = p.ReadU64()
+		r = U64(x)
+	case thrift.I64:
+		var x int64
+		x, err = p.ReadI64()
+		r = I64(x)
+	case thrift.STRING:
+		var x []byte
+		x, err = p.ReadBinary()
+		r = String(x)
+	case thrift.STRUCT:
+		s := new(Struct)
+		err = s.Read(p)
+		r = s
+	case thrift.MAP:
+		m := new(Map)
+		err = m.read(p)
+		r = m
+	case thrift.SET:
+		s := new(Set)
+		err = s.read(p)
+		r = s
+	case thrift.LIST:
+		l := new(List)
+		err = l.read(p)
+		r = l
+	default:
+		err = thrift.NewTProtocolException(thrift.TProtocolErrorInvalidData, fmt.Sprintf("unexpected TType: %d", v))
+	}
+	return
+}
+
+// Type returns thrift.BOOL.
+func (Bool) Type() thrift.TType { return thrift.BOOL }
+
+// Write writes v to p.
+func (v Bool) Write(p thrift.TProtocol) error { return p.WriteBool(bool(v)) }
+
+// Type returns thrift.BYTE.
+func (Byte) Type() thrift.TType { return thrift.BYTE }
+
+// Write writes v to p.
+func (v Byte) Write(p thrift.TProtocol) error { return p.WriteByte(byte(v)) }
+
+// Type returns thrift.DOUBLE.
+func (Double) Type() thrift.TType { return thrift.DOUBLE }
+
+// Write writes v to p.
+func (v Double) Write(p thrift.TProtocol) error { return p.WriteDouble(float64(v)) }
+
+// Type returns thrift.U16.
+func (U16) Type() thrift.TType { return thrift.U16 }
+
+// Write writes v to p.
+func (v U16) Write(p thrift.TProtocol) error { return p.WriteU16(uint16(v)) }
+
+// Type returns thrift.I16.
+func (I16) Type() thrift.TType { return thrift.I16 }
+
+// Write writes v to p.
+func (v I16) Write(p thrift.TProtocol) error { return p.WriteI16(int16(v)) }
+
+// Type returns thrift.U32.
+func (U32) Type() thrift.TType { return thrift.U32 }
+
+// Write writes v to p.
+func (v U32) Write(p thrift.TProtocol) error { return p.WriteU32(uint32(v)) }
+
+// Type returns thrift.I32.
+func (I32) Type() thrift.TType { return thrift.I32 }
+
+// Write writes v to p.
+func (v I32) Write(p thrift.TProtocol) error { return p.WriteI32(int32(v)) }
+
+// Type returns thrift.U64.
+func (U64) Type() thrift.TType { return thrift.U64 }
+
+// Write writes v to p.
+func (v U64) Write(p thrift.TProtocol) error { return p.WriteU64(uint64(v)) }
+
+// Type returns thrift.I64.
+func (I64) Type() thrift.TType { return thrift.I64 }
+
+// Write writes v to p.
+func (v I64) Write(p thrift.TProtocol) error { return p.WriteI64(int64(v)) }
+
+// Type returns thrift.STRING.
+func (String) Type() thrift.TType { return thrift.STRING }
+
+// Write writes v to p.
+func (v String) Write(p thrift.TProtocol) error { return p.WriteBinary([]byte(v)) }
+
+// Type returns thrift.STRUCT.
+func (*Struct) Type() thrift.TType { return thrift.STRUCT }
+
+// Write writes s to p.
+func (s *Struct) Write(p thrift.TProtocol) (err error) {
+	if err = p.WriteStructBegin(thrift.TStructHeader{}); err != nil {
+		return
+	}
+	for _, f := range s.Fields {
+		if err = p.WriteFieldBegin(thrift.TFieldHeader{Type: f.Value.Type(), Identity: f.Identity}); err != nil {
+			return
+		}
+		if err = f.Value.Write(p); err != nil {
+			return
+		}
+		if err = p.WriteFieldEnd(); err != nil {
+			return
+		}
+	}
+	if err = p.WriteFieldStop(); err == nil {
+		err = p.WriteStructEnd()
+	}
+	return
+}
+
+// Read reads s from p.
+func (s *Struct) Read(p thrift.TProtocol) (err error) {
+	s.Fields = nil
+	if _, err = p.ReadStructBegin(); err != nil {
+		return
+	}
+	var h thrift.TFieldHeader
+	for {
+		if h, err = p.ReadFieldBegin(); err != nil {
+			return
+		}
+		if h.Type == thrift.STOP {
+			break
+		}
+		f := Field{Identity: h.Identity}
+		if f.Value, err = ReadValue(h.Type, p); err != nil {
+			return
+		}
+		s.Fields = append(s.Fields, f)
+		if err = p.ReadFieldEnd(); err != nil {
+			return
+		}
+	}
+	err = p.ReadStructEnd()
+	return
+}
+
+// Field returns value of field id.
+func (s *Struct) Field(id int16) (Value, bool) {
+	for _, f := range s.Fields {
+		if f.Identity == id {
+			return f.Value, true
+		}
+	}
+	return nil, false
+}
+
+// SetField sets value of field id, it appends new field if not exists.
+func (s *Struct) SetField(id int16, v Value) {
+	for i, f := range s.Fields {
+		if f.Identity == id {
+			s.Fields[i].Value = v
+			return
+		}
+	}
+	s.Fields = append(s.Fields, Field{id, v})
+}
+
+// Get returns Value at path.
+// path is dot separated list of field identity for Struct,
+// index for List and Set, or key for Map; for example "1.4.2".
+func (s *Struct) Get(path string) (v Value, err error) {
+	v = s
+	for _, k := range splitPath(path) {
+		if v, err = child(v, k); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Set sets x at path of s.
+// the last element of path may refers to a non-existing struct field or map key.
+func (s *Struct) Set(path string, x Value) (err error) {
+	keys := splitPath(path)
+	if len(keys) == 0 {
+		return fmt.Errorf("dynamic: empty path")
+	}
+	var v Value = s
+	for _, k := range keys[:len(keys)-1] {
+		if v, err = child(v, k); err != nil {
+			return
+		}
+	}
+	return setChild(v, keys[len(keys)-1], x)
+}
+
+// MarshalJSON returns JSON encoding of ToInterface(s).
+func (s *Struct) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ToInterface(s))
+}
+
+// Type returns thrift.MAP.
+func (*Map) Type() thrift.TType { return thrift.MAP }
+
+// Write writes m to p.
+func (m *Map) Write(p thrift.TProtocol) (err error) {
+	if err = p.WriteMapBegin(thrift.TMapHeader{Key: m.Key, Value: m.Value, Size: len(m.Entries)}); err != nil {
+		return
+	}
+	for _, e := range m.Entries {
+		if err = e.Key.Write(p); err != nil {
+			return
+		}
+		if err = e.Value.Write(p); err != nil {
+			return
+		}
+	}
+	return p.WriteMapEnd()
+}
+
+func (m *Map) read(p thrift.TProtocol) (err error) {
+	var h thrift.TMapHeader
+	if h, err = p.ReadMapBegin(); err != nil {
+		return
+	}
+	m.Key, m.Value = h.Key, h.Value
+	m.Entries = make([]MapEntry, h.Size)
+	for i := range m.Entries {
+		if m.Entries[i].Key, err = ReadValue(h.Key, p); err != nil {
+			return
+		}
+		if m.Entries[i].Value, err = ReadValue(h.Value, p); err != nil {
+			return
+		}
+	}
+	return p.ReadMapEnd()
+}
+
+// Type returns thrift.SET.
+func (*Set) Type() thrift.TType { return thrift.SET }
+
+// Write writes s to p.
+func (s *Set) Write(p thrift.TProtocol) (err error) {
+	if err = p.WriteSetBegin(thrift.TSetHeader{Element: s.Element, Size: len(s.Values)}); err != nil {
+		return
+	}
+	for _, v := range s.Values {
+		if err = v.Write(p); err != nil {
+			return
+		}
+	}
+	return p.WriteSetEnd()
+}
+
+func (s *Set) read(p thrift.TProtocol) (err error) {
+	var h thrift.TSetHeader
+	if h, err = p.ReadSetBegin(); err != nil {
+		return
+	}
+	s.Element = h.Element
+	if s.Values, err = readValues(h.Element, h.Size, p); err != nil {
+		return
+	}
+	return p.ReadSetEnd()
+}
+
+// Type returns thrift.LIST.
+func (*List) Type() thrift.TType { return thrift.LIST }
+
+// Write writes l to p.
+func (l *List) Write(p thrift.TProtocol) (err error) {
+	if err = p.WriteListBegin(thrift.TListHeader{Element: l.Element, Size: len(l.Values)}); err != nil {
+		return
+	}
+	for _, v := range l.Values {
+		if err = v.Write(p); err != nil {
+			return
+		}
+	}
+	return p.WriteListEnd()
+}
+
+func (l *List) read(p thrift.TProtocol) (err error) {
+	var h thrift.TListHeader
+	if h, err = p.ReadListBegin(); err != nil {
+		return
+	}
+	l.Element = h.Element
+	if l.Values, err = readValues(h.Element, h.Size, p); err != nil {
+		return
+	}
+	return p.ReadListEnd()
+}
+
+func readValues(t thrift.TType, n int, p thrift.TProtocol) (r []Value, err error) {
+	r = make([]Value, n)
+	for i := range r {
+		if r[i], err = ReadValue(t, p); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// ToInterface converts v to Go value.
+// Struct becomes map[string]interface{} keyed by field identity,
+// Map becomes map[string]interface{} keyed by formatted key,
+// List and Set become []interface{} and the rest become their Go primitive.
+func ToInterface(v Value) interface{} {
+	switch v := v.(type) {
+	case Bool:
+		return bool(v)
+	case Byte:
+		return byte(v)
+	case Double:
+		return float64(v)
+	case U16:
+		return uint16(v)
+	case I16:
+		return int16(v)
+	case U32:
+		return uint32(v)
+	case I32:
+		return int32(v)
+	case U64:
+		return uint64(v)
+	case I64:
+		return int64(v)
+	case String:
+		return string(v)
+	case *Struct:
+		r := make(map[string]interface{}, len(v.Fields))
+		for _, f := range v.Fields {
+			r[strconv.Itoa(int(f.Identity))] = ToInterface(f.Value)
+		}
+		return r
+	case *Map:
+		r := make(map[string]interface{}, len(v.Entries))
+		for _, e := range v.Entries {
+			r[formatKey(e.Key)] = ToInterface(e.Value)
+		}
+		return r
+	case *Set:
+		return valuesToInterface(v.Values)
+	case *List:
+		return valuesToInterface(v.Values)
+	}
+	return nil
+}
+
+func valuesToInterface(v []Value) []interface{} {
+	r := make([]interface{}, len(v))
+	for i, e := range v {
+		r[i] = ToInterface(e)
+	}
+	return r
+}
+
+func formatKey(v Value) string {
+	switch v := v.(type) {
+	case String:
+		return string(v)
+	case Bool, Byte, Double, U16, I16, U32, I32, U64, I64:
+		return fmt.Sprint(v)
+	}
+	b, _ := json.Marshal(ToInterface(v))
+	return string(b)
+}
+
+func splitPath(path string) []string {
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, ".")
+}
+
+func child(v Value, k string) (Value, error) {
+	switch v := v.(type) {
+	case *Struct:
+		id, err := strconv.ParseInt(k, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("dynamic: invalid field identity %q", k)
+		}
+		if r, ok := v.Field(int16(id)); ok {
+			return r, nil
+		}
+	case *Map:
+		for _, e := range v.Entries {
+			if formatKey(e.Key) == k {
+				return e.Value, nil
+			}
+		}
+	case *Set:
+		return index(v.Values, k)
+	case *List:
+		return index(v.Values, k)
+	default:
+		return nil, fmt.Errorf("dynamic: %T has no element %q", v, k)
+	}
+	return nil, fmt.Errorf("dynamic: element %q not found", k)
+}
+
+func index(v []Value, k string) (Value, error) {
+	i, err := strconv.Atoi(k)
+	if err != nil || i < 0 || i >= len(v) {
+		return nil, fmt.Errorf("dynamic: invalid index %q", k)
+	}
+	return v[i], nil
+}
+
+func setChild(v Value, k string, x Value) error {
+	switch v := v.(type) {
+	case *Struct:
+		id, err := strconv.ParseInt(k, 10, 16)
+		if err != nil {
+			return fmt.Errorf("dynamic: invalid field identity %q", k)
+		}
+		v.SetField(int16(id), x)
+	case *Map:
+		if x.Type() != v.Value {
+			return fmt.Errorf("dynamic: map value must be %d not %d", v.Value, x.Type())
+		}
+		for i, e := range v.Entries {
+			if formatKey(e.Key) == k {
+				v.Entries[i].Value = x
+				return nil
+			}
+		}
+		key, err := parseKey(v.Key, k)
+		if err != nil {
+			return err
+		}
+		v.Entries = append(v.Entries, MapEntry{key, x})
+	case *Set:
+		return setIndex(v.Element, v.Values, k, x)
+	case *List:
+		return setIndex(v.Element, v.Values, k, x)
+	default:
+		return fmt.Errorf("dynamic: %T has no element %q", v, k)
+	}
+	return nil
+}
+
+func setIndex(t thrift.TType, v []Value, k string, x Value) error {
+	if x.Type() != t {
+		return fmt.Errorf("dynamic: element must be %d not %d", t, x.Type())
+	}
+	i, err := strconv.Atoi(k)
+	if err != nil || i < 0 || i >= len(v) {
+		return fmt.Errorf("dynamic: invalid index %q", k)
+	}
+	v[i] = x
+	return nil
+}
+
+func parseKey(t thrift.TType, k string) (Value, error) {
+	switch t {
+	case thrift.STRING:
+		return String(k), nil
+	case thrift.BOOL:
+		v, err := strconv.ParseBool(k)
+		return Bool(v), err
+	case thrift.DOUBLE:
+		v, err := strconv.ParseFloat(k, 64)
+		return Double(v), err
+	case thrift.BYTE:
+		v, err := strconv.ParseUint(k, 10, 8)
+		return Byte(v), err
+	case thrift.U16:
+		v, err := strconv.ParseUint(k, 10, 16)
+		return U16(v), err
+	case thrift.I16:
+		v, err := strconv.ParseInt(k, 10, 16)
+		return I16(v), err
+	case thrift.U32:
+		v, err := strconv.ParseUint(k, 10, 32)
+		return U32(v), err
+	case thrift.I32:
+		v, err := strconv.ParseInt(k, 10, 32)
+		return I32(v), err
+	case thrift.U64:
+		v, err := strconv.ParseUint(k, 10, 64)
+		return U64(v), err
+	case thrift.I64:
+		v, err := strconv.ParseInt(k, 10, 64)
+		return I64(v), err
+	}
+	return nil, fmt.Errorf("dynamic: can not parse map key of TType %d", t)
+}
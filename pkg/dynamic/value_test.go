@@ -0,0 +1,112 @@
+package dynamic_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/b1avk/thrift/pkg/dynamic"
+	"github.com/b1avk/thrift/pkg/thrift"
+)
+
+type GetProtocolOf func(t thrift.TTransport) thrift.TProtocol
+
+var valueTestValue = UnknownFullStruct{
+	Name:   "Hello",
+	Flag:   true,
+	Byte:   255,
+	Count:  123,
+	List:   []int64{1, -2, 3},
+	Map:    map[string]string{"Hello": "World"},
+	Nested: &BasicStruct{BooleanTrue: true, String: "Hello Mars", Set: []string{"A", "B"}},
+	Double: 0.123,
+}
+
+func testValueRoundTrip(t *testing.T, getProtocol GetProtocolOf) {
+	in := thrift.NewTMemoryBuffer()
+	if err := dynamic.ValueEncoderOf(reflect.TypeOf(valueTestValue)).Encode(valueTestValue, getProtocol(in)); err != nil {
+		t.Fatal(err)
+	}
+	expected := append([]byte(nil), in.Bytes()...)
+	v, err := dynamic.ReadValue(thrift.STRUCT, getProtocol(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := thrift.NewTMemoryBuffer()
+	if err := v.Write(getProtocol(out)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(expected, out.Bytes()) {
+		t.Fatalf("bytes mismatch\n%x\n%x", expected, out.Bytes())
+	}
+}
+
+func TestValueRoundTripBinaryProtocol(t *testing.T) {
+	testValueRoundTrip(t, func(b thrift.TTransport) thrift.TProtocol {
+		return thrift.NewTBinaryProtocol(b, nil)
+	})
+}
+
+func TestValueRoundTripCompactProtocol(t *testing.T) {
+	testValueRoundTrip(t, func(b thrift.TTransport) thrift.TProtocol {
+		return thrift.NewTCompactProtocol(b, nil)
+	})
+}
+
+func TestValuePath(t *testing.T) {
+	p := thrift.NewTBinaryProtocol(thrift.NewTMemoryBuffer(), nil)
+	if err := dynamic.ValueEncoderOf(reflect.TypeOf(valueTestValue)).Encode(valueTestValue, p); err != nil {
+		t.Fatal(err)
+	}
+	s := new(dynamic.Struct)
+	if err := s.Read(p); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := s.Get("7.4"); err != nil || v != dynamic.String("Hello Mars") {
+		t.Fatal(`Get("7.4") must returns "Hello Mars"`, v, err)
+	}
+	if v, err := s.Get("5.1"); err != nil || v != dynamic.I64(-2) {
+		t.Fatal(`Get("5.1") must returns -2`, v, err)
+	}
+	if v, err := s.Get("6.Hello"); err != nil || v != dynamic.String("World") {
+		t.Fatal(`Get("6.Hello") must returns "World"`, v, err)
+	}
+	if _, err := s.Get("9"); err == nil {
+		t.Fatal(`Get("9") must error`)
+	}
+	if err := s.Set("7.4", dynamic.String("Hello Venus")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("6.Hi", dynamic.String("Mars")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("5.1", dynamic.String("-2")); err == nil {
+		t.Fatal("Set must error on mismatch element type")
+	}
+	if err := s.Write(p); err != nil {
+		t.Fatal(err)
+	}
+	var r UnknownFullStruct
+	if err := dynamic.ValueEncoderOf(reflect.TypeOf(r)).Decode(&r, p); err != nil {
+		t.Fatal(err)
+	}
+	if r.Nested.String != "Hello Venus" || r.Map["Hi"] != "Mars" {
+		t.Fatal("value obtained after Set mismatch")
+	}
+}
+
+func TestValueJSON(t *testing.T) {
+	s := &dynamic.Struct{Fields: []dynamic.Field{
+		{1, dynamic.String("Hello")},
+		{2, &dynamic.List{Element: thrift.I32, Values: []dynamic.Value{dynamic.I32(1), dynamic.I32(2)}}},
+		{3, &dynamic.Map{Key: thrift.I16, Value: thrift.BOOL, Entries: []dynamic.MapEntry{{dynamic.I16(1), dynamic.Bool(true)}}}},
+	}}
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"1":"Hello","2":[1,2],"3":{"1":true}}` {
+		t.Fatal("unexpected JSON", string(b))
+	}
+}
@@ -0,0 +1,2 @@
+// Package idl contains a parser of Apache Thrift IDL.
+package idl
@@ -0,0 +1,245 @@
+package idl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Document a parsed thrift file.
+type Document struct {
+	Includes   []string
+	Namespaces map[string]string
+	Typedefs   []*Typedef
+	Constants  []*Constant
+	Enums      []*Enum
+	Structs    []*Struct
+	Services   []*Service
+
+	// Included documents keyed by include name, it is populated by ParseFile.
+	Included map[string]*Document
+}
+
+// Type a reference of type.
+// Name is base type name, container name (map, set, list) or identifier.
+type Type struct {
+	Name             string
+	KeyType, ValType *Type
+}
+
+// Typedef a type alias.
+type Typedef struct {
+	Name string
+	Type *Type
+}
+
+// Constant a constant definition, Value is kept as unparsed text.
+type Constant struct {
+	Name  string
+	Type  *Type
+	Value string
+}
+
+// EnumValue a named value of Enum.
+type EnumValue struct {
+	Name  string
+	Value int32
+}
+
+// Enum an enum definition.
+type Enum struct {
+	Name   string
+	Values []*EnumValue
+}
+
+// StructKind kind of Struct.
+type StructKind byte
+
+const (
+	KindStruct StructKind = iota
+	KindUnion
+	KindException
+)
+
+// Requiredness requiredness of Field.
+type Requiredness byte
+
+const (
+	RequirednessDefault Requiredness = iota
+	RequirednessRequired
+	RequirednessOptional
+)
+
+// Field a field of Struct or a argument of Function.
+type Field struct {
+	ID           int16
+	Name         string
+	Type         *Type
+	Requiredness Requiredness
+	Default      string
+}
+
+// Struct a struct, union or exception definition.
+type Struct struct {
+	Name   string
+	Kind   StructKind
+	Fields []*Field
+}
+
+// Function a function of Service, ReturnType is nil for void.
+type Function struct {
+	Name       string
+	Oneway     bool
+	ReturnType *Type
+	Arguments  []*Field
+	Exceptions []*Field
+}
+
+// Service a service definition.
+type Service struct {
+	Name      string
+	Extends   string
+	Functions []*Function
+}
+
+// IsBaseType returns true if name is a base type.
+func IsBaseType(name string) bool {
+	switch name {
+	case "bool", "byte", "i8", "i16", "i32", "i64", "double", "string", "binary", "uuid":
+		return true
+	}
+	return false
+}
+
+// IsContainerType returns true if name is a container type.
+func IsContainerType(name string) bool {
+	return name == "map" || name == "set" || name == "list"
+}
+
+// Lookup returns document and unqualified name of name.
+// qualified name such as "shared.Info" is looked up in d.Included.
+func (d *Document) Lookup(name string) (*Document, string) {
+	if i := strings.IndexByte(name, '.'); i != -1 {
+		if inc, ok := d.Included[name[:i]]; ok {
+			return inc, name[i+1:]
+		}
+	}
+	return d, name
+}
+
+// Typedef returns Typedef by name.
+func (d *Document) Typedef(name string) *Typedef {
+	d, name = d.Lookup(name)
+	for _, v := range d.Typedefs {
+		if v.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// Enum returns Enum by name.
+func (d *Document) Enum(name string) *Enum {
+	d, name = d.Lookup(name)
+	for _, v := range d.Enums {
+		if v.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// Struct returns Struct by name.
+func (d *Document) Struct(name string) *Struct {
+	d, name = d.Lookup(name)
+	for _, v := range d.Structs {
+		if v.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// Service returns Service by name.
+func (d *Document) Service(name string) *Service {
+	d, name = d.Lookup(name)
+	for _, v := range d.Services {
+		if v.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// Resolve follows typedefs of t and returns the document that
+// defines the resulting type with it.
+// it stops at the typedef that closes a cycle of typedefs, see Parse.
+func (d *Document) Resolve(t *Type) (*Document, *Type) {
+	d, t, _ = d.resolve(t)
+	return d, t
+}
+
+func (d *Document) resolve(t *Type) (*Document, *Type, error) {
+	type typedefKey struct {
+		d    *Document
+		name string
+	}
+	var seen map[typedefKey]bool
+	for t != nil && !IsBaseType(t.Name) && !IsContainerType(t.Name) {
+		td := d.Typedef(t.Name)
+		if td == nil {
+			break
+		}
+		key := typedefKey{}
+		key.d, key.name = d.Lookup(t.Name)
+		if seen[key] {
+			return d, t, fmt.Errorf("typedef cycle at %s", t.Name)
+		}
+		if seen == nil {
+			seen = make(map[typedefKey]bool)
+		}
+		seen[key] = true
+		d = key.d
+		t = td.Type
+	}
+	return d, t, nil
+}
+
+// checkTypedefs returns error if typedefs of d form a cycle.
+func (d *Document) checkTypedefs() error {
+	for _, td := range d.Typedefs {
+		if _, _, err := d.resolve(&Type{Name: td.Name}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValueOf returns value of name.
+func (e *Enum) ValueOf(name string) (int32, bool) {
+	for _, v := range e.Values {
+		if v.Name == name {
+			return v.Value, true
+		}
+	}
+	return 0, false
+}
+
+// NameOf returns name of value.
+func (e *Enum) NameOf(value int32) (string, bool) {
+	for _, v := range e.Values {
+		if v.Value == value {
+			return v.Name, true
+		}
+	}
+	return "", false
+}
+
+// Function returns Function by name.
+func (s *Service) Function(name string) *Function {
+	for _, f := range s.Functions {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
@@ -0,0 +1,620 @@
+package idl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Parse parses a thrift document from r.
+// includes are recorded but not loaded, use ParseFile to load them.
+// it returns error if typedefs form a cycle.
+func Parse(r io.Reader) (*Document, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{lexer: lexer{src: []rune(string(b)), line: 1}}
+	d, err := p.parseDocument()
+	if err == nil {
+		err = d.checkTypedefs()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// ParseFile parses a thrift document from file name and its includes.
+func ParseFile(name string) (*Document, error) {
+	return parseFile(name, make(map[string]*Document))
+}
+
+func parseFile(name string, seen map[string]*Document) (*Document, error) {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return nil, err
+	}
+	if d, ok := seen[abs]; ok {
+		return d, nil
+	}
+	f, err := os.Open(abs)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	d, err := Parse(bufio.NewReader(f))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	seen[abs] = d
+	d.Included = make(map[string]*Document)
+	for _, inc := range d.Includes {
+		i, err := parseFile(filepath.Join(filepath.Dir(abs), inc), seen)
+		if err != nil {
+			return nil, err
+		}
+		d.Included[strings.TrimSuffix(filepath.Base(inc), ".thrift")] = i
+	}
+	if err = d.checkTypedefs(); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return d, nil
+}
+
+type tokenKind byte
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdentifier
+	tokenNumber
+	tokenLiteral
+	tokenSymbol
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	line int
+}
+
+type lexer struct {
+	src  []rune
+	pos  int
+	line int
+	peek *token
+}
+
+func (l *lexer) next() (t token, err error) {
+	if l.peek != nil {
+		t, l.peek = *l.peek, nil
+		return
+	}
+	if err = l.skipSpaceAndComment(); err != nil {
+		return
+	}
+	t.line = l.line
+	if l.pos >= len(l.src) {
+		return
+	}
+	c := l.src[l.pos]
+	start := l.pos
+	switch {
+	case c == '_' || unicode.IsLetter(c):
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || l.src[l.pos] == '.' || unicode.IsLetter(l.src[l.pos]) || unicode.IsDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		t.kind = tokenIdentifier
+	case unicode.IsDigit(c) || ((c == '+' || c == '-') && l.pos+1 < len(l.src) && unicode.IsDigit(l.src[l.pos+1])):
+		l.pos++
+		for l.pos < len(l.src) && (unicode.IsDigit(l.src[l.pos]) || unicode.IsLetter(l.src[l.pos]) || l.src[l.pos] == '.' ||
+			((l.src[l.pos] == '+' || l.src[l.pos] == '-') && (l.src[l.pos-1] == 'e' || l.src[l.pos-1] == 'E'))) {
+			l.pos++
+		}
+		t.kind = tokenNumber
+	case c == '"' || c == '\'':
+		l.pos++
+		for l.pos < len(l.src) && l.src[l.pos] != c {
+			if l.src[l.pos] == '\\' {
+				l.pos++
+			}
+			if l.pos < len(l.src) && l.src[l.pos] == '\n' {
+				l.line++
+			}
+			l.pos++
+		}
+		if l.pos >= len(l.src) {
+			return t, fmt.Errorf("idl: line %d: unterminated literal", t.line)
+		}
+		l.pos++
+		t.kind = tokenLiteral
+		t.text = string(l.src[start+1 : l.pos-1])
+		return
+	default:
+		l.pos++
+		t.kind = tokenSymbol
+	}
+	t.text = string(l.src[start:l.pos])
+	return
+}
+
+func (l *lexer) skipSpaceAndComment() error {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '\n':
+			l.line++
+			l.pos++
+		case unicode.IsSpace(c):
+			l.pos++
+		case c == '#' || (c == '/' && l.pos+1 < len(l.src) && l.src[l.pos+1] == '/'):
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		case c == '/' && l.pos+1 < len(l.src) && l.src[l.pos+1] == '*':
+			line := l.line
+			l.pos += 2
+			for l.pos+1 < len(l.src) && !(l.src[l.pos] == '*' && l.src[l.pos+1] == '/') {
+				if l.src[l.pos] == '\n' {
+					l.line++
+				}
+				l.pos++
+			}
+			if l.pos+1 >= len(l.src) {
+				return fmt.Errorf("idl: line %d: unterminated comment", line)
+			}
+			l.pos += 2
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+type parser struct {
+	lexer
+}
+
+func (p *parser) peekToken() (token, error) {
+	if p.peek == nil {
+		t, err := p.next()
+		if err != nil {
+			return t, err
+		}
+		p.peek = &t
+	}
+	return *p.peek, nil
+}
+
+func (p *parser) accept(text string) (bool, error) {
+	t, err := p.peekToken()
+	if err != nil {
+		return false, err
+	}
+	if t.kind != tokenLiteral && t.text == text {
+		p.peek = nil
+		return true, nil
+	}
+	return false, nil
+}
+
+func (p *parser) expect(text string) error {
+	t, err := p.next()
+	if err != nil {
+		return err
+	}
+	if t.kind == tokenLiteral || t.text != text {
+		return unexpected(t, text)
+	}
+	return nil
+}
+
+func (p *parser) identifier() (string, error) {
+	t, err := p.next()
+	if err != nil {
+		return "", err
+	}
+	if t.kind != tokenIdentifier {
+		return "", unexpected(t, "identifier")
+	}
+	return t.text, nil
+}
+
+func (p *parser) literal() (string, error) {
+	t, err := p.next()
+	if err != nil {
+		return "", err
+	}
+	if t.kind != tokenLiteral {
+		return "", unexpected(t, "literal")
+	}
+	return t.text, nil
+}
+
+func unexpected(t token, expected string) error {
+	if t.kind == tokenEOF {
+		return fmt.Errorf("idl: line %d: expected %s, got EOF", t.line, expected)
+	}
+	return fmt.Errorf("idl: line %d: expected %s, got %q", t.line, expected, t.text)
+}
+
+func (p *parser) parseDocument() (d *Document, err error) {
+	d = &Document{Namespaces: make(map[string]string)}
+	for {
+		var t token
+		if t, err = p.next(); err != nil {
+			return
+		}
+		if t.kind == tokenEOF {
+			return
+		}
+		if t.kind != tokenIdentifier {
+			return nil, unexpected(t, "definition")
+		}
+		switch t.text {
+		case "include":
+			var s string
+			if s, err = p.literal(); err == nil {
+				d.Includes = append(d.Includes, s)
+			}
+		case "cpp_include":
+			_, err = p.literal()
+		case "namespace":
+			var scope token
+			if scope, err = p.next(); err == nil {
+				if scope.kind != tokenIdentifier && scope.text != "*" {
+					return nil, unexpected(scope, "namespace scope")
+				}
+				var name string
+				if name, err = p.identifier(); err == nil {
+					d.Namespaces[scope.text] = name
+				}
+			}
+		case "typedef":
+			v := new(Typedef)
+			if v.Type, err = p.parseType(); err == nil {
+				if v.Name, err = p.identifier(); err == nil {
+					d.Typedefs = append(d.Typedefs, v)
+				}
+			}
+		case "const":
+			v := new(Constant)
+			if v.Type, err = p.parseType(); err == nil {
+				if v.Name, err = p.identifier(); err == nil {
+					if err = p.expect("="); err == nil {
+						if v.Value, err = p.parseConstValue(); err == nil {
+							d.Constants = append(d.Constants, v)
+						}
+					}
+				}
+			}
+		case "enum":
+			var v *Enum
+			if v, err = p.parseEnum(); err == nil {
+				d.Enums = append(d.Enums, v)
+			}
+		case "struct", "union", "exception":
+			v := &Struct{Kind: map[string]StructKind{"struct": KindStruct, "union": KindUnion, "exception": KindException}[t.text]}
+			if v.Name, err = p.identifier(); err == nil {
+				if _, err = p.accept("xsd_all"); err == nil {
+					if v.Fields, err = p.parseFields("{", "}"); err == nil {
+						d.Structs = append(d.Structs, v)
+					}
+				}
+			}
+		case "service":
+			var v *Service
+			if v, err = p.parseService(); err == nil {
+				d.Services = append(d.Services, v)
+			}
+		default:
+			return nil, unexpected(t, "definition")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err = p.skipAnnotations(); err != nil {
+			return nil, err
+		}
+		if err = p.skipSeparator(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) skipSeparator() error {
+	if ok, err := p.accept(","); ok || err != nil {
+		return err
+	}
+	_, err := p.accept(";")
+	return err
+}
+
+func (p *parser) skipAnnotations() error {
+	ok, err := p.accept("(")
+	if !ok || err != nil {
+		return err
+	}
+	for {
+		var t token
+		if t, err = p.next(); err != nil {
+			return err
+		}
+		if t.kind == tokenEOF {
+			return unexpected(t, ")")
+		}
+		if t.kind == tokenSymbol && t.text == ")" {
+			return nil
+		}
+	}
+}
+
+func (p *parser) parseType() (t *Type, err error) {
+	t = new(Type)
+	if t.Name, err = p.identifier(); err != nil {
+		return
+	}
+	switch t.Name {
+	case "map":
+		if err = p.skipCppType(); err != nil {
+			return
+		}
+		if err = p.expect("<"); err != nil {
+			return
+		}
+		if t.KeyType, err = p.parseType(); err != nil {
+			return
+		}
+		if err = p.expect(","); err != nil {
+			return
+		}
+		if t.ValType, err = p.parseType(); err != nil {
+			return
+		}
+		err = p.expect(">")
+	case "set", "list":
+		if err = p.skipCppType(); err != nil {
+			return
+		}
+		if err = p.expect("<"); err != nil {
+			return
+		}
+		if t.ValType, err = p.parseType(); err != nil {
+			return
+		}
+		if err = p.expect(">"); err != nil {
+			return
+		}
+		err = p.skipCppType()
+	}
+	if err == nil {
+		err = p.skipAnnotations()
+	}
+	return
+}
+
+func (p *parser) skipCppType() error {
+	ok, err := p.accept("cpp_type")
+	if ok {
+		_, err = p.literal()
+	}
+	return err
+}
+
+func (p *parser) parseConstValue() (string, error) {
+	t, err := p.next()
+	if err != nil {
+		return "", err
+	}
+	switch t.kind {
+	case tokenNumber, tokenIdentifier:
+		return t.text, nil
+	case tokenLiteral:
+		return strconv.Quote(t.text), nil
+	case tokenSymbol:
+		var end string
+		switch t.text {
+		case "[":
+			end = "]"
+		case "{":
+			end = "}"
+		default:
+			return "", unexpected(t, "constant")
+		}
+		var values []string
+		for {
+			if ok, err := p.accept(end); ok || err != nil {
+				return t.text + strings.Join(values, ",") + end, err
+			}
+			v, err := p.parseConstValue()
+			if err != nil {
+				return "", err
+			}
+			if end == "}" {
+				if err = p.expect(":"); err != nil {
+					return "", err
+				}
+				var e string
+				if e, err = p.parseConstValue(); err != nil {
+					return "", err
+				}
+				v += ":" + e
+			}
+			if err = p.skipSeparator(); err != nil {
+				return "", err
+			}
+			values = append(values, v)
+		}
+	}
+	return "", unexpected(t, "constant")
+}
+
+func (p *parser) parseEnum() (e *Enum, err error) {
+	e = new(Enum)
+	if e.Name, err = p.identifier(); err != nil {
+		return
+	}
+	if err = p.expect("{"); err != nil {
+		return
+	}
+	var next int64
+	for {
+		var ok bool
+		if ok, err = p.accept("}"); ok || err != nil {
+			return
+		}
+		v := new(EnumValue)
+		if v.Name, err = p.identifier(); err != nil {
+			return
+		}
+		if ok, err = p.accept("="); err != nil {
+			return
+		} else if ok {
+			var t token
+			if t, err = p.next(); err != nil {
+				return
+			}
+			if t.kind != tokenNumber {
+				return nil, unexpected(t, "integer")
+			}
+			if next, err = strconv.ParseInt(t.text, 0, 32); err != nil {
+				return nil, fmt.Errorf("idl: line %d: %w", t.line, err)
+			}
+		}
+		v.Value = int32(next)
+		next++
+		e.Values = append(e.Values, v)
+		if err = p.skipAnnotations(); err != nil {
+			return
+		}
+		if err = p.skipSeparator(); err != nil {
+			return
+		}
+	}
+}
+
+func (p *parser) parseFields(begin, end string) (fields []*Field, err error) {
+	if err = p.expect(begin); err != nil {
+		return
+	}
+	var implicit int16
+	for {
+		var ok bool
+		if ok, err = p.accept(end); ok || err != nil {
+			return
+		}
+		f := new(Field)
+		var t token
+		if t, err = p.peekToken(); err != nil {
+			return
+		}
+		if t.kind == tokenNumber {
+			p.peek = nil
+			var id int64
+			if id, err = strconv.ParseInt(t.text, 0, 16); err != nil {
+				return nil, fmt.Errorf("idl: line %d: %w", t.line, err)
+			}
+			f.ID = int16(id)
+			if err = p.expect(":"); err != nil {
+				return
+			}
+		} else {
+			implicit--
+			f.ID = implicit
+		}
+		if ok, err = p.accept("required"); err != nil {
+			return
+		} else if ok {
+			f.Requiredness = RequirednessRequired
+		} else if ok, err = p.accept("optional"); err != nil {
+			return
+		} else if ok {
+			f.Requiredness = RequirednessOptional
+		}
+		if f.Type, err = p.parseType(); err != nil {
+			return
+		}
+		if f.Name, err = p.identifier(); err != nil {
+			return
+		}
+		if ok, err = p.accept("="); err != nil {
+			return
+		} else if ok {
+			if f.Default, err = p.parseConstValue(); err != nil {
+				return
+			}
+		}
+		for _, opt := range []string{"xsd_optional", "xsd_nillable"} {
+			if _, err = p.accept(opt); err != nil {
+				return
+			}
+		}
+		if err = p.skipAnnotations(); err != nil {
+			return
+		}
+		if err = p.skipSeparator(); err != nil {
+			return
+		}
+		fields = append(fields, f)
+	}
+}
+
+func (p *parser) parseService() (s *Service, err error) {
+	s = new(Service)
+	if s.Name, err = p.identifier(); err != nil {
+		return
+	}
+	var ok bool
+	if ok, err = p.accept("extends"); err != nil {
+		return
+	} else if ok {
+		if s.Extends, err = p.identifier(); err != nil {
+			return
+		}
+	}
+	if err = p.expect("{"); err != nil {
+		return
+	}
+	for {
+		if ok, err = p.accept("}"); ok || err != nil {
+			return
+		}
+		f := new(Function)
+		if f.Oneway, err = p.accept("oneway"); err != nil {
+			return
+		}
+		if ok, err = p.accept("void"); err != nil {
+			return
+		} else if !ok {
+			if f.ReturnType, err = p.parseType(); err != nil {
+				return
+			}
+		}
+		if f.Name, err = p.identifier(); err != nil {
+			return
+		}
+		if f.Arguments, err = p.parseFields("(", ")"); err != nil {
+			return
+		}
+		if ok, err = p.accept("throws"); err != nil {
+			return
+		} else if ok {
+			if f.Exceptions, err = p.parseFields("(", ")"); err != nil {
+				return
+			}
+		}
+		if err = p.skipAnnotations(); err != nil {
+			return
+		}
+		if err = p.skipSeparator(); err != nil {
+			return
+		}
+		s.Functions = append(s.Functions, f)
+	}
+}
@@ -0,0 +1,152 @@
+package idl_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/b1avk/thrift/pkg/idl"
+)
+
+const testIDL = `
+namespace go example
+namespace * example
+
+include "shared.thrift"
+
+/* block
+   comment */
+typedef i64 Timestamp
+
+const i32 MaxItems = 100;
+const map<string, list<i32>> Lookup = {"a": [1, 2], "b": []}
+
+enum Status {
+	ACTIVE = 1,
+	DISABLED,
+	DELETED = 0x10 (deprecated = "true")
+}
+
+struct Item {
+	1: required string name
+	2: optional Timestamp created = 0,
+	3: map<string, set<Status>> tags
+	4: list<Item> (cpp.template = "std::vector") children;
+	string implicit
+}
+
+exception NotFound {
+	1: string message
+}
+
+service Items extends shared.Base {
+	Item get(1: string name) throws (1: NotFound notFound),
+	oneway void touch(1: string name)
+	# line comment
+	void clear()
+}
+`
+
+func TestParse(t *testing.T) {
+	d, err := idl.Parse(strings.NewReader(testIDL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Namespaces["go"] != "example" || d.Namespaces["*"] != "example" {
+		t.Fatal("namespace mismatch", d.Namespaces)
+	}
+	if len(d.Includes) != 1 || d.Includes[0] != "shared.thrift" {
+		t.Fatal("include mismatch", d.Includes)
+	}
+	if td := d.Typedef("Timestamp"); td == nil || td.Type.Name != "i64" {
+		t.Fatal("typedef mismatch")
+	}
+	if len(d.Constants) != 2 || d.Constants[1].Value != `{"a":[1,2],"b":[]}` {
+		t.Fatal("constant mismatch", d.Constants[1].Value)
+	}
+	e := d.Enum("Status")
+	if e == nil {
+		t.Fatal("enum not found")
+	}
+	if v, _ := e.ValueOf("DISABLED"); v != 2 {
+		t.Fatal("DISABLED must be 2")
+	}
+	if v, _ := e.ValueOf("DELETED"); v != 16 {
+		t.Fatal("DELETED must be 16")
+	}
+	s := d.Struct("Item")
+	if s == nil || len(s.Fields) != 5 {
+		t.Fatal("struct mismatch")
+	}
+	if f := s.Fields[0]; f.ID != 1 || f.Name != "name" || f.Requiredness != idl.RequirednessRequired {
+		t.Fatal("field 1 mismatch", f)
+	}
+	if f := s.Fields[2]; f.Type.Name != "map" || f.Type.KeyType.Name != "string" || f.Type.ValType.ValType.Name != "Status" {
+		t.Fatal("field 3 mismatch", f)
+	}
+	if f := s.Fields[4]; f.ID != -1 {
+		t.Fatal("implicit field identity must be -1", f.ID)
+	}
+	if ex := d.Struct("NotFound"); ex == nil || ex.Kind != idl.KindException {
+		t.Fatal("exception mismatch")
+	}
+	svc := d.Service("Items")
+	if svc == nil || svc.Extends != "shared.Base" || len(svc.Functions) != 3 {
+		t.Fatal("service mismatch")
+	}
+	if f := svc.Function("get"); f.ReturnType.Name != "Item" || len(f.Exceptions) != 1 {
+		t.Fatal("function get mismatch")
+	}
+	if f := svc.Function("touch"); !f.Oneway || f.ReturnType != nil {
+		t.Fatal("function touch mismatch")
+	}
+}
+
+func TestParseError(t *testing.T) {
+	_, err := idl.Parse(strings.NewReader("struct A {\n1: string\n}"))
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Fatal("must error with line number", err)
+	}
+}
+
+func TestParseTypedefCycle(t *testing.T) {
+	for _, src := range []string{
+		"typedef A A",
+		"typedef A B\ntypedef B A",
+		"typedef i32 C\ntypedef A B\ntypedef B D\ntypedef D A",
+	} {
+		if _, err := idl.Parse(strings.NewReader(src)); err == nil {
+			t.Fatal("must error on typedef cycle", src)
+		}
+	}
+	if _, err := idl.Parse(strings.NewReader("typedef i32 A\ntypedef A B\ntypedef B C")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseFileTypedefCycle(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.thrift": "include \"b.thrift\"\ntypedef b.B A",
+		"b.thrift": "include \"a.thrift\"\ntypedef a.A B",
+	}
+	for name, src := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := idl.ParseFile(filepath.Join(dir, "a.thrift")); err == nil {
+		t.Fatal("must error on typedef cycle across includes")
+	}
+}
+
+func TestResolveTypedefCycle(t *testing.T) {
+	d := &idl.Document{Typedefs: []*idl.Typedef{
+		{Name: "A", Type: &idl.Type{Name: "B"}},
+		{Name: "B", Type: &idl.Type{Name: "A"}},
+	}}
+	if _, r := d.Resolve(&idl.Type{Name: "A"}); r == nil || r.Name != "A" && r.Name != "B" {
+		t.Fatal("must stop at cycle", r)
+	}
+}
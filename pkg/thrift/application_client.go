@@ -8,7 +8,7 @@ import (
 
 // TClient is interface that wraps Call method.
 type TClient interface {
-	// Call writes a message.
+	// Call writes a message, a nil result makes it oneway.
 	Call(ctx context.Context, method string, args, result TStruct) (err error)
 }
 
@@ -40,19 +40,24 @@ func NewTStandardClient(iprot, oprot TProtocol) *TStandardClient {
 	}
 }
 
-// Call writes message to oprot and reads from iprot.
+// Call writes message to oprot and reads from iprot,
+// a nil result writes ONEWAY message and reads nothing.
 func (p *TStandardClient) Call(ctx context.Context, method string, args, result TStruct) (err error) {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 	p.message.Identity++
 	p.message.Name = method
+	p.message.Type = CALL
+	if result == nil {
+		p.message.Type = ONEWAY
+	}
 	if err = p.oprot.WriteMessageBegin(p.message); err != nil {
 		return
 	}
 	if err = args.Write(p.oprot); err != nil {
 		return
 	}
-	if err = p.oprot.WriteMapEnd(); err != nil {
+	if err = p.oprot.WriteMessageEnd(); err != nil {
 		return
 	}
 	if err = p.oprot.Flush(ctx); err != nil {
@@ -151,8 +156,9 @@ func (cp *TPoolClient) Call(ctx context.Context, method string, args, result TSt
 	}
 	cp.mutex.Lock()
 	cp.sequence++
+	sequence := cp.sequence
 	cp.mutex.Unlock()
 	defer cp.pool.Put(c)
-	c.message.Identity = cp.sequence
+	c.message.Identity = sequence
 	return c.Call(ctx, method, args, result)
 }
@@ -5,8 +5,10 @@ import (
 )
 
 const (
-	DefaultMaxBufferSize  = 1024
-	DefaultMaxMessageSize = 8192
+	DefaultMaxBufferSize       = 1024
+	DefaultMaxMessageSize      = 8192
+	DefaultMaxDecompressedSize = 16 << 20
+	DefaultMaxRecursionDepth   = 64
 )
 
 // TConfiguration a shared configuration between an implementations.
@@ -14,6 +16,24 @@ type TConfiguration struct {
 	StrictRead, StrictWrite bool
 	MaxMessageSize          int
 	MaxBufferSize           int
+
+	// MaxDecompressedSize limits data decompressed by compressed transports
+	// for each message, see TReadLimiter.
+	MaxDecompressedSize int
+
+	// MaxRecursionDepth limits nesting of structs and containers
+	// read by protocols.
+	MaxRecursionDepth int
+
+	// Canonical requests deterministic output from encoders:
+	// fields in ascending identity order, set elements and map entries
+	// sorted by their binary encoded bytes.
+	Canonical bool
+
+	// JSONEnumNames makes JSON protocol carry values of registered enums of
+	// dynamic encoders by name. Apache Thrift writes enums as i32 and does not
+	// read names, neither does Skip.
+	JSONEnumNames bool
 }
 
 // TConfigurationSetter is interface that wraps SetTConfiguration method.
@@ -21,11 +41,27 @@ type TConfigurationSetter interface {
 	SetTConfiguration(cfg *TConfiguration)
 }
 
+// TConfigurationGetter is interface that wraps GetTConfiguration method.
+type TConfigurationGetter interface {
+	GetTConfiguration() *TConfiguration
+}
+
+// TConfigurationOf returns TConfiguration of impl.
+// will returns DefaultTConfiguration if impl is not TConfigurationGetter.
+func TConfigurationOf(impl interface{}) *TConfiguration {
+	if getter, ok := impl.(TConfigurationGetter); ok {
+		return getter.GetTConfiguration().NonNil()
+	}
+	return DefaultTConfiguration
+}
+
 // DefaultTConfiguration default TConfiguration.
 var DefaultTConfiguration = &TConfiguration{
-	StrictWrite:    true,
-	MaxBufferSize:  DefaultMaxBufferSize,
-	MaxMessageSize: DefaultMaxMessageSize,
+	StrictWrite:         true,
+	MaxBufferSize:       DefaultMaxBufferSize,
+	MaxMessageSize:      DefaultMaxMessageSize,
+	MaxDecompressedSize: DefaultMaxDecompressedSize,
+	MaxRecursionDepth:   DefaultMaxRecursionDepth,
 }
 
 // IsStrictRead returns protocol strict read configuration.
@@ -38,6 +74,16 @@ func (cfg *TConfiguration) IsStrictWrite() bool {
 	return cfg.NonNil().StrictWrite
 }
 
+// IsCanonical returns canonical output configuration.
+func (cfg *TConfiguration) IsCanonical() bool {
+	return cfg.NonNil().Canonical
+}
+
+// IsJSONEnumNames returns JSON enum names configuration.
+func (cfg *TConfiguration) IsJSONEnumNames() bool {
+	return cfg.NonNil().JSONEnumNames
+}
+
 // GetMaxMessageSize returns max message size.
 // will returns DefaultMaxMessageSize if cfg.MaxMessageSize < 1.
 func (cfg *TConfiguration) GetMaxMessageSize() int {
@@ -58,6 +104,26 @@ func (cfg *TConfiguration) GetMaxBufferSize() int {
 	return cfg.MaxBufferSize
 }
 
+// GetMaxDecompressedSize returns max decompressed size.
+// will returns DefaultMaxDecompressedSize if cfg.MaxDecompressedSize < 1.
+func (cfg *TConfiguration) GetMaxDecompressedSize() int {
+	cfg = cfg.NonNil()
+	if cfg.MaxDecompressedSize < 1 {
+		return DefaultMaxDecompressedSize
+	}
+	return cfg.MaxDecompressedSize
+}
+
+// GetMaxRecursionDepth returns max recursion depth.
+// will returns DefaultMaxRecursionDepth if cfg.MaxRecursionDepth < 1.
+func (cfg *TConfiguration) GetMaxRecursionDepth() int {
+	cfg = cfg.NonNil()
+	if cfg.MaxRecursionDepth < 1 {
+		return DefaultMaxRecursionDepth
+	}
+	return cfg.MaxRecursionDepth
+}
+
 // CheckSizeForProtocol returns TProtocolException if size is not valid.
 func (cfg *TConfiguration) CheckSizeForProtocol(size int) error {
 	if size < 0 {
@@ -0,0 +1,99 @@
+package thrift
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+type tContextKey int
+
+const (
+	tContextKeyPeerAddr tContextKey = iota
+	tContextKeyHTTPHeader
+	tContextKeyTransport
+	tContextKeyPeerCred
+	tContextKeyTLSState
+)
+
+// TPeerCred credentials of process of peer of unix socket.
+type TPeerCred struct {
+	PID int32
+	UID uint32
+	GID uint32
+}
+
+// WithTPeerAddr returns copy of ctx with address of peer.
+func WithTPeerAddr(ctx context.Context, addr net.Addr) context.Context {
+	return context.WithValue(ctx, tContextKeyPeerAddr, addr)
+}
+
+// TPeerAddrFromContext returns address of peer of ctx, nil if unknown.
+func TPeerAddrFromContext(ctx context.Context) net.Addr {
+	addr, _ := ctx.Value(tContextKeyPeerAddr).(net.Addr)
+	return addr
+}
+
+// THTTPHeaderFromContext returns header of HTTP request of ctx, nil if ctx is not of HTTP request.
+func THTTPHeaderFromContext(ctx context.Context) http.Header {
+	h, _ := ctx.Value(tContextKeyHTTPHeader).(http.Header)
+	return h
+}
+
+// WithTTransport returns copy of ctx with transport of connection.
+func WithTTransport(ctx context.Context, t TTransport) context.Context {
+	return context.WithValue(ctx, tContextKeyTransport, t)
+}
+
+// TTransportFromContext returns transport of connection of ctx, nil if unknown.
+func TTransportFromContext(ctx context.Context) TTransport {
+	t, _ := ctx.Value(tContextKeyTransport).(TTransport)
+	return t
+}
+
+// WithTPeerCred returns copy of ctx with credentials of peer.
+func WithTPeerCred(ctx context.Context, cred *TPeerCred) context.Context {
+	return context.WithValue(ctx, tContextKeyPeerCred, cred)
+}
+
+// TPeerCredFromContext returns credentials of peer of ctx, nil if unknown.
+func TPeerCredFromContext(ctx context.Context) *TPeerCred {
+	cred, _ := ctx.Value(tContextKeyPeerCred).(*TPeerCred)
+	return cred
+}
+
+// WithTTLSConnectionState returns copy of ctx with state of TLS connection.
+func WithTTLSConnectionState(ctx context.Context, state *tls.ConnectionState) context.Context {
+	return context.WithValue(ctx, tContextKeyTLSState, state)
+}
+
+// TTLSConnectionStateFromContext returns state of TLS connection of ctx, nil if not TLS.
+func TTLSConnectionStateFromContext(ctx context.Context) *tls.ConnectionState {
+	state, _ := ctx.Value(tContextKeyTLSState).(*tls.ConnectionState)
+	return state
+}
+
+// TPeerCertificatesFromContext returns verified certificate chain of peer of ctx,
+// leaf first; nil if the peer is not verified.
+func TPeerCertificatesFromContext(ctx context.Context) []*x509.Certificate {
+	if state := TTLSConnectionStateFromContext(ctx); state != nil && len(state.VerifiedChains) > 0 {
+		return state.VerifiedChains[0]
+	}
+	return nil
+}
+
+// TPeerSPIFFEIDFromContext returns spiffe:// URI SAN of verified certificate of peer of ctx,
+// nil if there is none.
+func TPeerSPIFFEIDFromContext(ctx context.Context) *url.URL {
+	if chain := TPeerCertificatesFromContext(ctx); len(chain) > 0 {
+		for _, u := range chain[0].URIs {
+			if u.Scheme == "spiffe" {
+				return u
+			}
+		}
+	}
+	return nil
+}
@@ -13,6 +13,7 @@ const (
 	TProtocolErrorNegativeSize
 	TProtocolErrorSizeLimit
 	TProtocolErrorBadVersion
+	TProtocolErrorDepthLimit
 )
 
 // TProtocolException a protocol-level exception.
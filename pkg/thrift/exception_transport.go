@@ -12,6 +12,9 @@ const (
 	TTransportErrorUnknown TTransportError = iota
 	TTransportErrorEOF
 	TTransportErrorTimeout
+	TTransportErrorSizeLimit
+	TTransportErrorNotOpen
+	TTransportErrorAlreadyOpen
 )
 
 // TTransportException a transport-level exception.
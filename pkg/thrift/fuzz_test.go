@@ -0,0 +1,63 @@
+//go:build go1.18
+// +build go1.18
+
+package thrift_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/b1avk/thrift/pkg/thrift"
+	"github.com/b1avk/thrift/pkg/thrifttest"
+)
+
+// fuzzProtocol reads untrusted bytes with protocol of pf, as a message
+// and as a skipped value of type of first byte. seed corpus is golden files in dir.
+func fuzzProtocol(f *testing.F, pf thrift.TProtocolFactory, dir string) {
+	names, err := filepath.Glob(filepath.Join(dir, "*.hex"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, name := range names {
+		content, err := ioutil.ReadFile(name)
+		if err != nil {
+			f.Fatal(err)
+		}
+		b, err := thrifttest.ReadGolden(content)
+		if err != nil {
+			f.Fatalf("invalid golden file %s: %v", name, err)
+		}
+		f.Add(b)
+		f.Add(append([]byte{thrift.STRUCT}, b...))
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		p := pf.GetProtocol(newMemoryBuffer(b))
+		if _, err := p.ReadMessageBegin(); err == nil {
+			if err = p.Skip(thrift.STRUCT); err == nil {
+				p.ReadMessageEnd()
+			}
+		}
+		if len(b) > 0 {
+			pf.GetProtocol(newMemoryBuffer(b[1:])).Skip(b[0])
+		}
+	})
+}
+
+func FuzzBinaryProtocol(f *testing.F) {
+	fuzzProtocol(f, thrift.NewTBinaryProtocolFactory(nil), "testdata/golden/binary")
+}
+
+func FuzzCompactProtocol(f *testing.F) {
+	fuzzProtocol(f, thrift.NewTCompactProtocolFactory(nil), "testdata/golden/compact")
+}
+
+func FuzzJSONProtocol(f *testing.F) {
+	fuzzProtocol(f, thrift.NewTJSONProtocolFactory(nil), "testdata/golden/json")
+}
+
+func newMemoryBuffer(b []byte) *thrift.TMemoryBuffer {
+	m := thrift.NewTMemoryBuffer()
+	m.Write(b)
+	return m
+}
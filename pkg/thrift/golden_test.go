@@ -0,0 +1,79 @@
+package thrift_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/b1avk/thrift/pkg/thrift"
+	"github.com/b1avk/thrift/pkg/thrifttest"
+)
+
+func TestGoldenBinary(t *testing.T) {
+	thrifttest.TestGolden(t, thrift.NewTBinaryProtocolFactory(nil), "testdata/golden/binary")
+}
+
+func TestGoldenCompact(t *testing.T) {
+	thrifttest.TestGolden(t, thrift.NewTCompactProtocolFactory(nil), "testdata/golden/compact")
+}
+
+func TestGoldenJSON(t *testing.T) {
+	thrifttest.TestGolden(t, thrift.NewTJSONProtocolFactory(nil), "testdata/golden/json")
+}
+
+// TestGoldenSpec checks some golden files against bytes which are derived by hand
+// from specifications of Apache Thrift protocols, rather than by this package.
+func TestGoldenSpec(t *testing.T) {
+	spec := map[string][]string{
+		"binary/message_exception.hex": {
+			"80010003",                  // strict version 1, EXCEPTION
+			"00000004 70696e67",         // name "ping"
+			"ffffffff",                  // sequence id -1
+			"0b 0001 00000004 626f6f6d", // 1: string "boom"
+			"08 0002 00000006",          // 2: i32 INTERNAL_ERROR
+			"00",                        // stop
+		},
+		"compact/message_exception.hex": {
+			"82",             // protocol id
+			"61",             // EXCEPTION << 5 | version 1
+			"ffffffff0f",     // sequence id -1, varint without zigzag
+			"04 70696e67",    // name "ping"
+			"18 04 626f6f6d", // 1: delta 1, binary "boom"
+			"15 0c",          // 2: delta 1, i32 zigzag 6
+			"00",             // stop
+		},
+		"compact/struct_field_ids.hex": {
+			"11",                        // 1: delta 1, true
+			"f3 01",                     // 16: delta 15, byte
+			"12",                        // 17: delta 1, false
+			"05 d804 d704",              // 300: long form, i32 -300
+			"01 04",                     // 2: long form as id decreases, true
+			"08 01 08 6e65676174697665", // -1: binary "negative"
+			"02 feff03",                 // 32767: long form, false
+			"04 ffff03 ffff03",          // -32768: long form, i16 -32768
+			"00",                        // stop
+		},
+		"json/message_exception.hex": {
+			hex.EncodeToString([]byte(`[1,"ping",3,-1,{"1":{"str":"boom"},"2":{"i32":6}}]`)),
+		},
+	}
+	for name, parts := range spec {
+		want, err := hex.DecodeString(strings.Join(strings.Fields(strings.Join(parts, "")), ""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadFile("testdata/golden/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := thrifttest.ReadGolden(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("%s differs from specification:\nwant %x\ngot  %x", name, want, got)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package thrift
+
+import (
+	"context"
+	"fmt"
+)
+
+// TProcessor processes messages of a connection.
+type TProcessor interface {
+	// Process reads a message from in and writes reply to out.
+	// returned error means the connection is broken and must be closed.
+	Process(ctx context.Context, in, out TProtocol) (err error)
+}
+
+// TProcessorFunc processes a call of which message header has been read from in.
+type TProcessorFunc func(ctx context.Context, h TMessageHeader, in, out TProtocol) (err error)
+
+// THandler handles a call with decoded args and returns result to reply,
+// result of oneway call is ignored.
+// *TApplicationException error is replied as exception, other errors are
+// replied as exception of TApplicationErrorInternalError.
+type THandler func(ctx context.Context, args TStruct) (result TStruct, err error)
+
+// TCallInfo describes a call being handled.
+type TCallInfo struct {
+	Method   string
+	Sequence int32
+	Type     TMessageType
+}
+
+// TMiddleware intercepts calls of handlers registered by TStandardProcessor.Handle,
+// it calls next to continue handling of the call.
+type TMiddleware func(ctx context.Context, call TCallInfo, args TStruct, next THandler) (result TStruct, err error)
+
+// TStandardProcessor a TProcessor which dispatches calls by method name.
+// calls of unknown methods are replied with exception of TApplicationErrorUnknownMethod.
+type TStandardProcessor struct {
+	functions  map[string]TProcessorFunc
+	middleware []TMiddleware
+}
+
+// NewTStandardProcessor returns new empty TStandardProcessor.
+func NewTStandardProcessor() *TStandardProcessor {
+	return &TStandardProcessor{functions: make(map[string]TProcessorFunc)}
+}
+
+// Use appends middleware m, the first middleware is the outermost.
+func (p *TStandardProcessor) Use(m ...TMiddleware) {
+	p.middleware = append(p.middleware, m...)
+}
+
+// AddFunction registers f to process calls of method.
+func (p *TStandardProcessor) AddFunction(method string, f TProcessorFunc) {
+	p.functions[method] = f
+}
+
+// Handle registers h to handle calls of method, args are decoded to value of newArgs.
+// panic of h or middleware is replied as exception of TApplicationErrorInternalError.
+func (p *TStandardProcessor) Handle(method string, newArgs func() TStruct, h THandler) {
+	p.AddFunction(method, func(ctx context.Context, m TMessageHeader, in, out TProtocol) (err error) {
+		args := newArgs()
+		if err = args.Read(in); err != nil {
+			if m.Type != ONEWAY {
+				writeReply(ctx, out, m, nil, &TApplicationException{Message: err.Error(), Type: TApplicationErrorProtocolError})
+			}
+			return
+		}
+		if err = in.ReadMessageEnd(); err != nil {
+			return
+		}
+		result, err := p.call(ctx, TCallInfo{m.Name, m.Identity, m.Type}, args, h)
+		if m.Type == ONEWAY {
+			return nil
+		}
+		return writeReply(ctx, out, m, result, err)
+	})
+}
+
+// call calls h through middleware, panic is returned as error.
+func (p *TStandardProcessor) call(ctx context.Context, call TCallInfo, args TStruct, h THandler) (result TStruct, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, &TApplicationException{
+				Message: fmt.Sprintf("%s: panic: %v", call.Method, r),
+				Type:    TApplicationErrorInternalError,
+			}
+		}
+	}()
+	for i := len(p.middleware) - 1; i >= 0; i-- {
+		m, next := p.middleware[i], h
+		h = func(ctx context.Context, args TStruct) (TStruct, error) {
+			return m(ctx, call, args, next)
+		}
+	}
+	return h(ctx, args)
+}
+
+// Process reads a message from in and calls function of its method.
+func (p *TStandardProcessor) Process(ctx context.Context, in, out TProtocol) (err error) {
+	var h TMessageHeader
+	if h, err = in.ReadMessageBegin(); err != nil {
+		return
+	}
+	if f, ok := p.functions[h.Name]; ok {
+		return f(ctx, h, in, out)
+	}
+	if err = in.Skip(STRUCT); err != nil {
+		return
+	}
+	if err = in.ReadMessageEnd(); err != nil || h.Type == ONEWAY {
+		return
+	}
+	return writeReply(ctx, out, h, nil, &TApplicationException{
+		Message: fmt.Sprintf("unknown method %s", h.Name),
+		Type:    TApplicationErrorUnknownMethod,
+	})
+}
+
+// writeReply writes result, or err as exception, in reply to message h.
+func writeReply(ctx context.Context, out TProtocol, h TMessageHeader, result TStruct, err error) error {
+	h.Type = REPLY
+	if err != nil {
+		e, ok := err.(*TApplicationException)
+		if !ok {
+			e = &TApplicationException{Message: err.Error(), Type: TApplicationErrorInternalError}
+		}
+		h.Type, result = EXCEPTION, e
+	} else if result == nil {
+		h.Type, result = EXCEPTION, &TApplicationException{
+			Message: fmt.Sprintf("%s: missing result", h.Name),
+			Type:    TApplicationErrorMissingResult,
+		}
+	}
+	if err = out.WriteMessageBegin(h); err == nil {
+		if err = result.Write(out); err == nil {
+			if err = out.WriteMessageEnd(); err == nil {
+				err = out.Flush(ctx)
+			}
+		}
+	}
+	return err
+}
@@ -0,0 +1,89 @@
+package thrift_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/b1avk/thrift/pkg/thrift"
+)
+
+// call writes call of method to processor p and returns reply.
+func call(t *testing.T, p thrift.TProcessor, method string, args thrift.TStruct) (thrift.TMessageHeader, *thrift.TApplicationException) {
+	in, out := thrift.NewTMemoryBuffer(), thrift.NewTMemoryBuffer()
+	iprot, oprot := thrift.NewTBinaryProtocol(in, nil), thrift.NewTBinaryProtocol(out, nil)
+	if err := iprot.WriteMessageBegin(thrift.TMessageHeader{Name: method, Type: thrift.CALL, Identity: 7}); err != nil {
+		t.Fatal(err)
+	}
+	if err := args.Write(iprot); err != nil {
+		t.Fatal(err)
+	}
+	if err := iprot.WriteMessageEnd(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Process(context.Background(), iprot, oprot); err != nil {
+		t.Fatal(err)
+	}
+	if in.Len() != 0 {
+		t.Fatal("request must be consumed")
+	}
+	h, err := oprot.ReadMessageBegin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := new(thrift.TApplicationException)
+	if err = r.Read(oprot); err != nil {
+		t.Fatal(err)
+	}
+	return h, r
+}
+
+func TestTStandardProcessorMiddleware(t *testing.T) {
+	p := newEchoProcessor(0, nil)
+	var trace []string
+	p.Use(func(ctx context.Context, call thrift.TCallInfo, args thrift.TStruct, next thrift.THandler) (thrift.TStruct, error) {
+		trace = append(trace, "outer "+call.Method+" "+args.(*thrift.TApplicationException).Message)
+		if call.Sequence != 7 || call.Type != thrift.CALL {
+			t.Errorf("unexpected call: %+v", call)
+		}
+		return next(ctx, args)
+	}, func(ctx context.Context, call thrift.TCallInfo, args thrift.TStruct, next thrift.THandler) (thrift.TStruct, error) {
+		trace = append(trace, "inner")
+		if args.(*thrift.TApplicationException).Message == "deny" {
+			return nil, errors.New("denied")
+		}
+		return next(ctx, args)
+	})
+	h, r := call(t, p, "echo", &thrift.TApplicationException{Message: "Hello"})
+	if h.Type != thrift.REPLY || h.Identity != 7 || r.Message != "Hello" {
+		t.Fatalf("unexpected reply: %+v %+v", h, r)
+	}
+	if !reflect.DeepEqual(trace, []string{"outer echo Hello", "inner"}) {
+		t.Fatalf("unexpected middleware order: %v", trace)
+	}
+	h, r = call(t, p, "echo", &thrift.TApplicationException{Message: "deny"})
+	if h.Type != thrift.EXCEPTION || r.Type != thrift.TApplicationErrorInternalError || r.Message != "denied" {
+		t.Fatalf("unexpected reply: %+v %+v", h, r)
+	}
+}
+
+func TestTStandardProcessorPanic(t *testing.T) {
+	p := thrift.NewTStandardProcessor()
+	p.Handle("panic", func() thrift.TStruct {
+		return new(thrift.TApplicationException)
+	}, func(ctx context.Context, args thrift.TStruct) (thrift.TStruct, error) {
+		panic("boom")
+	})
+	h, r := call(t, p, "panic", &thrift.TApplicationException{})
+	if h.Type != thrift.EXCEPTION || r.Type != thrift.TApplicationErrorInternalError || r.Message != "panic: panic: boom" {
+		t.Fatalf("unexpected reply: %+v %+v", h, r)
+	}
+}
+
+func TestTStandardProcessorUnknownMethod(t *testing.T) {
+	h, r := call(t, newEchoProcessor(0, nil), "unknown", &thrift.TApplicationException{Message: "Hello", Type: 1})
+	if h.Type != thrift.EXCEPTION || h.Name != "unknown" || r.Type != thrift.TApplicationErrorUnknownMethod {
+		t.Fatalf("unexpected reply: %+v %+v", h, r)
+	}
+}
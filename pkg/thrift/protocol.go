@@ -112,6 +112,13 @@ type TProtocol interface {
 	Skip(v TType) (err error)
 }
 
+// TEnumProtocol is implemented by protocols which may carry enum values by name,
+// see TConfiguration.JSONEnumNames.
+type TEnumProtocol interface {
+	// ReadEnum reads enum value, name is non-empty if the value is carried by name.
+	ReadEnum() (v int32, name string, err error)
+}
+
 // Skip skips over next v from p.
 func Skip(v TType, p TProtocol) (err error) {
 	switch v {
@@ -197,6 +204,35 @@ func Skip(v TType, p TProtocol) (err error) {
 	return
 }
 
+// tRecursionDepth nesting of structs and containers being read.
+type tRecursionDepth int
+
+// enter returns TProtocolException if d is at max recursion depth of cfg,
+// otherwise it increments d.
+func (d *tRecursionDepth) enter(cfg *TConfiguration) error {
+	if int(*d) >= cfg.GetMaxRecursionDepth() {
+		return NewTProtocolException(TProtocolErrorDepthLimit, fmt.Sprintf("depth exceeded max allowed: %d", cfg.GetMaxRecursionDepth()))
+	}
+	*d++
+	return nil
+}
+
+// leave decrements d.
+func (d *tRecursionDepth) leave() {
+	if *d > 0 {
+		*d--
+	}
+}
+
+// checkRemainingBytes returns TProtocolException if t knows count of its
+// unread bytes and it is less than n, for n bytes or elements of at least one byte.
+func checkRemainingBytes(t TTransport, n int) error {
+	if r, ok := t.(interface{ RemainingBytes() int }); ok && n > r.RemainingBytes() {
+		return NewTProtocolException(TProtocolErrorInvalidData, fmt.Sprintf("size exceeded remaining bytes: %d", n))
+	}
+	return nil
+}
+
 type tProtocolFactory struct {
 	cfg *TConfiguration
 	new func(TTransport, *TConfiguration) TProtocol
@@ -205,3 +241,7 @@ type tProtocolFactory struct {
 func (f *tProtocolFactory) GetProtocol(t TTransport) TProtocol {
 	return f.new(t, f.cfg)
 }
+
+func (f *tProtocolFactory) GetTConfiguration() *TConfiguration {
+	return f.cfg
+}
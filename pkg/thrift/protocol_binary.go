@@ -3,6 +3,7 @@ package thrift
 import (
 	"context"
 	"encoding/binary"
+	"io"
 	"math"
 )
 
@@ -26,8 +27,9 @@ const (
 
 type tBinaryProtocol struct {
 	TExtraTransport
-	cfg *TConfiguration
-	buf [8]byte
+	cfg   *TConfiguration
+	buf   [8]byte
+	depth tRecursionDepth
 }
 
 func (p *tBinaryProtocol) SetTConfiguration(cfg *TConfiguration) {
@@ -35,6 +37,10 @@ func (p *tBinaryProtocol) SetTConfiguration(cfg *TConfiguration) {
 	p.cfg.Propagate(p.TExtraTransport)
 }
 
+func (p *tBinaryProtocol) GetTConfiguration() *TConfiguration {
+	return p.cfg
+}
+
 func (p *tBinaryProtocol) WriteMessageBegin(h TMessageHeader) (err error) {
 	if p.cfg.IsStrictWrite() {
 		if err = p.WriteU32(binaryVersion1 | uint32(h.Type)); err == nil {
@@ -183,6 +189,8 @@ func (p *tBinaryProtocol) writeSize(v int) error {
 }
 
 func (p *tBinaryProtocol) ReadMessageBegin() (h TMessageHeader, err error) {
+	p.depth = 0
+	resetReadLimit(p.TExtraTransport)
 	var n int
 	if n, err = p.readSize(); err != nil {
 		return
@@ -199,7 +207,7 @@ func (p *tBinaryProtocol) ReadMessageBegin() (h TMessageHeader, err error) {
 	} else if p.cfg.IsStrictRead() {
 		err = NewTProtocolException(TProtocolErrorBadVersion, "missing version in message header")
 	} else {
-		if h.Name, err = p.ReadString(); err == nil {
+		if h.Name, err = p.readStringBody(n); err == nil {
 			if h.Type, err = p.ReadByte(); err == nil {
 				h.Identity, err = p.ReadI32()
 			}
@@ -213,10 +221,12 @@ func (p *tBinaryProtocol) ReadMessageEnd() error {
 }
 
 func (p *tBinaryProtocol) ReadStructBegin() (h TStructHeader, err error) {
+	err = p.depth.enter(p.cfg)
 	return
 }
 
 func (p *tBinaryProtocol) ReadStructEnd() error {
+	p.depth.leave()
 	return nil
 }
 
@@ -232,37 +242,55 @@ func (p *tBinaryProtocol) ReadFieldEnd() error {
 }
 
 func (p *tBinaryProtocol) ReadMapBegin() (h TMapHeader, err error) {
+	if err = p.depth.enter(p.cfg); err != nil {
+		return
+	}
 	if h.Key, err = p.ReadByte(); err == nil {
 		if h.Value, err = p.ReadByte(); err == nil {
-			h.Size, err = p.readSize()
+			if h.Size, err = p.readSize(); err == nil {
+				err = p.checkSize(h.Size)
+			}
 		}
 	}
 	return
 }
 
 func (p *tBinaryProtocol) ReadMapEnd() error {
+	p.depth.leave()
 	return nil
 }
 
 func (p *tBinaryProtocol) ReadSetBegin() (h TSetHeader, err error) {
+	if err = p.depth.enter(p.cfg); err != nil {
+		return
+	}
 	if h.Element, err = p.ReadByte(); err == nil {
-		h.Size, err = p.readSize()
+		if h.Size, err = p.readSize(); err == nil {
+			err = p.checkSize(h.Size)
+		}
 	}
 	return
 }
 
 func (p *tBinaryProtocol) ReadSetEnd() error {
+	p.depth.leave()
 	return nil
 }
 
 func (p *tBinaryProtocol) ReadListBegin() (h TListHeader, err error) {
+	if err = p.depth.enter(p.cfg); err != nil {
+		return
+	}
 	if h.Element, err = p.ReadByte(); err == nil {
-		h.Size, err = p.readSize()
+		if h.Size, err = p.readSize(); err == nil {
+			err = p.checkSize(h.Size)
+		}
 	}
 	return
 }
 
 func (p *tBinaryProtocol) ReadListEnd() error {
+	p.depth.leave()
 	return nil
 }
 
@@ -325,17 +353,20 @@ func (p *tBinaryProtocol) ReadString() (v string, err error) {
 func (p *tBinaryProtocol) ReadBinary() (v []byte, err error) {
 	var n int
 	if n, err = p.readSize(); err == nil {
-		if err = p.cfg.CheckSizeForProtocol(n); err != nil {
+		if err = p.checkSize(n); err != nil {
 			return
 		}
-		v = make([]byte, n)
-		_, err = p.Read(v)
+		var buffered bool
+		if v, buffered, err = readBytes(p.TExtraTransport, n); buffered {
+			v = append([]byte(nil), v...)
+		}
+		err = NewTProtocolExceptionFromError(err)
 	}
 	return
 }
 
 func (p *tBinaryProtocol) Read(v []byte) (int, error) {
-	n, err := p.TExtraTransport.Read(v)
+	n, err := io.ReadFull(p.TExtraTransport, v)
 	return n, NewTProtocolExceptionFromError(err)
 }
 
@@ -344,13 +375,20 @@ func (p *tBinaryProtocol) readSize() (int, error) {
 	return int(v), err
 }
 
-func (p *tBinaryProtocol) readStringBody(n int) (string, error) {
+// checkSize checks size of string or container against configuration and remaining bytes.
+func (p *tBinaryProtocol) checkSize(n int) error {
 	if err := p.cfg.CheckSizeForProtocol(n); err != nil {
+		return err
+	}
+	return checkRemainingBytes(p.TExtraTransport, n)
+}
+
+func (p *tBinaryProtocol) readStringBody(n int) (string, error) {
+	if err := p.checkSize(n); err != nil {
 		return "", err
 	}
-	v := make([]byte, n)
-	_, err := p.Read(v)
-	return string(v), err
+	v, _, err := readBytes(p.TExtraTransport, n)
+	return string(v), NewTProtocolExceptionFromError(err)
 }
 
 func (p *tBinaryProtocol) Skip(v TType) error {
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/b1avk/thrift/pkg/thrift"
+	"github.com/b1avk/thrift/pkg/thrifttest"
 )
 
 func TestTBinaryProtocolMessageHeader(t *testing.T) {
@@ -31,3 +32,11 @@ func TestTBinaryProtocolMessageHeaderStrict(t *testing.T) {
 		t.Fatal("must error on reading message header", err)
 	}
 }
+
+func TestTBinaryProtocolConformance(t *testing.T) {
+	thrifttest.TestProtocol(t, thrift.NewTBinaryProtocolFactory(nil))
+}
+
+func TestTBinaryProtocolConformanceNonStrict(t *testing.T) {
+	thrifttest.TestProtocol(t, thrift.NewTBinaryProtocolFactory(&thrift.TConfiguration{}))
+}
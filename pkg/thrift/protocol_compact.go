@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"math"
 )
 
@@ -18,7 +19,6 @@ func NewTCompactProtocol(t TTransport, cfg *TConfiguration) TProtocol {
 	p := &tCompactProtocol{cfg: cfg}
 	p.TExtraTransport = NewTExtraTransport(t, cfg)
 	p.identityStack.Init()
-	p.booleanWrite = -1
 	return p
 }
 
@@ -51,7 +51,8 @@ const (
 
 var tTypeToCompactType = map[TType]compactType{
 	STOP:   compactStop,
-	BOOL:   compactByte,
+	BOOL:   compactBooleanTrue,
+	BYTE:   compactByte,
 	I16:    compactI16,
 	U16:    compactI16,
 	I32:    compactI32,
@@ -90,14 +91,27 @@ type tCompactProtocol struct {
 	identityStack list.List
 	lastIdentity  int16
 
-	booleanWrite int16
-	booleanRead  byte
+	booleanWrite   int16
+	booleanWriting bool
+	booleanRead    byte
+
+	depth tRecursionDepth
+}
+
+func (p *tCompactProtocol) SetTConfiguration(cfg *TConfiguration) {
+	p.cfg = cfg.NonNil()
+	p.cfg.Propagate(p.TExtraTransport)
+}
+
+func (p *tCompactProtocol) GetTConfiguration() *TConfiguration {
+	return p.cfg.NonNil()
 }
 
 func (p *tCompactProtocol) WriteMessageBegin(h TMessageHeader) (err error) {
 	if err = p.WriteByte(compactProtocolID); err == nil {
 		if err = p.WriteByte(compactVersion | (h.Type << compactTypeShiftAmount)); err == nil {
-			if err = p.WriteI32(h.Identity); err == nil {
+			// sequence id is not zigzag encoded, as Apache Thrift writes it.
+			if err = p.WriteU32(uint32(h.Identity)); err == nil {
 				err = p.WriteString(h.Name)
 			}
 		}
@@ -124,7 +138,7 @@ func (p *tCompactProtocol) WriteStructEnd() error {
 
 func (p *tCompactProtocol) WriteFieldBegin(h TFieldHeader) error {
 	if h.Type == BOOL {
-		p.booleanWrite = h.Identity
+		p.booleanWrite, p.booleanWriting = h.Identity, true
 		return nil
 	} else {
 		if c, ok := tTypeToCompactType[h.Type]; ok {
@@ -135,7 +149,7 @@ func (p *tCompactProtocol) WriteFieldBegin(h TFieldHeader) error {
 }
 
 func (p *tCompactProtocol) writeFieldHeader(t compactType, i int16) (err error) {
-	delta := i - p.lastIdentity
+	delta := int32(i) - int32(p.lastIdentity)
 	if 0 < delta && delta <= 15 {
 		if err = p.WriteByte(byte((delta << 4)) | t); err != nil {
 			return
@@ -191,8 +205,12 @@ func (p *tCompactProtocol) WriteSetEnd() error {
 }
 
 func (p *tCompactProtocol) WriteListBegin(h TListHeader) (err error) {
-	if err = p.WriteByte(h.Element); err == nil {
-		err = p.writeSize(h.Size)
+	if h.Size <= 14 {
+		err = p.WriteByte(byte(int32(h.Size<<4) | int32(tTypeToCompactType[h.Element])))
+	} else {
+		if err = p.WriteByte(0xf0 | byte(tTypeToCompactType[h.Element])); err == nil {
+			err = p.writeSize(h.Size)
+		}
 	}
 	return
 }
@@ -206,9 +224,9 @@ func (p *tCompactProtocol) WriteBool(v bool) error {
 	if v {
 		c = compactBooleanTrue
 	}
-	if p.booleanWrite != -1 {
+	if p.booleanWriting {
 		err := p.writeFieldHeader(c, p.booleanWrite)
-		p.booleanWrite = -1
+		p.booleanWriting = false
 		return err
 	}
 	return p.WriteByte(c)
@@ -276,6 +294,8 @@ func (p *tCompactProtocol) writeSize(v int) error {
 }
 
 func (p *tCompactProtocol) ReadMessageBegin() (h TMessageHeader, err error) {
+	p.depth = 0
+	resetReadLimit(p.TExtraTransport)
 	var b byte
 	if b, err = p.ReadByte(); err != nil {
 		return
@@ -292,7 +312,9 @@ func (p *tCompactProtocol) ReadMessageBegin() (h TMessageHeader, err error) {
 		return
 	}
 	h.Type = (b >> compactTypeShiftAmount) & compactTypeBits
-	if h.Identity, err = p.ReadI32(); err == nil {
+	var seq uint32
+	if seq, err = p.ReadU32(); err == nil {
+		h.Identity = int32(seq)
 		h.Name, err = p.ReadString()
 	}
 	return
@@ -303,12 +325,16 @@ func (p *tCompactProtocol) ReadMessageEnd() error {
 }
 
 func (p *tCompactProtocol) ReadStructBegin() (h TStructHeader, err error) {
+	if err = p.depth.enter(p.cfg); err != nil {
+		return
+	}
 	p.identityStack.PushBack(p.lastIdentity)
 	p.lastIdentity = 0
 	return
 }
 
 func (p *tCompactProtocol) ReadStructEnd() error {
+	p.depth.leave()
 	e := p.identityStack.Back()
 	p.lastIdentity = e.Value.(int16)
 	p.identityStack.Remove(e)
@@ -352,10 +378,13 @@ func (p *tCompactProtocol) ReadFieldEnd() error {
 }
 
 func (p *tCompactProtocol) ReadMapBegin() (h TMapHeader, err error) {
+	if err = p.depth.enter(p.cfg); err != nil {
+		return
+	}
 	if h.Size, err = p.readSize(); err != nil {
 		return
 	}
-	if err = p.cfg.CheckSizeForProtocol(h.Size); err != nil {
+	if err = p.checkSize(h.Size); err != nil {
 		return
 	}
 	var kvt byte
@@ -377,10 +406,14 @@ func (p *tCompactProtocol) ReadMapBegin() (h TMapHeader, err error) {
 }
 
 func (p *tCompactProtocol) ReadMapEnd() error {
+	p.depth.leave()
 	return nil
 }
 
 func (p *tCompactProtocol) ReadSetBegin() (h TSetHeader, err error) {
+	if err = p.depth.enter(p.cfg); err != nil {
+		return
+	}
 	var b byte
 	if b, err = p.ReadByte(); err != nil {
 		return
@@ -391,7 +424,7 @@ func (p *tCompactProtocol) ReadSetBegin() (h TSetHeader, err error) {
 			return
 		}
 	}
-	if err = p.cfg.CheckSizeForProtocol(h.Size); err != nil {
+	if err = p.checkSize(h.Size); err != nil {
 		return
 	}
 	var ok bool
@@ -403,10 +436,14 @@ func (p *tCompactProtocol) ReadSetBegin() (h TSetHeader, err error) {
 }
 
 func (p *tCompactProtocol) ReadSetEnd() error {
+	p.depth.leave()
 	return nil
 }
 
 func (p *tCompactProtocol) ReadListBegin() (h TListHeader, err error) {
+	if err = p.depth.enter(p.cfg); err != nil {
+		return
+	}
 	var b byte
 	if b, err = p.ReadByte(); err != nil {
 		return
@@ -417,7 +454,7 @@ func (p *tCompactProtocol) ReadListBegin() (h TListHeader, err error) {
 			return
 		}
 	}
-	if err = p.cfg.CheckSizeForProtocol(h.Size); err != nil {
+	if err = p.checkSize(h.Size); err != nil {
 		return
 	}
 	var ok bool
@@ -429,6 +466,7 @@ func (p *tCompactProtocol) ReadListBegin() (h TListHeader, err error) {
 }
 
 func (p *tCompactProtocol) ReadListEnd() error {
+	p.depth.leave()
 	return nil
 }
 
@@ -474,12 +512,16 @@ func (p *tCompactProtocol) ReadI32() (int32, error) {
 }
 
 func (p *tCompactProtocol) ReadU64() (uint64, error) {
-	v, err := binary.ReadUvarint(p.TExtraTransport)
+	v, err := readUvarint(p.TExtraTransport)
 	return v, NewTProtocolExceptionFromError(err)
 }
 
 func (p *tCompactProtocol) ReadI64() (int64, error) {
-	v, err := binary.ReadVarint(p.TExtraTransport)
+	u, err := readUvarint(p.TExtraTransport)
+	v := int64(u >> 1)
+	if u&1 != 0 {
+		v = ^v
+	}
 	return v, NewTProtocolExceptionFromError(err)
 }
 
@@ -494,32 +536,42 @@ func (p *tCompactProtocol) ReadString() (v string, err error) {
 func (p *tCompactProtocol) ReadBinary() (v []byte, err error) {
 	var n int
 	if n, err = p.readSize(); err == nil {
-		if err = p.cfg.CheckSizeForProtocol(n); err != nil {
+		if err = p.checkSize(n); err != nil {
 			return
 		}
-		v = make([]byte, n)
-		_, err = p.Read(v)
+		var buffered bool
+		if v, buffered, err = readBytes(p.TExtraTransport, n); buffered {
+			v = append([]byte(nil), v...)
+		}
+		err = NewTProtocolExceptionFromError(err)
 	}
 	return
 }
 
 func (p *tCompactProtocol) Read(v []byte) (int, error) {
-	n, err := p.TExtraTransport.Read(v)
+	n, err := io.ReadFull(p.TExtraTransport, v)
 	return n, NewTProtocolExceptionFromError(err)
 }
 
 func (p *tCompactProtocol) readSize() (int, error) {
-	v, err := binary.ReadUvarint(p.TExtraTransport)
+	v, err := readUvarint(p.TExtraTransport)
 	return int(v), NewTProtocolExceptionFromError(err)
 }
 
-func (p *tCompactProtocol) readStringBody(n int) (string, error) {
+// checkSize checks size of string or container against configuration and remaining bytes.
+func (p *tCompactProtocol) checkSize(n int) error {
 	if err := p.cfg.CheckSizeForProtocol(n); err != nil {
+		return err
+	}
+	return checkRemainingBytes(p.TExtraTransport, n)
+}
+
+func (p *tCompactProtocol) readStringBody(n int) (string, error) {
+	if err := p.checkSize(n); err != nil {
 		return "", err
 	}
-	v := make([]byte, n)
-	_, err := p.Read(v)
-	return string(v), err
+	v, _, err := readBytes(p.TExtraTransport, n)
+	return string(v), NewTProtocolExceptionFromError(err)
 }
 
 func (p *tCompactProtocol) Skip(v TType) error {
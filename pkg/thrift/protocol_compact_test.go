@@ -1,9 +1,11 @@
 package thrift_test
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/b1avk/thrift/pkg/thrift"
+	"github.com/b1avk/thrift/pkg/thrifttest"
 )
 
 func TestTCompactProtocolMessageHeader(t *testing.T) {
@@ -15,3 +17,31 @@ func TestTCompactProtocolMessageHeader(t *testing.T) {
 		t.Fatal("fail to read message header", err)
 	}
 }
+
+func TestTCompactProtocolListHeader(t *testing.T) {
+	for _, c := range []struct {
+		h    thrift.TListHeader
+		want []byte
+	}{
+		{thrift.TListHeader{Element: thrift.BOOL, Size: 3}, []byte{0x31}},
+		{thrift.TListHeader{Element: thrift.BYTE, Size: 14}, []byte{0xe3}},
+		{thrift.TListHeader{Element: thrift.I32, Size: 15}, []byte{0xf5, 0x0f}},
+	} {
+		b := thrift.NewTMemoryBuffer()
+		p := thrift.NewTCompactProtocol(b, nil)
+		if err := p.WriteListBegin(c.h); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b.Bytes(), c.want) {
+			t.Fatalf("list header %+v: want %x, got %x", c.h, c.want, b.Bytes())
+		}
+		b.Write(make([]byte, c.h.Size))
+		if h, err := p.ReadListBegin(); err != nil || h != c.h {
+			t.Fatalf("list header %+v: got %+v, %v", c.h, h, err)
+		}
+	}
+}
+
+func TestTCompactProtocolConformance(t *testing.T) {
+	thrifttest.TestProtocol(t, thrift.NewTCompactProtocolFactory(nil))
+}
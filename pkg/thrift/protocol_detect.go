@@ -0,0 +1,126 @@
+package thrift
+
+import (
+	"fmt"
+	"io"
+)
+
+// NewTAutoDetectProtocolFactory returns new TProtocolFactory of NewTAutoDetectProtocol.
+func NewTAutoDetectProtocolFactory(cfg *TConfiguration) TProtocolFactory {
+	return &tProtocolFactory{cfg, NewTAutoDetectProtocol}
+}
+
+// NewTAutoDetectProtocol returns new protocol which detects protocol of
+// each message read from first bytes of message; 0x80 0x01 for strict binary,
+// 0x82 for compact, '[' for JSON, otherwise non-strict binary.
+// messages are written with protocol of last message read, binary protocol
+// is used until a message is read.
+func NewTAutoDetectProtocol(t TTransport, cfg *TConfiguration) TProtocol {
+	cfg = cfg.NonNil()
+	nonStrict := *cfg
+	nonStrict.StrictRead = false
+	p := &tAutoDetectProtocol{cfg: cfg, peek: &tPeekTransport{TTransport: t}}
+	cfg.Propagate(t)
+	p.binary = NewTBinaryProtocol(p.peek, &nonStrict)
+	p.TProtocol = p.binary
+	return p
+}
+
+type tAutoDetectProtocol struct {
+	TProtocol
+	cfg                   *TConfiguration
+	peek                  *tPeekTransport
+	binary, compact, json TProtocol
+}
+
+func (p *tAutoDetectProtocol) SetTConfiguration(cfg *TConfiguration) {
+	p.cfg = cfg.NonNil()
+	nonStrict := *p.cfg
+	nonStrict.StrictRead = false
+	p.cfg.Propagate(p.peek.TTransport)
+	p.binary = NewTBinaryProtocol(p.peek, &nonStrict)
+	p.compact, p.json = nil, nil
+	p.TProtocol = p.binary
+}
+
+func (p *tAutoDetectProtocol) GetTConfiguration() *TConfiguration {
+	return p.cfg
+}
+
+func (p *tAutoDetectProtocol) ReadMessageBegin() (h TMessageHeader, err error) {
+	var b []byte
+	p.peek.ResetReadLimit()
+	if b, err = p.peek.peek(2); err != nil {
+		err = NewTProtocolExceptionFromError(err)
+		return
+	}
+	switch {
+	case b[0] == 0x80 && b[1] == 0x01:
+		p.TProtocol = p.binary
+	case b[0] == compactProtocolID:
+		if p.compact == nil {
+			p.compact = NewTCompactProtocol(p.peek, p.cfg)
+		}
+		p.TProtocol = p.compact
+	case b[0] == '[':
+		if p.json == nil {
+			p.json = NewTJSONProtocol(p.peek, p.cfg)
+		}
+		p.TProtocol = p.json
+	case b[0] < 0x80:
+		// length of message name of non-strict binary.
+		p.TProtocol = p.binary
+	default:
+		err = NewTProtocolException(TProtocolErrorBadVersion, fmt.Sprintf("unknown protocol of message: %#x %#x", b[0], b[1]))
+		return
+	}
+	return p.TProtocol.ReadMessageBegin()
+}
+
+// tPeekTransport transport which allows to read bytes ahead without consuming them.
+type tPeekTransport struct {
+	TTransport
+	buf   []byte
+	cache [1]byte
+}
+
+// peek returns next n bytes without consuming them.
+func (t *tPeekTransport) peek(n int) ([]byte, error) {
+	if len(t.buf) < n {
+		b := make([]byte, n)
+		copy(b, t.buf)
+		if _, err := io.ReadFull(t.TTransport, b[len(t.buf):]); err != nil {
+			return nil, NewTTransportExceptionFromError(err)
+		}
+		t.buf = b
+	}
+	return t.buf[:n], nil
+}
+
+func (t *tPeekTransport) Read(b []byte) (n int, err error) {
+	if len(t.buf) == 0 {
+		return t.TTransport.Read(b)
+	}
+	n = copy(b, t.buf)
+	t.buf = t.buf[n:]
+	return
+}
+
+func (t *tPeekTransport) ReadByte() (byte, error) {
+	_, err := io.ReadFull(t, t.cache[:])
+	return t.cache[0], NewTTransportExceptionFromError(err)
+}
+
+func (t *tPeekTransport) WriteByte(b byte) error {
+	t.cache[0] = b
+	_, err := t.Write(t.cache[:])
+	return NewTTransportExceptionFromError(err)
+}
+
+func (t *tPeekTransport) ResetReadLimit() {
+	resetReadLimit(t.TTransport)
+}
+
+func (t *tPeekTransport) SetTConfiguration(cfg *TConfiguration) {
+	cfg.Propagate(t.TTransport)
+}
@@ -0,0 +1,82 @@
+package thrift_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/b1avk/thrift/pkg/thrift"
+)
+
+func TestTAutoDetectProtocol(t *testing.T) {
+	ctx := context.Background()
+	for name, f := range map[string]thrift.TProtocolFactory{
+		"Binary":          thrift.NewTBinaryProtocolFactory(nil),
+		"BinaryNonStrict": thrift.NewTBinaryProtocolFactory(&thrift.TConfiguration{}),
+		"Compact":         thrift.NewTCompactProtocolFactory(nil),
+		"JSON":            thrift.NewTJSONProtocolFactory(nil),
+	} {
+		t.Run(name, func(t *testing.T) {
+			b := thrift.NewTMemoryBuffer()
+			client := f.GetProtocol(b)
+			server := thrift.NewTAutoDetectProtocolFactory(nil).GetProtocol(b)
+			for i := int32(0); i < 2; i++ {
+				h := thrift.TMessageHeader{Name: "Hello", Type: thrift.CALL, Identity: i}
+				v := &thrift.TApplicationException{Message: "World", Type: thrift.TApplicationError(i)}
+				if err := client.WriteMessageBegin(h); err != nil {
+					t.Fatal(err)
+				}
+				if err := v.Write(client); err != nil {
+					t.Fatal(err)
+				}
+				if err := client.WriteMessageEnd(); err != nil {
+					t.Fatal(err)
+				}
+				if err := client.Flush(ctx); err != nil {
+					t.Fatal(err)
+				}
+				r, x := thrift.TMessageHeader{}, new(thrift.TApplicationException)
+				var err error
+				if r, err = server.ReadMessageBegin(); err != nil {
+					t.Fatal(err)
+				}
+				if err = x.Read(server); err != nil {
+					t.Fatal(err)
+				}
+				if err = server.ReadMessageEnd(); err != nil {
+					t.Fatal(err)
+				}
+				if r != h || *x != *v {
+					t.Fatal("value obtained for detected protocol mismatch")
+				}
+
+				// response must be written with the detected protocol.
+				h.Type = thrift.REPLY
+				if err = server.WriteMessageBegin(h); err == nil {
+					if err = server.WriteMessageEnd(); err == nil {
+						err = server.Flush(ctx)
+					}
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if r, err = client.ReadMessageBegin(); err != nil {
+					t.Fatal(err)
+				}
+				if err = client.ReadMessageEnd(); err != nil {
+					t.Fatal(err)
+				}
+				if r != h {
+					t.Fatal("response must be written with protocol of request")
+				}
+			}
+		})
+	}
+}
+
+func TestTAutoDetectProtocolUnknown(t *testing.T) {
+	b := thrift.NewTMemoryBuffer()
+	b.Write([]byte{0xff, 0xff, 0, 0})
+	if _, err := thrift.NewTAutoDetectProtocol(b, nil).ReadMessageBegin(); err == nil {
+		t.Fatal("expected error for unknown protocol")
+	}
+}
@@ -0,0 +1,782 @@
+package thrift
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// NewTJSONProtocolFactory returns new TProtocolFactory of NewTJSONProtocol.
+func NewTJSONProtocolFactory(cfg *TConfiguration) TProtocolFactory {
+	return &tProtocolFactory{cfg, NewTJSONProtocol}
+}
+
+// NewTJSONProtocol returns new JSON protocol.
+// the format is compatible with TJSONProtocol of Apache Thrift,
+// U16, U32 and U64 are written with type names "u16", "u32" and "u64".
+func NewTJSONProtocol(t TTransport, cfg *TConfiguration) TProtocol {
+	p := &tJSONProtocol{cfg: cfg.NonNil()}
+	p.TExtraTransport = NewTExtraTransport(t, p.cfg)
+	return p
+}
+
+const jsonVersion = 1
+
+var tTypeToJSONName = map[TType]string{
+	BOOL:   "tf",
+	BYTE:   "i8",
+	DOUBLE: "dbl",
+	U16:    "u16",
+	I16:    "i16",
+	U32:    "u32",
+	I32:    "i32",
+	U64:    "u64",
+	I64:    "i64",
+	STRING: "str",
+	STRUCT: "rec",
+	MAP:    "map",
+	SET:    "set",
+	LIST:   "lst",
+}
+
+var jsonNameToTType = map[string]TType{}
+
+func init() {
+	for t, name := range tTypeToJSONName {
+		jsonNameToTType[name] = t
+	}
+}
+
+// jsonContext a JSON array or object being written or read.
+type jsonContext struct {
+	// object is true if values alternate between key and value.
+	object bool
+	n      int
+}
+
+type tJSONProtocol struct {
+	TExtraTransport
+	cfg     *TConfiguration
+	context []jsonContext
+	peeked  bool
+	peek    byte
+	buf     []byte
+	depth   tRecursionDepth
+}
+
+func (p *tJSONProtocol) SetTConfiguration(cfg *TConfiguration) {
+	p.cfg = cfg.NonNil()
+	p.cfg.Propagate(p.TExtraTransport)
+}
+
+func (p *tJSONProtocol) GetTConfiguration() *TConfiguration {
+	return p.cfg
+}
+
+func (p *tJSONProtocol) WriteMessageBegin(h TMessageHeader) (err error) {
+	// a failed write may leave contexts of previous message.
+	p.context = p.context[:0]
+	if err = p.writeBegin('['); err == nil {
+		if err = p.writeNumber(strconv.Itoa(jsonVersion)); err == nil {
+			if err = p.WriteString(h.Name); err == nil {
+				if err = p.writeNumber(strconv.Itoa(int(h.Type))); err == nil {
+					err = p.WriteI32(h.Identity)
+				}
+			}
+		}
+	}
+	return
+}
+
+func (p *tJSONProtocol) WriteMessageEnd() error {
+	return p.writeEnd(']')
+}
+
+func (p *tJSONProtocol) WriteStructBegin(h TStructHeader) error {
+	return p.writeBegin('{')
+}
+
+func (p *tJSONProtocol) WriteStructEnd() error {
+	return p.writeEnd('}')
+}
+
+func (p *tJSONProtocol) WriteFieldBegin(h TFieldHeader) (err error) {
+	if err = p.WriteI16(h.Identity); err == nil {
+		if err = p.writeBegin('{'); err == nil {
+			err = p.writeType(h.Type)
+		}
+	}
+	return
+}
+
+func (p *tJSONProtocol) WriteFieldEnd() error {
+	return p.writeEnd('}')
+}
+
+func (p *tJSONProtocol) WriteFieldStop() error {
+	return nil
+}
+
+func (p *tJSONProtocol) WriteMapBegin(h TMapHeader) (err error) {
+	if err = p.writeBegin('['); err == nil {
+		if err = p.writeType(h.Key); err == nil {
+			if err = p.writeType(h.Value); err == nil {
+				if err = p.writeNumber(strconv.Itoa(h.Size)); err == nil {
+					err = p.writeBegin('{')
+				}
+			}
+		}
+	}
+	return
+}
+
+func (p *tJSONProtocol) WriteMapEnd() (err error) {
+	if err = p.writeEnd('}'); err == nil {
+		err = p.writeEnd(']')
+	}
+	return
+}
+
+func (p *tJSONProtocol) WriteSetBegin(h TSetHeader) error {
+	return p.writeListBegin(h.Element, h.Size)
+}
+
+func (p *tJSONProtocol) WriteSetEnd() error {
+	return p.writeEnd(']')
+}
+
+func (p *tJSONProtocol) WriteListBegin(h TListHeader) error {
+	return p.writeListBegin(h.Element, h.Size)
+}
+
+func (p *tJSONProtocol) WriteListEnd() error {
+	return p.writeEnd(']')
+}
+
+func (p *tJSONProtocol) WriteBool(v bool) error {
+	if v {
+		return p.writeNumber("1")
+	}
+	return p.writeNumber("0")
+}
+
+func (p *tJSONProtocol) WriteByte(v byte) error {
+	return p.writeNumber(strconv.Itoa(int(int8(v))))
+}
+
+func (p *tJSONProtocol) WriteDouble(v float64) (err error) {
+	switch {
+	case math.IsNaN(v):
+		return p.writeQuoted("NaN")
+	case math.IsInf(v, 1):
+		return p.writeQuoted("Infinity")
+	case math.IsInf(v, -1):
+		return p.writeQuoted("-Infinity")
+	}
+	return p.writeNumber(strconv.FormatFloat(v, 'g', -1, 64))
+}
+
+func (p *tJSONProtocol) WriteU16(v uint16) error {
+	return p.writeNumber(strconv.FormatUint(uint64(v), 10))
+}
+
+func (p *tJSONProtocol) WriteI16(v int16) error {
+	return p.writeNumber(strconv.FormatInt(int64(v), 10))
+}
+
+func (p *tJSONProtocol) WriteU32(v uint32) error {
+	return p.writeNumber(strconv.FormatUint(uint64(v), 10))
+}
+
+func (p *tJSONProtocol) WriteI32(v int32) error {
+	return p.writeNumber(strconv.FormatInt(int64(v), 10))
+}
+
+func (p *tJSONProtocol) WriteU64(v uint64) error {
+	return p.writeNumber(strconv.FormatUint(v, 10))
+}
+
+func (p *tJSONProtocol) WriteI64(v int64) error {
+	return p.writeNumber(strconv.FormatInt(v, 10))
+}
+
+func (p *tJSONProtocol) WriteString(v string) (err error) {
+	if _, err = p.writeSeparator(); err != nil {
+		return
+	}
+	b := append(p.buf[:0], '"')
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		if c >= 0x20 && c != '"' && c != '\\' {
+			b = append(b, c)
+			continue
+		}
+		switch c {
+		case '"', '\\':
+			b = append(b, '\\', c)
+		case '\b':
+			b = append(b, '\\', 'b')
+		case '\f':
+			b = append(b, '\\', 'f')
+		case '\n':
+			b = append(b, '\\', 'n')
+		case '\r':
+			b = append(b, '\\', 'r')
+		case '\t':
+			b = append(b, '\\', 't')
+		default:
+			b = append(b, fmt.Sprintf(`\u%04x`, c)...)
+		}
+	}
+	p.buf = append(b, '"')
+	_, err = p.Write(p.buf)
+	return
+}
+
+func (p *tJSONProtocol) WriteBinary(v []byte) (err error) {
+	if _, err = p.writeSeparator(); err != nil {
+		return
+	}
+	n := base64.StdEncoding.EncodedLen(len(v))
+	if cap(p.buf) < n+2 {
+		p.buf = make([]byte, n+2)
+	}
+	b := p.buf[:n+2]
+	b[0] = '"'
+	base64.StdEncoding.Encode(b[1:], v)
+	b[n+1] = '"'
+	_, err = p.Write(b)
+	return
+}
+
+func (p *tJSONProtocol) Write(v []byte) (int, error) {
+	n, err := p.TExtraTransport.Write(v)
+	return n, NewTProtocolExceptionFromError(err)
+}
+
+func (p *tJSONProtocol) writeListBegin(e TType, size int) (err error) {
+	if err = p.writeBegin('['); err == nil {
+		if err = p.writeType(e); err == nil {
+			err = p.writeNumber(strconv.Itoa(size))
+		}
+	}
+	return
+}
+
+func (p *tJSONProtocol) writeType(t TType) error {
+	name, ok := tTypeToJSONName[t]
+	if !ok {
+		return NewTProtocolException(TProtocolErrorInvalidData, fmt.Sprintf("unexpected TType: %d", t))
+	}
+	return p.WriteString(name)
+}
+
+// writeSeparator writes separator before next value,
+// it returns true if the value is a key of object.
+func (p *tJSONProtocol) writeSeparator() (key bool, err error) {
+	if len(p.context) == 0 {
+		return
+	}
+	c := &p.context[len(p.context)-1]
+	if c.object && c.n%2 == 1 {
+		err = p.writeRawByte(':')
+	} else if c.n > 0 {
+		err = p.writeRawByte(',')
+	}
+	key = c.object && c.n%2 == 0
+	c.n++
+	return
+}
+
+func (p *tJSONProtocol) writeRawByte(c byte) error {
+	return NewTProtocolExceptionFromError(p.TExtraTransport.WriteByte(c))
+}
+
+func (p *tJSONProtocol) writeNumber(v string) (err error) {
+	var key bool
+	if key, err = p.writeSeparator(); err == nil {
+		if key {
+			_, err = p.Write([]byte(`"` + v + `"`))
+		} else {
+			_, err = p.Write([]byte(v))
+		}
+	}
+	return
+}
+
+func (p *tJSONProtocol) writeQuoted(v string) (err error) {
+	if _, err = p.writeSeparator(); err == nil {
+		_, err = p.Write([]byte(`"` + v + `"`))
+	}
+	return
+}
+
+func (p *tJSONProtocol) writeBegin(c byte) (err error) {
+	if _, err = p.writeSeparator(); err == nil {
+		if err = p.writeRawByte(c); err == nil {
+			p.context = append(p.context, jsonContext{object: c == '{'})
+		}
+	}
+	return
+}
+
+func (p *tJSONProtocol) writeEnd(c byte) error {
+	if err := p.popContext(c); err != nil {
+		return err
+	}
+	return p.writeRawByte(c)
+}
+
+func (p *tJSONProtocol) popContext(c byte) error {
+	if len(p.context) == 0 || p.context[len(p.context)-1].object != (c == '}') {
+		return NewTProtocolException(TProtocolErrorInvalidData, fmt.Sprintf("unexpected %q", c))
+	}
+	p.context = p.context[:len(p.context)-1]
+	return nil
+}
+
+func (p *tJSONProtocol) ReadMessageBegin() (h TMessageHeader, err error) {
+	// a failed read may leave contexts and peeked byte of previous message.
+	p.context = p.context[:0]
+	p.peeked = false
+	p.depth = 0
+	resetReadLimit(p.TExtraTransport)
+	if err = p.readBegin('['); err != nil {
+		return
+	}
+	var version int64
+	if version, err = p.readInteger(32); err != nil {
+		return
+	}
+	if version != jsonVersion {
+		err = NewTProtocolException(TProtocolErrorBadVersion, "bad version in message header")
+		return
+	}
+	if h.Name, err = p.ReadString(); err == nil {
+		if h.Type, err = p.ReadByte(); err == nil {
+			h.Identity, err = p.ReadI32()
+		}
+	}
+	return
+}
+
+func (p *tJSONProtocol) ReadMessageEnd() error {
+	return p.readEnd(']')
+}
+
+func (p *tJSONProtocol) ReadStructBegin() (h TStructHeader, err error) {
+	if err = p.depth.enter(p.cfg); err == nil {
+		err = p.readBegin('{')
+	}
+	return
+}
+
+func (p *tJSONProtocol) ReadStructEnd() error {
+	p.depth.leave()
+	return p.readEnd('}')
+}
+
+func (p *tJSONProtocol) ReadFieldBegin() (h TFieldHeader, err error) {
+	var c byte
+	if c, err = p.peekByte(); err != nil || c == '}' {
+		return
+	}
+	if h.Identity, err = p.ReadI16(); err == nil {
+		if err = p.readBegin('{'); err == nil {
+			h.Type, err = p.readType()
+		}
+	}
+	return
+}
+
+func (p *tJSONProtocol) ReadFieldEnd() error {
+	return p.readEnd('}')
+}
+
+func (p *tJSONProtocol) ReadMapBegin() (h TMapHeader, err error) {
+	if err = p.depth.enter(p.cfg); err != nil {
+		return
+	}
+	if err = p.readBegin('['); err != nil {
+		return
+	}
+	if h.Key, err = p.readType(); err == nil {
+		if h.Value, err = p.readType(); err == nil {
+			if h.Size, err = p.readSize(); err == nil {
+				err = p.readBegin('{')
+			}
+		}
+	}
+	return
+}
+
+func (p *tJSONProtocol) ReadMapEnd() (err error) {
+	p.depth.leave()
+	if err = p.readEnd('}'); err == nil {
+		err = p.readEnd(']')
+	}
+	return
+}
+
+func (p *tJSONProtocol) ReadSetBegin() (h TSetHeader, err error) {
+	h.Element, h.Size, err = p.readListBegin()
+	return
+}
+
+func (p *tJSONProtocol) ReadSetEnd() error {
+	p.depth.leave()
+	return p.readEnd(']')
+}
+
+func (p *tJSONProtocol) ReadListBegin() (h TListHeader, err error) {
+	h.Element, h.Size, err = p.readListBegin()
+	return
+}
+
+func (p *tJSONProtocol) ReadListEnd() error {
+	p.depth.leave()
+	return p.readEnd(']')
+}
+
+func (p *tJSONProtocol) ReadBool() (bool, error) {
+	v, err := p.readInteger(8)
+	return v != 0, err
+}
+
+func (p *tJSONProtocol) ReadByte() (byte, error) {
+	v, err := p.readInteger(8)
+	return byte(v), err
+}
+
+func (p *tJSONProtocol) ReadDouble() (v float64, err error) {
+	var s string
+	if s, err = p.readNumber(); err != nil {
+		return
+	}
+	switch s {
+	case "NaN":
+		return math.NaN(), nil
+	case "Infinity":
+		return math.Inf(1), nil
+	case "-Infinity":
+		return math.Inf(-1), nil
+	}
+	if v, err = strconv.ParseFloat(s, 64); err != nil {
+		err = NewTProtocolException(TProtocolErrorInvalidData, fmt.Sprintf("invalid double: %q", s))
+	}
+	return
+}
+
+func (p *tJSONProtocol) ReadU16() (uint16, error) {
+	v, err := p.readUnsigned(16)
+	return uint16(v), err
+}
+
+func (p *tJSONProtocol) ReadI16() (int16, error) {
+	v, err := p.readInteger(16)
+	return int16(v), err
+}
+
+func (p *tJSONProtocol) ReadU32() (uint32, error) {
+	v, err := p.readUnsigned(32)
+	return uint32(v), err
+}
+
+func (p *tJSONProtocol) ReadI32() (int32, error) {
+	v, err := p.readInteger(32)
+	return int32(v), err
+}
+
+func (p *tJSONProtocol) ReadEnum() (v int32, name string, err error) {
+	var s string
+	if s, err = p.readNumber(); err != nil {
+		return
+	}
+	if i, e := strconv.ParseInt(s, 10, 32); e == nil {
+		return int32(i), "", nil
+	}
+	if s == "" || strings.ContainsAny(s[:1], "+-.0123456789") {
+		err = NewTProtocolException(TProtocolErrorInvalidData, fmt.Sprintf("invalid enum: %q", s))
+	}
+	return 0, s, err
+}
+
+func (p *tJSONProtocol) ReadU64() (uint64, error) {
+	return p.readUnsigned(64)
+}
+
+func (p *tJSONProtocol) ReadI64() (int64, error) {
+	return p.readInteger(64)
+}
+
+func (p *tJSONProtocol) ReadString() (v string, err error) {
+	if err = p.readSeparator(); err == nil {
+		var b []byte
+		if b, err = p.readQuoted(); err == nil {
+			v = string(b)
+		}
+	}
+	return
+}
+
+func (p *tJSONProtocol) ReadBinary() (v []byte, err error) {
+	if err = p.readSeparator(); err != nil {
+		return
+	}
+	var b []byte
+	if b, err = p.readQuoted(); err != nil {
+		return
+	}
+	b = []byte(strings.TrimRight(string(b), "="))
+	v = make([]byte, base64.RawStdEncoding.DecodedLen(len(b)))
+	var n int
+	if n, err = base64.RawStdEncoding.Decode(v, b); err != nil {
+		return nil, NewTProtocolException(TProtocolErrorInvalidData, "invalid base64 binary")
+	}
+	return v[:n], nil
+}
+
+func (p *tJSONProtocol) readListBegin() (e TType, size int, err error) {
+	if err = p.depth.enter(p.cfg); err != nil {
+		return
+	}
+	if err = p.readBegin('['); err == nil {
+		if e, err = p.readType(); err == nil {
+			size, err = p.readSize()
+		}
+	}
+	return
+}
+
+func (p *tJSONProtocol) readType() (t TType, err error) {
+	var name string
+	if name, err = p.ReadString(); err == nil {
+		var ok bool
+		if t, ok = jsonNameToTType[name]; !ok {
+			err = NewTProtocolException(TProtocolErrorInvalidData, fmt.Sprintf("unexpected type name: %q", name))
+		}
+	}
+	return
+}
+
+func (p *tJSONProtocol) readSize() (n int, err error) {
+	var v int64
+	if v, err = p.readInteger(32); err == nil {
+		n = int(v)
+		if err = p.cfg.CheckSizeForProtocol(n); err == nil {
+			// every element takes at least one byte, peeked byte is not in transport.
+			if p.peeked {
+				v--
+			}
+			err = checkRemainingBytes(p.TExtraTransport, int(v))
+		}
+	}
+	return
+}
+
+func (p *tJSONProtocol) readInteger(bits int) (v int64, err error) {
+	var s string
+	if s, err = p.readNumber(); err == nil {
+		if v, err = strconv.ParseInt(s, 10, bits); err != nil {
+			err = NewTProtocolException(TProtocolErrorInvalidData, fmt.Sprintf("invalid i%d: %q", bits, s))
+		}
+	}
+	return
+}
+
+func (p *tJSONProtocol) readUnsigned(bits int) (v uint64, err error) {
+	var s string
+	if s, err = p.readNumber(); err == nil {
+		if v, err = strconv.ParseUint(s, 10, bits); err != nil {
+			err = NewTProtocolException(TProtocolErrorInvalidData, fmt.Sprintf("invalid u%d: %q", bits, s))
+		}
+	}
+	return
+}
+
+// readNumber reads number which may be quoted.
+func (p *tJSONProtocol) readNumber() (v string, err error) {
+	if err = p.readSeparator(); err != nil {
+		return
+	}
+	var c byte
+	if c, err = p.peekByte(); err != nil {
+		return
+	}
+	if c == '"' {
+		var b []byte
+		b, err = p.readQuoted()
+		return string(b), err
+	}
+	b := p.buf[:0]
+	for {
+		if c, err = p.peekByte(); err != nil {
+			break
+		}
+		if !strings.ContainsRune("+-.0123456789Ee", rune(c)) {
+			break
+		}
+		p.peeked = false
+		b = append(b, c)
+	}
+	p.buf = b
+	if len(b) > 0 {
+		err = nil
+	} else if err == nil {
+		err = NewTProtocolException(TProtocolErrorInvalidData, fmt.Sprintf("unexpected %q, expected number", c))
+	}
+	return string(b), err
+}
+
+// readQuoted reads JSON string.
+func (p *tJSONProtocol) readQuoted() (b []byte, err error) {
+	if err = p.expect('"'); err != nil {
+		return
+	}
+	max := p.cfg.GetMaxMessageSize()
+	var c byte
+	for {
+		if c, err = p.readRawByte(); err != nil {
+			return
+		}
+		if c == '"' {
+			return
+		}
+		if len(b) >= max {
+			return nil, NewTProtocolException(TProtocolErrorSizeLimit, fmt.Sprintf("string size exceeded max allowed: %d", max))
+		}
+		if c != '\\' {
+			b = append(b, c)
+			continue
+		}
+		if c, err = p.readRawByte(); err != nil {
+			return
+		}
+		switch c {
+		case '"', '\\', '/':
+			b = append(b, c)
+		case 'b':
+			b = append(b, '\b')
+		case 'f':
+			b = append(b, '\f')
+		case 'n':
+			b = append(b, '\n')
+		case 'r':
+			b = append(b, '\r')
+		case 't':
+			b = append(b, '\t')
+		case 'u':
+			var r rune
+			if r, err = p.readRune(); err != nil {
+				return
+			}
+			if utf16.IsSurrogate(r) {
+				var r2 rune
+				if err = p.expect('\\'); err == nil {
+					if err = p.expect('u'); err == nil {
+						r2, err = p.readRune()
+					}
+				}
+				if err != nil {
+					return
+				}
+				r = utf16.DecodeRune(r, r2)
+			}
+			b = append(b, string(r)...)
+		default:
+			return nil, NewTProtocolException(TProtocolErrorInvalidData, fmt.Sprintf("invalid escape: %q", c))
+		}
+	}
+}
+
+func (p *tJSONProtocol) readRune() (rune, error) {
+	var h [4]byte
+	for i := range h {
+		c, err := p.readRawByte()
+		if err != nil {
+			return 0, err
+		}
+		h[i] = c
+	}
+	v, err := strconv.ParseUint(string(h[:]), 16, 16)
+	if err != nil {
+		return 0, NewTProtocolException(TProtocolErrorInvalidData, fmt.Sprintf("invalid unicode escape: %q", h[:]))
+	}
+	return rune(v), nil
+}
+
+// readSeparator reads separator before next value.
+func (p *tJSONProtocol) readSeparator() (err error) {
+	if len(p.context) == 0 {
+		return
+	}
+	c := &p.context[len(p.context)-1]
+	if c.object && c.n%2 == 1 {
+		err = p.expect(':')
+	} else if c.n > 0 {
+		err = p.expect(',')
+	}
+	c.n++
+	return
+}
+
+func (p *tJSONProtocol) readBegin(c byte) (err error) {
+	if err = p.readSeparator(); err == nil {
+		if err = p.expect(c); err == nil {
+			p.context = append(p.context, jsonContext{object: c == '{'})
+		}
+	}
+	return
+}
+
+func (p *tJSONProtocol) readEnd(c byte) error {
+	if err := p.popContext(c); err != nil {
+		return err
+	}
+	return p.expect(c)
+}
+
+func (p *tJSONProtocol) expect(c byte) error {
+	v, err := p.peekByte()
+	if err == nil {
+		if v != c {
+			return NewTProtocolException(TProtocolErrorInvalidData, fmt.Sprintf("unexpected %q, expected %q", v, c))
+		}
+		p.peeked = false
+	}
+	return err
+}
+
+// peekByte returns next non-whitespace byte without consuming it.
+func (p *tJSONProtocol) peekByte() (c byte, err error) {
+	for !p.peeked {
+		if c, err = p.readRawByte(); err != nil {
+			return
+		}
+		if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+			p.peek, p.peeked = c, true
+		}
+	}
+	return p.peek, nil
+}
+
+func (p *tJSONProtocol) readRawByte() (byte, error) {
+	if p.peeked {
+		p.peeked = false
+		return p.peek, nil
+	}
+	c, err := p.TExtraTransport.ReadByte()
+	return c, NewTProtocolExceptionFromError(err)
+}
+
+func (p *tJSONProtocol) Skip(v TType) error {
+	return Skip(v, p)
+}
+
+func (p *tJSONProtocol) Flush(ctx context.Context) error {
+	return NewTProtocolExceptionFromError(p.TExtraTransport.Flush(ctx))
+}
@@ -0,0 +1,149 @@
+package thrift_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/b1avk/thrift/pkg/thrift"
+	"github.com/b1avk/thrift/pkg/thrifttest"
+)
+
+func TestTJSONProtocolMessage(t *testing.T) {
+	b := thrift.NewTMemoryBuffer()
+	p := thrift.NewTJSONProtocol(b, nil)
+	v := &thrift.TApplicationException{Message: "a\"b\n", Type: thrift.TApplicationError(1)}
+	if err := p.WriteMessageBegin(thrift.TMessageHeader{Name: "Hello", Type: thrift.EXCEPTION, Identity: 7}); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Write(p); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.WriteMessageEnd(); err != nil {
+		t.Fatal(err)
+	}
+	expected := `[1,"Hello",3,7,{"1":{"str":"a\"b\n"},"2":{"i32":1}}]`
+	if s := string(b.Bytes()); s != expected {
+		t.Fatalf("unexpected encoding: %s", s)
+	}
+}
+
+func TestTJSONProtocolReuseAfterError(t *testing.T) {
+	b := thrift.NewTMemoryBuffer()
+	p := thrift.NewTJSONProtocol(b, nil)
+	h := thrift.TMessageHeader{Name: "ping", Type: thrift.CALL, Identity: 1}
+	v := &thrift.TApplicationException{Message: "Hello", Type: thrift.TApplicationError(1)}
+	// an unfinished message.
+	if err := p.WriteMessageBegin(h); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.WriteStructBegin(thrift.TStructHeader{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.ReadMessageBegin(); err != nil {
+		t.Fatal(err)
+	}
+	r := new(thrift.TApplicationException)
+	if err := r.Read(p); err == nil {
+		t.Fatal("expected error on reading unfinished message")
+	}
+	b.Reset()
+	err := p.WriteMessageBegin(h)
+	if err == nil {
+		if err = v.Write(p); err == nil {
+			err = p.WriteMessageEnd()
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := p.ReadMessageBegin(); err != nil || got != h {
+		t.Fatal("fail to read message after error", got, err)
+	}
+	r = new(thrift.TApplicationException)
+	if err = r.Read(p); err == nil {
+		err = p.ReadMessageEnd()
+	}
+	if err != nil {
+		t.Fatal("fail to read message after error", err)
+	}
+	if *r != *v {
+		t.Fatal("message mismatch", r)
+	}
+}
+
+func TestTJSONProtocolValues(t *testing.T) {
+	b := thrift.NewTMemoryBuffer()
+	p := thrift.NewTJSONProtocol(b, nil)
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	must(p.WriteStructBegin(thrift.TStructHeader{}))
+	must(p.WriteFieldBegin(thrift.TFieldHeader{Type: thrift.MAP, Identity: 1}))
+	must(p.WriteMapBegin(thrift.TMapHeader{Key: thrift.DOUBLE, Value: thrift.LIST, Size: 2}))
+	must(p.WriteDouble(1.5))
+	must(p.WriteListBegin(thrift.TListHeader{Element: thrift.BOOL, Size: 2}))
+	must(p.WriteBool(true))
+	must(p.WriteBool(false))
+	must(p.WriteListEnd())
+	must(p.WriteDouble(math.Inf(-1)))
+	must(p.WriteListBegin(thrift.TListHeader{Element: thrift.BOOL}))
+	must(p.WriteListEnd())
+	must(p.WriteMapEnd())
+	must(p.WriteFieldEnd())
+	must(p.WriteFieldBegin(thrift.TFieldHeader{Type: thrift.SET, Identity: 2}))
+	must(p.WriteSetBegin(thrift.TSetHeader{Element: thrift.STRING, Size: 2}))
+	must(p.WriteBinary([]byte{0xff, 0}))
+	must(p.WriteString("é\x01"))
+	must(p.WriteSetEnd())
+	must(p.WriteFieldEnd())
+	must(p.WriteFieldBegin(thrift.TFieldHeader{Type: thrift.BYTE, Identity: 3}))
+	must(p.WriteByte(0xff))
+	must(p.WriteFieldEnd())
+	must(p.WriteFieldBegin(thrift.TFieldHeader{Type: thrift.U64, Identity: 4}))
+	must(p.WriteU64(math.MaxUint64))
+	must(p.WriteFieldEnd())
+	must(p.WriteFieldStop())
+	must(p.WriteStructEnd())
+
+	expected := `{"1":{"map":["dbl","lst",2,{"1.5":["tf",2,1,0],"-Infinity":["tf",0]}]},` +
+		`"2":{"set":["str",2,"/wA=","é\u0001"]},"3":{"i8":-1},"4":{"u64":18446744073709551615}}`
+	if s := string(b.Bytes()); s != expected {
+		t.Fatalf("unexpected encoding: %s", s)
+	}
+
+	// skipping must consume the whole struct.
+	b.Write([]byte(" 1"))
+	must(p.Skip(thrift.STRUCT))
+	if v, err := p.ReadByte(); err != nil || v != 1 {
+		t.Fatal("unexpected value after skip", v, err)
+	}
+}
+
+func TestTJSONProtocolInvalid(t *testing.T) {
+	for _, s := range []string{`[2,"a",1,0]`, `{"1":{"xyz":1}}`, `["i32",1,`, `"\x"`} {
+		b := thrift.NewTMemoryBuffer()
+		b.Write([]byte(s))
+		p := thrift.NewTJSONProtocol(b, nil)
+		var err error
+		switch s[0] {
+		case '[':
+			if _, err = p.ReadMessageBegin(); err == nil {
+				_, err = p.ReadListBegin()
+			}
+		case '{':
+			err = p.Skip(thrift.STRUCT)
+		default:
+			_, err = p.ReadString()
+		}
+		if err == nil {
+			t.Fatalf("expected error for %s", s)
+		}
+	}
+}
+
+func TestTJSONProtocolConformance(t *testing.T) {
+	thrifttest.TestProtocol(t, thrift.NewTJSONProtocolFactory(nil))
+}
@@ -0,0 +1,452 @@
+package thrift
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// a record stream is an optional header followed by frames.
+// header: magic "TREC", version, flags, protocol id and 8 bytes fingerprint.
+// frame: sync marker, length, payload and CRC32C of payload if enabled.
+// payload is an encoded record, or a flate-compressed block of
+// length-prefixed records if compression is enabled.
+
+const (
+	DefaultRecordBlockSize = 64 << 10
+	DefaultMaxRecordSize   = 16 << 20
+)
+
+const (
+	tRecordVersion = 1
+
+	tRecordFlagFixedLength = 1 << iota
+	tRecordFlagChecksum
+	tRecordFlagCompress
+)
+
+var (
+	tRecordMagic = [4]byte{'T', 'R', 'E', 'C'}
+	tRecordSync  = [4]byte{0xd7, 0x3b, 0x91, 0x5c}
+	crc32c       = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// ErrCorruptRecord returned by TRecordReader when a record is corrupt,
+// the record is skipped and next Read resumes from next valid record.
+var ErrCorruptRecord = errors.New("thrift: corrupt record")
+
+// TRecordHeader header of record stream.
+type TRecordHeader struct {
+	// ProtocolID identifies protocol of records, such as 0x82 for compact.
+	ProtocolID byte
+
+	// Fingerprint identifies schema of records.
+	Fingerprint uint64
+}
+
+// TRecordOptions options of record stream.
+// if WithHeader is true, the writer stores options in header
+// and the reader takes options from header.
+type TRecordOptions struct {
+	WithHeader bool
+	Header     TRecordHeader
+
+	// FixedLength uses 4 bytes big-endian length instead of varint.
+	FixedLength bool
+
+	// Checksum appends CRC32C to each frame.
+	Checksum bool
+
+	// Compress compresses blocks of records with compress/flate.
+	Compress bool
+
+	// BlockSize uncompressed size of block, DefaultRecordBlockSize if < 1.
+	BlockSize int
+
+	// MaxRecordSize max size of frame, DefaultMaxRecordSize if < 1.
+	MaxRecordSize int
+}
+
+func (o *TRecordOptions) flags() (f byte) {
+	if o.FixedLength {
+		f |= tRecordFlagFixedLength
+	}
+	if o.Checksum {
+		f |= tRecordFlagChecksum
+	}
+	if o.Compress {
+		f |= tRecordFlagCompress
+	}
+	return
+}
+
+func (o *TRecordOptions) setFlags(f byte) {
+	o.FixedLength = f&tRecordFlagFixedLength != 0
+	o.Checksum = f&tRecordFlagChecksum != 0
+	o.Compress = f&tRecordFlagCompress != 0
+}
+
+func (o *TRecordOptions) getBlockSize() int {
+	if o.BlockSize < 1 {
+		return DefaultRecordBlockSize
+	}
+	return o.BlockSize
+}
+
+func (o *TRecordOptions) getMaxRecordSize() int {
+	if o.MaxRecordSize < 1 {
+		return DefaultMaxRecordSize
+	}
+	return o.MaxRecordSize
+}
+
+// TRecordWriter writes a sequence of TStruct to io.Writer.
+// *dynamic.Struct and dynamic.TStructOf may be used to write dynamic values.
+type TRecordWriter struct {
+	w     io.Writer
+	opts  TRecordOptions
+	s     *TSerializer
+	block bytes.Buffer
+	z     *flate.Writer
+	zbuf  bytes.Buffer
+	buf   [binary.MaxVarintLen64]byte
+}
+
+// NewTRecordWriter returns new TRecordWriter which encodes records with protocol of f,
+// the header is written if opts.WithHeader is true.
+func NewTRecordWriter(w io.Writer, f TProtocolFactory, opts TRecordOptions) (*TRecordWriter, error) {
+	r := &TRecordWriter{w: w, opts: opts, s: NewTSerializer(f)}
+	if opts.WithHeader {
+		var h [15]byte
+		copy(h[:], tRecordMagic[:])
+		h[4] = tRecordVersion
+		h[5] = opts.flags()
+		h[6] = opts.Header.ProtocolID
+		binary.BigEndian.PutUint64(h[7:], opts.Header.Fingerprint)
+		if _, err := w.Write(h[:]); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Write writes msg as next record, records are buffered until block
+// is full or Flush is called if compression is enabled.
+func (w *TRecordWriter) Write(ctx context.Context, msg TStruct) error {
+	b, err := w.s.Serialize(ctx, msg)
+	if err != nil {
+		return err
+	}
+	if !w.opts.Compress {
+		return w.writeFrame(b)
+	}
+	if len(b) > w.opts.getMaxRecordSize() {
+		return NewTProtocolException(TProtocolErrorSizeLimit, fmt.Sprintf("record size exceeded max allowed: %d", len(b)))
+	}
+	w.block.Write(w.putLength(len(b)))
+	w.block.Write(b)
+	if w.block.Len() >= w.opts.getBlockSize() {
+		return w.Flush()
+	}
+	return nil
+}
+
+// Flush writes buffered block of records.
+func (w *TRecordWriter) Flush() (err error) {
+	if w.block.Len() == 0 {
+		return
+	}
+	w.zbuf.Reset()
+	if w.z == nil {
+		w.z, _ = flate.NewWriter(&w.zbuf, flate.DefaultCompression)
+	} else {
+		w.z.Reset(&w.zbuf)
+	}
+	if _, err = w.z.Write(w.block.Bytes()); err == nil {
+		if err = w.z.Close(); err == nil {
+			w.block.Reset()
+			err = w.writeFrame(w.zbuf.Bytes())
+		}
+	}
+	return
+}
+
+func (w *TRecordWriter) putLength(n int) []byte {
+	if w.opts.FixedLength {
+		binary.BigEndian.PutUint32(w.buf[:], uint32(n))
+		return w.buf[:4]
+	}
+	return w.buf[:binary.PutUvarint(w.buf[:], uint64(n))]
+}
+
+func (w *TRecordWriter) writeFrame(b []byte) (err error) {
+	if len(b) > w.opts.getMaxRecordSize() {
+		return NewTProtocolException(TProtocolErrorSizeLimit, fmt.Sprintf("record size exceeded max allowed: %d", len(b)))
+	}
+	if _, err = w.w.Write(tRecordSync[:]); err != nil {
+		return
+	}
+	if _, err = w.w.Write(w.putLength(len(b))); err != nil {
+		return
+	}
+	if _, err = w.w.Write(b); err != nil {
+		return
+	}
+	if w.opts.Checksum {
+		binary.BigEndian.PutUint32(w.buf[:], crc32.Checksum(b, crc32c))
+		_, err = w.w.Write(w.buf[:4])
+	}
+	return
+}
+
+// TRecordReader reads a sequence of TStruct written by TRecordWriter.
+type TRecordReader struct {
+	r      *tRecordSource
+	opts   TRecordOptions
+	d      *TDeserializer
+	synced bool
+	rescan bool
+	frame  []byte
+	block  []byte
+	zbuf   bytes.Buffer
+	z      io.ReadCloser
+}
+
+// NewTRecordReader returns new TRecordReader which decodes records with protocol of f,
+// the header is read if opts.WithHeader is true.
+func NewTRecordReader(r io.Reader, f TProtocolFactory, opts TRecordOptions) (*TRecordReader, error) {
+	rr := &TRecordReader{r: &tRecordSource{r: bufio.NewReader(r)}, opts: opts, d: NewTDeserializer(f)}
+	if opts.WithHeader {
+		var h [15]byte
+		if _, err := io.ReadFull(rr.r, h[:]); err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(h[:4], tRecordMagic[:]) || h[4] != tRecordVersion {
+			return nil, NewTProtocolException(TProtocolErrorBadVersion, "bad record stream header")
+		}
+		rr.opts.setFlags(h[5])
+		rr.opts.Header.ProtocolID = h[6]
+		rr.opts.Header.Fingerprint = binary.BigEndian.Uint64(h[7:])
+	}
+	return rr, nil
+}
+
+// Options returns options of r, which includes the header read.
+func (r *TRecordReader) Options() TRecordOptions {
+	return r.opts
+}
+
+// Read reads next record to msg, it returns io.EOF at end of stream.
+// it returns error wrapping ErrCorruptRecord if a record is corrupt,
+// the next call resumes from the next valid record.
+func (r *TRecordReader) Read(ctx context.Context, msg TStruct) (err error) {
+	var b []byte
+	if r.opts.Compress {
+		b, err = r.nextBlockRecord()
+	} else {
+		b, err = r.nextFrame()
+	}
+	if err == nil {
+		if err = r.d.Deserialize(ctx, b, msg); err != nil {
+			err = fmt.Errorf("%w: %v", ErrCorruptRecord, err)
+		}
+	}
+	return
+}
+
+func (r *TRecordReader) nextBlockRecord() ([]byte, error) {
+	for len(r.block) == 0 {
+		b, err := r.nextFrame()
+		if err != nil {
+			return nil, err
+		}
+		r.zbuf.Reset()
+		if r.z == nil {
+			r.z = flate.NewReader(bytes.NewReader(b))
+		} else {
+			r.z.(flate.Resetter).Reset(bytes.NewReader(b), nil)
+		}
+		// a block holds records up to block size and one more record.
+		limit := 2 * int64(r.opts.getMaxRecordSize())
+		if _, err = r.zbuf.ReadFrom(io.LimitReader(r.z, limit+1)); err != nil {
+			r.unread()
+			return nil, fmt.Errorf("%w: %v", ErrCorruptRecord, err)
+		}
+		if int64(r.zbuf.Len()) > limit {
+			return nil, fmt.Errorf("%w: block size exceeded max allowed", ErrCorruptRecord)
+		}
+		r.block = r.zbuf.Bytes()
+	}
+	var n, m int
+	if r.opts.FixedLength {
+		if len(r.block) >= 4 {
+			n, m = int(binary.BigEndian.Uint32(r.block)), 4
+		}
+	} else {
+		x, l := binary.Uvarint(r.block)
+		n, m = int(x), l
+		if x > uint64(len(r.block)) {
+			m = 0
+		}
+	}
+	if m <= 0 || n < 0 || n > len(r.block)-m {
+		r.block = nil
+		return nil, fmt.Errorf("%w: bad record length in block", ErrCorruptRecord)
+	}
+	b := r.block[m : m+n]
+	r.block = r.block[m+n:]
+	return b, nil
+}
+
+// sync reads until sync marker, it returns true if any byte was skipped.
+func (r *TRecordReader) sync() (skipped bool, err error) {
+	var w [4]byte
+	n := 0
+	for {
+		var c byte
+		if c, err = r.r.ReadByte(); err != nil {
+			if err == io.EOF && n > 0 && !r.rescan {
+				err = fmt.Errorf("%w: truncated stream", ErrCorruptRecord)
+			}
+			return
+		}
+		if n < 4 {
+			w[n] = c
+			n++
+		} else {
+			copy(w[:], w[1:])
+			w[3] = c
+			skipped = true
+		}
+		if n == 4 && w == tRecordSync {
+			return
+		}
+	}
+}
+
+// nextFrame reads next frame, bytes of frame are kept until next call
+// to be scanned again if the frame turns out to be corrupt.
+func (r *TRecordReader) nextFrame() (b []byte, err error) {
+	r.r.track(false)
+	if !r.synced {
+		var skipped bool
+		if skipped, err = r.sync(); err != nil {
+			return
+		}
+		if skipped && !r.rescan {
+			r.synced = true
+			return nil, fmt.Errorf("%w: bytes skipped before sync marker", ErrCorruptRecord)
+		}
+	}
+	r.synced = false
+	r.rescan = false
+	r.r.track(true)
+	if b, err = r.readFrame(); err != nil {
+		r.unread()
+	}
+	return
+}
+
+func (r *TRecordReader) readFrame() (b []byte, err error) {
+	var n uint64
+	if r.opts.FixedLength {
+		var l [4]byte
+		if _, err = io.ReadFull(r.r, l[:]); err == nil {
+			n = uint64(binary.BigEndian.Uint32(l[:]))
+		}
+	} else {
+		n, err = binary.ReadUvarint(r.r)
+	}
+	if err != nil {
+		return nil, corruptRecord(err)
+	}
+	if n > uint64(r.opts.getMaxRecordSize()) {
+		return nil, fmt.Errorf("%w: record size %d exceeded max allowed", ErrCorruptRecord, n)
+	}
+	if cap(r.frame) < int(n) {
+		r.frame = make([]byte, n)
+	}
+	b = r.frame[:n]
+	if _, err = io.ReadFull(r.r, b); err != nil {
+		return nil, corruptRecord(err)
+	}
+	if r.opts.Checksum {
+		var c [4]byte
+		if _, err = io.ReadFull(r.r, c[:]); err != nil {
+			return nil, corruptRecord(err)
+		}
+		if binary.BigEndian.Uint32(c[:]) != crc32.Checksum(b, crc32c) {
+			return nil, fmt.Errorf("%w: checksum mismatch", ErrCorruptRecord)
+		}
+	}
+	return
+}
+
+// unread returns bytes of corrupt frame to be scanned for next sync marker,
+// since length of frame may be corrupt.
+func (r *TRecordReader) unread() {
+	r.r.unread()
+	r.rescan = true
+}
+
+// corruptRecord wraps err of reading frame, unexpected end of stream
+// is reported as a corrupt record.
+func corruptRecord(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("%w: truncated stream", ErrCorruptRecord)
+	}
+	return err
+}
+
+// tRecordSource reader of record stream which keeps bytes read since track,
+// so they can be read again by unread.
+type tRecordSource struct {
+	r        *bufio.Reader
+	pending  []byte
+	consumed []byte
+	tracking bool
+}
+
+func (s *tRecordSource) Read(b []byte) (n int, err error) {
+	if len(s.pending) > 0 {
+		n = copy(b, s.pending)
+		s.pending = s.pending[n:]
+	} else {
+		n, err = s.r.Read(b)
+	}
+	if s.tracking {
+		s.consumed = append(s.consumed, b[:n]...)
+	}
+	return
+}
+
+func (s *tRecordSource) ReadByte() (c byte, err error) {
+	if len(s.pending) > 0 {
+		c = s.pending[0]
+		s.pending = s.pending[1:]
+	} else if c, err = s.r.ReadByte(); err != nil {
+		return
+	}
+	if s.tracking {
+		s.consumed = append(s.consumed, c)
+	}
+	return
+}
+
+// track starts or stops keeping bytes read.
+func (s *tRecordSource) track(on bool) {
+	s.consumed = s.consumed[:0]
+	s.tracking = on
+}
+
+// unread returns bytes read since track to be read again.
+func (s *tRecordSource) unread() {
+	s.pending = append(append([]byte(nil), s.consumed...), s.pending...)
+	s.track(false)
+}
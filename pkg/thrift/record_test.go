@@ -0,0 +1,120 @@
+package thrift_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/b1avk/thrift/pkg/thrift"
+)
+
+func writeRecords(t *testing.T, opts thrift.TRecordOptions, n int) []byte {
+	var b bytes.Buffer
+	w, err := thrift.NewTRecordWriter(&b, nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		v := &thrift.TApplicationException{Message: fmt.Sprint("record ", i), Type: thrift.TApplicationError(i)}
+		if err = w.Write(context.Background(), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// readRecords returns type of valid records and number of corrupt records.
+func readRecords(t *testing.T, b []byte, opts thrift.TRecordOptions) (r []int, corrupt int) {
+	rr, err := thrift.NewTRecordReader(bytes.NewReader(b), nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		v := new(thrift.TApplicationException)
+		err = rr.Read(context.Background(), v)
+		if err == io.EOF {
+			return
+		}
+		if errors.Is(err, thrift.ErrCorruptRecord) {
+			corrupt++
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = append(r, int(v.Type))
+	}
+}
+
+func TestRecord(t *testing.T) {
+	for name, opts := range map[string]thrift.TRecordOptions{
+		"Varint":     {},
+		"Fixed":      {FixedLength: true, Checksum: true},
+		"Header":     {WithHeader: true, Checksum: true, Header: thrift.TRecordHeader{ProtocolID: 0x80, Fingerprint: 42}},
+		"Compressed": {WithHeader: true, Compress: true, BlockSize: 64},
+	} {
+		t.Run(name, func(t *testing.T) {
+			b := writeRecords(t, opts, 10)
+			readOpts := opts
+			if opts.WithHeader {
+				readOpts = thrift.TRecordOptions{WithHeader: true}
+			}
+			r, corrupt := readRecords(t, b, readOpts)
+			if len(r) != 10 || corrupt != 0 {
+				t.Fatalf("expected 10 valid records, got %v and %d corrupt", r, corrupt)
+			}
+			for i, x := range r {
+				if x != i {
+					t.Fatalf("unexpected record order: %v", r)
+				}
+			}
+		})
+	}
+}
+
+func TestRecordHeader(t *testing.T) {
+	opts := thrift.TRecordOptions{WithHeader: true, FixedLength: true, Header: thrift.TRecordHeader{ProtocolID: 0x82, Fingerprint: 1 << 40}}
+	b := writeRecords(t, opts, 1)
+	r, err := thrift.NewTRecordReader(bytes.NewReader(b), nil, thrift.TRecordOptions{WithHeader: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o := r.Options(); o.Header != opts.Header || !o.FixedLength || o.Checksum || o.Compress {
+		t.Fatalf("unexpected options: %+v", o)
+	}
+	if _, err = thrift.NewTRecordReader(bytes.NewReader([]byte("not a record stream")), nil, thrift.TRecordOptions{WithHeader: true}); err == nil {
+		t.Fatal("expected error for bad header")
+	}
+}
+
+func TestRecordCorrupt(t *testing.T) {
+	opts := thrift.TRecordOptions{Checksum: true}
+	one := len(writeRecords(t, opts, 1))
+	b := writeRecords(t, opts, 5)
+
+	// payload of second record.
+	x := append([]byte(nil), b...)
+	x[one+8] ^= 0xff
+	if r, corrupt := readRecords(t, x, opts); fmt.Sprint(r) != "[0 2 3 4]" || corrupt != 1 {
+		t.Fatalf("unexpected result for corrupt payload: %v, %d", r, corrupt)
+	}
+
+	// length of third record.
+	x = append([]byte(nil), b...)
+	x[2*one+4] = 0xff
+	if r, corrupt := readRecords(t, x, opts); fmt.Sprint(r) != "[0 1 3 4]" || corrupt == 0 {
+		t.Fatalf("unexpected result for corrupt length: %v, %d", r, corrupt)
+	}
+
+	// garbage between records and truncated tail.
+	x = append(append(append([]byte(nil), b[:one]...), "garbage"...), b[one:len(b)-3]...)
+	if r, corrupt := readRecords(t, x, opts); fmt.Sprint(r) != "[0 1 2 3]" || corrupt != 2 {
+		t.Fatalf("unexpected result for garbage: %v, %d", r, corrupt)
+	}
+}
@@ -0,0 +1,140 @@
+package thrift
+
+import (
+	"context"
+	"sync"
+)
+
+// TSerializer serializes TStruct to bytes with a reused buffer,
+// it is not safe for concurrent use, see TSerializerPool.
+type TSerializer struct {
+	Transport *TMemoryBuffer
+	Protocol  TProtocol
+	factory   TProtocolFactory
+}
+
+// NewTSerializer returns new TSerializer with protocol of f.
+// binary protocol is used if f is nil.
+func NewTSerializer(f TProtocolFactory) *TSerializer {
+	if f == nil {
+		f = NewTBinaryProtocolFactory(nil)
+	}
+	b := NewTMemoryBuffer()
+	return &TSerializer{b, f.GetProtocol(b), f}
+}
+
+// Serialize returns encoding of msg.
+func (s *TSerializer) Serialize(ctx context.Context, msg TStruct) (b []byte, err error) {
+	s.Transport.Reset()
+	if err = msg.Write(s.Protocol); err == nil {
+		if err = s.Protocol.Flush(ctx); err == nil {
+			b = append([]byte(nil), s.Transport.Bytes()...)
+		}
+	}
+	if err != nil {
+		s.reset()
+	}
+	return
+}
+
+// SerializeString returns encoding of msg as string.
+func (s *TSerializer) SerializeString(ctx context.Context, msg TStruct) (string, error) {
+	b, err := s.Serialize(ctx, msg)
+	return string(b), err
+}
+
+// reset replaces protocol which may be left in a broken state by an error.
+func (s *TSerializer) reset() {
+	s.Transport.Reset()
+	s.Protocol = s.factory.GetProtocol(s.Transport)
+}
+
+// TDeserializer deserializes TStruct from bytes with a reused buffer,
+// it is not safe for concurrent use, see TDeserializerPool.
+type TDeserializer struct {
+	Transport *TMemoryBuffer
+	Protocol  TProtocol
+	factory   TProtocolFactory
+}
+
+// NewTDeserializer returns new TDeserializer with protocol of f.
+// binary protocol is used if f is nil.
+func NewTDeserializer(f TProtocolFactory) *TDeserializer {
+	if f == nil {
+		f = NewTBinaryProtocolFactory(nil)
+	}
+	b := NewTMemoryBuffer()
+	return &TDeserializer{b, f.GetProtocol(b), f}
+}
+
+// Deserialize reads msg from b.
+func (d *TDeserializer) Deserialize(ctx context.Context, b []byte, msg TStruct) (err error) {
+	d.Transport.Reset()
+	d.Transport.Write(b)
+	if err = msg.Read(d.Protocol); err != nil {
+		d.Transport.Reset()
+		d.Protocol = d.factory.GetProtocol(d.Transport)
+	}
+	return
+}
+
+// DeserializeString reads msg from s.
+func (d *TDeserializer) DeserializeString(ctx context.Context, s string, msg TStruct) error {
+	return d.Deserialize(ctx, []byte(s), msg)
+}
+
+// TSerializerPool a goroutine-safe pool of TSerializer.
+type TSerializerPool struct {
+	pool sync.Pool
+}
+
+// NewTSerializerPool returns new TSerializerPool with protocol of f.
+func NewTSerializerPool(f TProtocolFactory) *TSerializerPool {
+	return &TSerializerPool{sync.Pool{
+		New: func() interface{} {
+			return NewTSerializer(f)
+		},
+	}}
+}
+
+// Serialize returns encoding of msg.
+func (p *TSerializerPool) Serialize(ctx context.Context, msg TStruct) ([]byte, error) {
+	s := p.pool.Get().(*TSerializer)
+	defer p.pool.Put(s)
+	return s.Serialize(ctx, msg)
+}
+
+// SerializeString returns encoding of msg as string.
+func (p *TSerializerPool) SerializeString(ctx context.Context, msg TStruct) (string, error) {
+	s := p.pool.Get().(*TSerializer)
+	defer p.pool.Put(s)
+	return s.SerializeString(ctx, msg)
+}
+
+// TDeserializerPool a goroutine-safe pool of TDeserializer.
+type TDeserializerPool struct {
+	pool sync.Pool
+}
+
+// NewTDeserializerPool returns new TDeserializerPool with protocol of f.
+func NewTDeserializerPool(f TProtocolFactory) *TDeserializerPool {
+	return &TDeserializerPool{sync.Pool{
+		New: func() interface{} {
+			return NewTDeserializer(f)
+		},
+	}}
+}
+
+// Deserialize reads msg from b.
+func (p *TDeserializerPool) Deserialize(ctx context.Context, b []byte, msg TStruct) error {
+	d := p.pool.Get().(*TDeserializer)
+	defer p.pool.Put(d)
+	return d.Deserialize(ctx, b, msg)
+}
+
+// DeserializeString reads msg from s.
+func (p *TDeserializerPool) DeserializeString(ctx context.Context, s string, msg TStruct) error {
+	d := p.pool.Get().(*TDeserializer)
+	defer p.pool.Put(d)
+	return d.DeserializeString(ctx, s, msg)
+}
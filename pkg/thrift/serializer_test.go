@@ -0,0 +1,64 @@
+package thrift_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/b1avk/thrift/pkg/thrift"
+)
+
+func TestSerializer(t *testing.T) {
+	ctx := context.Background()
+	for name, f := range map[string]thrift.TProtocolFactory{
+		"Binary":  thrift.NewTBinaryProtocolFactory(nil),
+		"Compact": thrift.NewTCompactProtocolFactory(nil),
+	} {
+		t.Run(name, func(t *testing.T) {
+			s, d := thrift.NewTSerializer(f), thrift.NewTDeserializer(f)
+			for i := 0; i < 3; i++ {
+				v := &thrift.TApplicationException{Message: "Hello", Type: thrift.TApplicationError(i)}
+				b, err := s.SerializeString(ctx, v)
+				if err != nil {
+					t.Fatal(err)
+				}
+				r := new(thrift.TApplicationException)
+				if err = d.DeserializeString(ctx, b, r); err != nil {
+					t.Fatal(err)
+				}
+				if *r != *v {
+					t.Fatal("value obtained for serialize and deserialize mismatch")
+				}
+			}
+			if err := d.Deserialize(ctx, []byte{0xff}, new(thrift.TApplicationException)); err == nil {
+				t.Fatal("expected error for invalid data")
+			}
+		})
+	}
+}
+
+func TestSerializerPool(t *testing.T) {
+	ctx := context.Background()
+	s, d := thrift.NewTSerializerPool(nil), thrift.NewTDeserializerPool(nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				v := &thrift.TApplicationException{Message: "Hello", Type: thrift.TApplicationError(i)}
+				b, err := s.Serialize(ctx, v)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				r := new(thrift.TApplicationException)
+				if err = d.Deserialize(ctx, b, r); err != nil || *r != *v {
+					t.Error("value obtained for serialize and deserialize mismatch")
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,380 @@
+package thrift
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// TServerOptions options of TServer.
+type TServerOptions struct {
+	// InputTransportFactory wraps accepted transport for reading, if non-nil.
+	InputTransportFactory TTransportFactory
+
+	// OutputTransportFactory wraps accepted transport for writing,
+	// the input transport is used if nil.
+	OutputTransportFactory TTransportFactory
+
+	// InputProtocolFactory protocol of requests, binary if nil.
+	InputProtocolFactory TProtocolFactory
+
+	// OutputProtocolFactory protocol of replies, InputProtocolFactory if nil.
+	OutputProtocolFactory TProtocolFactory
+
+	// MaxConnections max concurrent connections, unlimited if < 1.
+	// accepting waits until a connection is closed if the limit is reached.
+	MaxConnections int
+
+	// MaxInFlight max requests processed at once, unlimited if < 1.
+	MaxInFlight int
+
+	// Workers number of goroutines which process requests, if > 0.
+	// otherwise requests are processed by goroutine of their connection.
+	Workers int
+
+	// ErrorHandler is called with error of connection t which is closed by the error.
+	ErrorHandler func(t TTransport, err error)
+
+	// EventHandler observes the server and its connections, if non-nil.
+	EventHandler TServerEventHandler
+}
+
+// TServerEventHandler hooks of TServer, it may be called concurrently
+// for different connections.
+type TServerEventHandler interface {
+	// PreServe is called once server is listening, before accepting connections.
+	PreServe()
+
+	// CreateContext is called when a connection is accepted, ctx holds
+	// the peer address and transport of the connection.
+	// returned context is passed to each call of the connection.
+	CreateContext(ctx context.Context, in, out TProtocol) context.Context
+
+	// DeleteContext is called with context of connection before it is closed.
+	DeleteContext(ctx context.Context, in, out TProtocol)
+
+	// ProcessContext is called with context of connection before each call is processed.
+	ProcessContext(ctx context.Context, t TTransport)
+}
+
+// TServer a concurrent server which serves each connection in a goroutine.
+type TServer struct {
+	transport TServerTransport
+	processor TProcessor
+	opts      TServerOptions
+
+	mutex    sync.Mutex
+	conns    map[*tServerConn]struct{}
+	stopping bool
+	quit     chan struct{}
+	wg       sync.WaitGroup
+
+	connSlots chan struct{}
+	inFlight  chan struct{}
+	jobs      chan func()
+}
+
+type tServerConn struct {
+	t    TTransport
+	busy bool
+}
+
+var errTServerStopped = errors.New("thrift: server stopped")
+
+// NewTServer returns new TServer which serves connections of transport with processor.
+func NewTServer(transport TServerTransport, processor TProcessor, opts TServerOptions) *TServer {
+	if opts.InputProtocolFactory == nil {
+		opts.InputProtocolFactory = NewTBinaryProtocolFactory(nil)
+	}
+	if opts.OutputProtocolFactory == nil {
+		opts.OutputProtocolFactory = opts.InputProtocolFactory
+	}
+	s := &TServer{
+		transport: transport,
+		processor: processor,
+		opts:      opts,
+		conns:     make(map[*tServerConn]struct{}),
+		quit:      make(chan struct{}),
+	}
+	if opts.MaxConnections > 0 {
+		s.connSlots = make(chan struct{}, opts.MaxConnections)
+	}
+	if opts.MaxInFlight > 0 {
+		s.inFlight = make(chan struct{}, opts.MaxInFlight)
+	}
+	if opts.Workers > 0 {
+		s.jobs = make(chan func())
+	}
+	return s
+}
+
+// Serve listens and serves connections until Stop is called.
+// it returns nil after Stop.
+func (s *TServer) Serve() error {
+	if err := s.transport.Listen(); err != nil {
+		return err
+	}
+	if s.opts.EventHandler != nil {
+		s.opts.EventHandler.PreServe()
+	}
+	for i := 0; i < s.opts.Workers; i++ {
+		go s.work()
+	}
+	var delay time.Duration
+	for {
+		if s.connSlots != nil {
+			select {
+			case s.connSlots <- struct{}{}:
+			case <-s.quit:
+				return nil
+			}
+		}
+		t, err := s.transport.Accept()
+		if err != nil {
+			s.releaseConnSlot()
+			if s.isStopping() {
+				return nil
+			}
+			var temporary interface{ Temporary() bool }
+			if errors.As(err, &temporary) && temporary.Temporary() {
+				if delay = 2*delay + 5*time.Millisecond; delay > time.Second {
+					delay = time.Second
+				}
+				time.Sleep(delay)
+				continue
+			}
+			return err
+		}
+		delay = 0
+		c := &tServerConn{t: t}
+		s.mutex.Lock()
+		if s.stopping {
+			s.mutex.Unlock()
+			closeTransport(t)
+			s.releaseConnSlot()
+			return nil
+		}
+		s.conns[c] = struct{}{}
+		s.wg.Add(1)
+		s.mutex.Unlock()
+		go s.serve(c)
+	}
+}
+
+// Stop stops accepting and closes idle connections, then waits for
+// in-flight calls to finish until ctx is done; remaining connections are closed.
+func (s *TServer) Stop(ctx context.Context) error {
+	var err error
+	s.mutex.Lock()
+	if !s.stopping {
+		s.stopping = true
+		close(s.quit)
+		err = s.transport.Close()
+	}
+	for c := range s.conns {
+		if !c.busy {
+			closeTransport(c.t)
+		}
+	}
+	s.mutex.Unlock()
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return err
+	case <-ctx.Done():
+		s.mutex.Lock()
+		for c := range s.conns {
+			closeTransport(c.t)
+		}
+		s.mutex.Unlock()
+		return ctx.Err()
+	}
+}
+
+func (s *TServer) isStopping() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.stopping
+}
+
+func (s *TServer) releaseConnSlot() {
+	if s.connSlots != nil {
+		<-s.connSlots
+	}
+}
+
+func (s *TServer) work() {
+	for {
+		select {
+		case job := <-s.jobs:
+			job()
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+func (s *TServer) serve(c *tServerConn) {
+	defer func() {
+		s.mutex.Lock()
+		delete(s.conns, c)
+		s.mutex.Unlock()
+		closeTransport(c.t)
+		s.releaseConnSlot()
+		s.wg.Done()
+	}()
+	in, out, err := s.protocols(c.t)
+	if err != nil {
+		s.reportError(c.t, err)
+		return
+	}
+	ctx, err := connContext(c.t)
+	if err != nil {
+		s.reportError(c.t, err)
+		return
+	}
+	if h := s.opts.EventHandler; h != nil {
+		ctx = h.CreateContext(ctx, in.p, out)
+		defer h.DeleteContext(ctx, in.p, out)
+	}
+	for {
+		// waits for next message, so idle connection holds no worker.
+		resetReadLimit(in.t)
+		if _, err = in.peek(1); err != nil {
+			if !isEOF(err) {
+				s.reportError(c.t, err)
+			}
+			return
+		}
+		if !s.setBusy(c, true) {
+			return
+		}
+		if h := s.opts.EventHandler; h != nil {
+			h.ProcessContext(ctx, c.t)
+		}
+		err = s.process(ctx, in.p, out)
+		if !s.setBusy(c, false) {
+			return
+		}
+		if err != nil {
+			if err != errTServerStopped {
+				s.reportError(c.t, err)
+			}
+			return
+		}
+	}
+}
+
+// tServerInput input protocol of connection, peek waits for next message.
+// it peeks outermost input transport, which may buffer messages already read.
+type tServerInput struct {
+	t    TTransport
+	peek func(n int) ([]byte, error)
+	p    TProtocol
+}
+
+func (s *TServer) protocols(t TTransport) (in tServerInput, out TProtocol, err error) {
+	var itrans, otrans TTransport = t, nil
+	if s.opts.InputTransportFactory != nil {
+		if itrans, err = s.opts.InputTransportFactory.GetTransport(itrans); err != nil {
+			return
+		}
+	}
+	if r, ok := itrans.(TBufferedReader); ok {
+		in.peek = r.Peek
+	} else {
+		pt := &tPeekTransport{TTransport: itrans}
+		itrans, in.peek = pt, pt.peek
+	}
+	in.t = itrans
+	otrans = itrans
+	if s.opts.OutputTransportFactory != nil {
+		if otrans, err = s.opts.OutputTransportFactory.GetTransport(t); err != nil {
+			return
+		}
+	}
+	in.p = s.opts.InputProtocolFactory.GetProtocol(itrans)
+	out = s.opts.OutputProtocolFactory.GetProtocol(otrans)
+	return
+}
+
+// setBusy marks c as busy or idle, it returns false if server is stopping.
+func (s *TServer) setBusy(c *tServerConn, busy bool) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	c.busy = busy
+	return !s.stopping
+}
+
+func (s *TServer) process(ctx context.Context, in, out TProtocol) error {
+	if s.inFlight != nil {
+		select {
+		case s.inFlight <- struct{}{}:
+			defer func() { <-s.inFlight }()
+		case <-s.quit:
+			return errTServerStopped
+		}
+	}
+	if s.jobs == nil {
+		return s.processor.Process(ctx, in, out)
+	}
+	done := make(chan error, 1)
+	select {
+	case s.jobs <- func() { done <- s.processor.Process(ctx, in, out) }:
+		return <-done
+	case <-s.quit:
+		return errTServerStopped
+	}
+}
+
+// connContext returns context with transport, peer address and credentials of t.
+// it completes handshake if t is TLS connection.
+func connContext(t TTransport) (context.Context, error) {
+	ctx := WithTTransport(context.Background(), t)
+	if r, ok := t.(interface{ RemoteAddr() net.Addr }); ok {
+		if addr := r.RemoteAddr(); addr != nil {
+			ctx = WithTPeerAddr(ctx, addr)
+		}
+	}
+	if p, ok := t.(interface{ PeerCred() (*TPeerCred, error) }); ok {
+		if cred, err := p.PeerCred(); err == nil {
+			ctx = WithTPeerCred(ctx, cred)
+		}
+	}
+	if c, ok := t.(interface {
+		Handshake() error
+		ConnectionState() tls.ConnectionState
+	}); ok {
+		if err := c.Handshake(); err != nil {
+			return nil, err
+		}
+		state := c.ConnectionState()
+		ctx = WithTTLSConnectionState(ctx, &state)
+	}
+	return ctx, nil
+}
+
+func (s *TServer) reportError(t TTransport, err error) {
+	if s.opts.ErrorHandler != nil && !s.isStopping() {
+		s.opts.ErrorHandler(t, err)
+	}
+}
+
+func closeTransport(t TTransport) {
+	if c, ok := t.(io.Closer); ok {
+		c.Close()
+	}
+}
+
+func isEOF(err error) bool {
+	var e *TTransportException
+	return errors.As(err, &e) && e.Kind() == TTransportErrorEOF
+}
@@ -0,0 +1,70 @@
+package thrift
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+)
+
+// NewHTTPHandler returns new http.Handler which processes a message of
+// each POST request with processor; replies are written to response.
+// size of request body is limited to max message size of configuration of in,
+// a larger body is replied with 413 and other errors of reading it with 400.
+// handlers can get request header with THTTPHeaderFromContext and
+// address of client with TPeerAddrFromContext.
+func NewHTTPHandler(processor TProcessor, in, out TProtocolFactory) http.Handler {
+	if in == nil {
+		in = NewTBinaryProtocolFactory(nil)
+	}
+	if out == nil {
+		out = in
+	}
+	return &tHTTPHandler{processor, in, out}
+}
+
+type tHTTPHandler struct {
+	processor TProcessor
+	in, out   TProtocolFactory
+}
+
+func (h *tHTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	max := TConfigurationOf(h.in).GetMaxMessageSize()
+	if r.ContentLength > int64(max) {
+		http.Error(w, fmt.Sprintf("request body exceeded max allowed: %d", max), http.StatusRequestEntityTooLarge)
+		return
+	}
+	b, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, int64(max)))
+	if err != nil {
+		if isHTTPBodyTooLarge(err) {
+			http.Error(w, fmt.Sprintf("request body exceeded max allowed: %d", max), http.StatusRequestEntityTooLarge)
+		} else {
+			http.Error(w, fmt.Sprintf("reading request body: %v", err), http.StatusBadRequest)
+		}
+		return
+	}
+	input, output := NewTMemoryBuffer(), NewTMemoryBuffer()
+	input.Write(b)
+	ctx := context.WithValue(r.Context(), tContextKeyHTTPHeader, r.Header)
+	if addr, err := net.ResolveTCPAddr("tcp", r.RemoteAddr); err == nil {
+		ctx = WithTPeerAddr(ctx, addr)
+	}
+	if err = h.processor.Process(ctx, h.in.GetProtocol(input), h.out.GetProtocol(output)); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-thrift")
+	w.Write(output.Bytes())
+}
+
+// isHTTPBodyTooLarge returns true if err is the limit error of http.MaxBytesReader,
+// it is matched by message as its type is not exported before go1.19.
+func isHTTPBodyTooLarge(err error) bool {
+	return err.Error() == "http: request body too large"
+}
@@ -0,0 +1,83 @@
+package thrift_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/iotest"
+
+	"github.com/b1avk/thrift/pkg/thrift"
+)
+
+func TestHTTPHandler(t *testing.T) {
+	p := newEchoProcessor(0, nil)
+	var header http.Header
+	var peer string
+	p.Use(func(ctx context.Context, call thrift.TCallInfo, args thrift.TStruct, next thrift.THandler) (thrift.TStruct, error) {
+		header = thrift.THTTPHeaderFromContext(ctx)
+		if addr := thrift.TPeerAddrFromContext(ctx); addr != nil {
+			peer = addr.String()
+		}
+		return next(ctx, args)
+	})
+	s := httptest.NewServer(thrift.NewHTTPHandler(p, thrift.NewTCompactProtocolFactory(&thrift.TConfiguration{MaxMessageSize: 64}), nil))
+	defer s.Close()
+
+	c, err := thrift.NewTHttpClient(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetHeader("X-Token", "secret")
+	client := thrift.NewTStandardClient(thrift.NewTCompactProtocol(c, nil), nil)
+	if err = echo(client, 1); err != nil {
+		t.Fatal(err)
+	}
+	if header.Get("X-Token") != "secret" || peer == "" {
+		t.Fatalf("request header and peer address must be passed to handler: %v %q", header, peer)
+	}
+
+	r, err := http.Get(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Body.Close()
+	if r.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatal("expected 405 for GET", r.StatusCode)
+	}
+
+	r, err = http.Post(s.URL, "application/x-thrift", bytes.NewReader(make([]byte, 65)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Body.Close()
+	if r.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatal("expected 413 for large body", r.StatusCode)
+	}
+}
+
+func TestHTTPHandlerBodyError(t *testing.T) {
+	h := thrift.NewHTTPHandler(newEchoProcessor(0, nil), thrift.NewTCompactProtocolFactory(&thrift.TConfiguration{MaxMessageSize: 64}), nil)
+	for _, c := range []struct {
+		name string
+		body io.Reader
+		code int
+	}{
+		{"TooLarge", bytes.NewReader(make([]byte, 65)), http.StatusRequestEntityTooLarge},
+		{"Broken", io.MultiReader(bytes.NewReader(make([]byte, 8)), iotest.ErrReader(errors.New("connection reset"))), http.StatusBadRequest},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", c.body)
+			// unknown length, so the body is limited while it is read.
+			r.ContentLength = -1
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != c.code {
+				t.Fatalf("expected %d, got %d: %s", c.code, w.Code, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,295 @@
+package thrift_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/b1avk/thrift/pkg/thrift"
+)
+
+func newEchoProcessor(delay time.Duration, started chan<- struct{}) *thrift.TStandardProcessor {
+	p := thrift.NewTStandardProcessor()
+	p.Handle("echo", func() thrift.TStruct {
+		return new(thrift.TApplicationException)
+	}, func(ctx context.Context, args thrift.TStruct) (thrift.TStruct, error) {
+		if started != nil {
+			started <- struct{}{}
+		}
+		time.Sleep(delay)
+		return args, nil
+	})
+	return p
+}
+
+func startServer(t *testing.T, p thrift.TProcessor, opts thrift.TServerOptions) (*thrift.TServer, string, chan error) {
+	l := thrift.NewTServerSocket("tcp", "127.0.0.1:0", thrift.TSocketOptions{})
+	if err := l.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	s := thrift.NewTServer(l, p, opts)
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Serve()
+	}()
+	return s, l.Addr().String(), done
+}
+
+func newEchoClient(t *testing.T, addr string) *thrift.TStandardClient {
+	s := thrift.NewTSocket("tcp", addr, thrift.TSocketOptions{ConnectTimeout: time.Second})
+	if err := s.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	b := thrift.NewTBufferedTransport(s, nil)
+	return thrift.NewTStandardClient(thrift.NewTBinaryProtocol(b, nil), nil)
+}
+
+func echo(c thrift.TClient, i int) error {
+	args := &thrift.TApplicationException{Message: "Hello", Type: thrift.TApplicationError(i)}
+	r := new(thrift.TApplicationException)
+	if err := c.Call(context.Background(), "echo", args, r); err != nil {
+		return err
+	}
+	if *r != *args {
+		return thrift.NewTProtocolException(thrift.TProtocolErrorInvalidData, "echo mismatch")
+	}
+	return nil
+}
+
+func TestTServer(t *testing.T) {
+	s, addr, done := startServer(t, newEchoProcessor(0, nil), thrift.TServerOptions{
+		Workers:     2,
+		MaxInFlight: 2,
+	})
+	c := thrift.NewTPoolClient(thrift.NewTBufferedTransportFactory(thrift.NewTSocketFactory("tcp", addr, thrift.TSocketOptions{}), nil), nil, thrift.NewTBinaryProtocolFactory(nil), nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if err := echo(c, i); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTServerBufferedInput(t *testing.T) {
+	s, addr, done := startServer(t, newEchoProcessor(0, nil), thrift.TServerOptions{
+		InputTransportFactory: thrift.NewTBufferedTransportFactory(nil, nil),
+	})
+	sock := thrift.NewTSocket("tcp", addr, thrift.TSocketOptions{SocketTimeout: time.Second})
+	if err := sock.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	c := thrift.NewTStandardClient(thrift.NewTBinaryProtocol(thrift.NewTBufferedTransport(sock, nil), nil), nil)
+	// requests are shorter than buffer of input transport.
+	for i := 0; i < 3; i++ {
+		if err := echo(c, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := s.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTServerPipelined(t *testing.T) {
+	for name, f := range map[string]thrift.TTransportFactory{
+		"Buffered": thrift.NewTBufferedTransportFactory(nil, nil),
+		"Zlib":     thrift.NewTZlibTransportFactory(nil, 1),
+	} {
+		f := f
+		t.Run(name, func(t *testing.T) {
+			s, addr, _ := startServer(t, newEchoProcessor(0, nil), thrift.TServerOptions{
+				InputTransportFactory: f,
+			})
+			defer s.Stop(context.Background())
+			sock := thrift.NewTSocket("tcp", addr, thrift.TSocketOptions{SocketTimeout: time.Second})
+			if err := sock.Open(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			tr, err := f.GetTransport(sock)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// both requests are sent in a single write.
+			b := thrift.NewTMemoryBuffer()
+			w, err := f.GetTransport(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := thrift.NewTBinaryProtocol(w, nil)
+			for i := int32(1); i <= 2; i++ {
+				p.WriteMessageBegin(thrift.TMessageHeader{Name: "echo", Type: thrift.CALL, Identity: i})
+				(&thrift.TApplicationException{Message: "Hello"}).Write(p)
+				p.WriteMessageEnd()
+			}
+			if err = p.Flush(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if _, err = sock.Write(b.Bytes()); err != nil {
+				t.Fatal(err)
+			}
+			if err = sock.Flush(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			p = thrift.NewTBinaryProtocol(tr, nil)
+			for i := int32(1); i <= 2; i++ {
+				h, err := p.ReadMessageBegin()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if h.Identity != i || h.Type != thrift.REPLY {
+					t.Fatalf("unexpected reply %+v", h)
+				}
+				if err = p.Skip(thrift.STRUCT); err != nil {
+					t.Fatal(err)
+				}
+				if err = p.ReadMessageEnd(); err != nil {
+					t.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestTServerMaxConnections(t *testing.T) {
+	s, addr, _ := startServer(t, newEchoProcessor(0, nil), thrift.TServerOptions{MaxConnections: 1})
+	defer s.Stop(context.Background())
+	a := newEchoClient(t, addr)
+	if err := echo(a, 1); err != nil {
+		t.Fatal(err)
+	}
+	b := newEchoClient(t, addr)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- echo(b, 2)
+	}()
+	select {
+	case <-errs:
+		t.Fatal("second connection must wait for first connection to be closed")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestTServerStop(t *testing.T) {
+	started := make(chan struct{}, 1)
+	var errors int32
+	s, addr, done := startServer(t, newEchoProcessor(100*time.Millisecond, started), thrift.TServerOptions{
+		ErrorHandler: func(thrift.TTransport, error) {
+			atomic.AddInt32(&errors, 1)
+		},
+	})
+	idle := newEchoClient(t, addr)
+	if err := echo(idle, 0); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	busy := newEchoClient(t, addr)
+	result := make(chan error, 1)
+	go func() {
+		result <- echo(busy, 1)
+	}()
+	<-started
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-result; err != nil {
+		t.Fatal("in-flight call must finish", err)
+	}
+	if err := echo(idle, 2); err == nil {
+		t.Fatal("idle connection must be closed")
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&errors) != 0 {
+		t.Fatal("closing connections on stop must not be reported")
+	}
+}
+
+func TestTServerStopDeadline(t *testing.T) {
+	started := make(chan struct{}, 1)
+	s, addr, _ := startServer(t, newEchoProcessor(time.Second, started), thrift.TServerOptions{})
+	c := newEchoClient(t, addr)
+	go echo(c, 0)
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.Stop(ctx); err != context.DeadlineExceeded {
+		t.Fatal("expected deadline exceeded", err)
+	}
+}
+
+type testEventHandler struct {
+	preServe, created, deleted, processed int32
+}
+
+type testConnKey struct{}
+
+func (h *testEventHandler) PreServe() {
+	atomic.AddInt32(&h.preServe, 1)
+}
+
+func (h *testEventHandler) CreateContext(ctx context.Context, in, out thrift.TProtocol) context.Context {
+	return context.WithValue(ctx, testConnKey{}, atomic.AddInt32(&h.created, 1))
+}
+
+func (h *testEventHandler) DeleteContext(ctx context.Context, in, out thrift.TProtocol) {
+	atomic.AddInt32(&h.deleted, 1)
+}
+
+func (h *testEventHandler) ProcessContext(ctx context.Context, t thrift.TTransport) {
+	atomic.AddInt32(&h.processed, 1)
+}
+
+func TestTServerEventHandler(t *testing.T) {
+	h := new(testEventHandler)
+	p := newEchoProcessor(0, nil)
+	calls := make(chan context.Context, 2)
+	p.Use(func(ctx context.Context, call thrift.TCallInfo, args thrift.TStruct, next thrift.THandler) (thrift.TStruct, error) {
+		calls <- ctx
+		return next(ctx, args)
+	})
+	s, addr, done := startServer(t, p, thrift.TServerOptions{EventHandler: h})
+	c := newEchoClient(t, addr)
+	for i := 0; i < 2; i++ {
+		if err := echo(c, i); err != nil {
+			t.Fatal(err)
+		}
+		ctx := <-calls
+		if ctx.Value(testConnKey{}) != int32(1) {
+			t.Fatal("context of CreateContext must be passed to handler")
+		}
+		if thrift.TPeerAddrFromContext(ctx) == nil || thrift.TTransportFromContext(ctx) == nil {
+			t.Fatal("peer address and transport must be in context")
+		}
+	}
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if h.preServe != 1 || h.created != 1 || h.deleted != 1 || h.processed != 2 {
+		t.Fatalf("unexpected calls of event handler: %+v", h)
+	}
+}
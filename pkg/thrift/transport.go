@@ -18,6 +18,22 @@ type TTransport interface {
 	TFlusher
 }
 
+// TReadLimiter is implemented by transports which limit data read
+// by each message, such as TCompressedTransport. protocols call
+// ResetReadLimit at ReadMessageBegin, transports which wrap
+// other transports pass it to them.
+type TReadLimiter interface {
+	// ResetReadLimit starts limit of next message.
+	ResetReadLimit()
+}
+
+// resetReadLimit calls ResetReadLimit of t if t is TReadLimiter.
+func resetReadLimit(t TTransport) {
+	if l, ok := t.(TReadLimiter); ok {
+		l.ResetReadLimit()
+	}
+}
+
 // TFlusher interface that wraps Flush method which
 // allows to flush underlying buffer.
 // it implemented by TTransport and TProtocol.
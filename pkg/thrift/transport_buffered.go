@@ -0,0 +1,169 @@
+package thrift
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+)
+
+// TBufferedReader is interface of transports which expose their read buffer,
+// protocols use it to decode without reading byte by byte and extra copies.
+type TBufferedReader interface {
+	// Buffered returns number of bytes that can be read from buffer.
+	Buffered() int
+
+	// Peek returns next n bytes without advancing the reader,
+	// the bytes stop being valid at the next read.
+	Peek(n int) ([]byte, error)
+
+	// Next returns next n bytes and advances the reader,
+	// the bytes stop being valid at the next read.
+	// it returns error and advances nothing if n is larger than buffer.
+	Next(n int) ([]byte, error)
+}
+
+// TBufferedTransport a TTransport with read and write buffers
+// of TConfiguration.MaxBufferSize.
+type TBufferedTransport struct {
+	t TTransport
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+// NewTBufferedTransport returns new TBufferedTransport of t.
+func NewTBufferedTransport(t TTransport, cfg *TConfiguration) *TBufferedTransport {
+	cfg = cfg.NonNil()
+	cfg.Propagate(t)
+	size := cfg.GetMaxBufferSize()
+	return &TBufferedTransport{t, bufio.NewReaderSize(t, size), bufio.NewWriterSize(t, size)}
+}
+
+// SetTConfiguration propagates cfg to underlying transport,
+// buffers are resized if they are empty.
+func (t *TBufferedTransport) SetTConfiguration(cfg *TConfiguration) {
+	cfg = cfg.NonNil()
+	cfg.Propagate(t.t)
+	size := cfg.GetMaxBufferSize()
+	if t.r.Buffered() == 0 && t.r.Size() != size {
+		t.r = bufio.NewReaderSize(t.t, size)
+	}
+	if t.w.Buffered() == 0 && t.w.Size() != size {
+		t.w = bufio.NewWriterSize(t.t, size)
+	}
+}
+
+// Read reads data to v.
+func (t *TBufferedTransport) Read(v []byte) (int, error) {
+	n, err := t.r.Read(v)
+	return n, NewTTransportExceptionFromError(err)
+}
+
+// ReadByte reads next one byte.
+func (t *TBufferedTransport) ReadByte() (byte, error) {
+	v, err := t.r.ReadByte()
+	return v, NewTTransportExceptionFromError(err)
+}
+
+// Buffered returns number of bytes that can be read from buffer.
+func (t *TBufferedTransport) Buffered() int {
+	return t.r.Buffered()
+}
+
+// Peek returns next n bytes without advancing the reader.
+func (t *TBufferedTransport) Peek(n int) ([]byte, error) {
+	v, err := t.r.Peek(n)
+	return v, NewTTransportExceptionFromError(err)
+}
+
+// Next returns next n bytes and advances the reader.
+func (t *TBufferedTransport) Next(n int) ([]byte, error) {
+	v, err := t.r.Peek(n)
+	if err != nil {
+		return nil, NewTTransportExceptionFromError(err)
+	}
+	t.r.Discard(n)
+	return v, nil
+}
+
+// ResetReadLimit resets read limit of underlying transport.
+func (t *TBufferedTransport) ResetReadLimit() {
+	resetReadLimit(t.t)
+}
+
+// Write writes v to write buffer.
+func (t *TBufferedTransport) Write(v []byte) (int, error) {
+	n, err := t.w.Write(v)
+	return n, NewTTransportExceptionFromError(err)
+}
+
+// WriteByte writes v to write buffer.
+func (t *TBufferedTransport) WriteByte(v byte) error {
+	return NewTTransportExceptionFromError(t.w.WriteByte(v))
+}
+
+// Flush writes write buffer to underlying transport and flushes it.
+func (t *TBufferedTransport) Flush(ctx context.Context) error {
+	if err := t.w.Flush(); err != nil {
+		return NewTTransportExceptionFromError(err)
+	}
+	return NewTTransportExceptionFromError(t.t.Flush(ctx))
+}
+
+// Close closes underlying transport if it is io.Closer.
+func (t *TBufferedTransport) Close() error {
+	if c, ok := t.t.(io.Closer); ok {
+		return NewTTransportExceptionFromError(c.Close())
+	}
+	return nil
+}
+
+// NewTBufferedTransportFactory returns new TTransportFactory of NewTBufferedTransport,
+// which wraps transport of f, or given transport if f is nil.
+func NewTBufferedTransportFactory(f TTransportFactory, cfg *TConfiguration) TTransportFactory {
+	return &tBufferedTransportFactory{f, cfg}
+}
+
+type tBufferedTransportFactory struct {
+	f   TTransportFactory
+	cfg *TConfiguration
+}
+
+func (f *tBufferedTransportFactory) GetTransport(t TTransport) (TTransport, error) {
+	if f.f != nil {
+		var err error
+		if t, err = f.f.GetTransport(t); err != nil {
+			return nil, err
+		}
+	}
+	return NewTBufferedTransport(t, f.cfg), nil
+}
+
+// readBytes reads n bytes from t, the result is buffer of t if buffered is true.
+func readBytes(t TExtraTransport, n int) (v []byte, buffered bool, err error) {
+	if r, ok := t.(TBufferedReader); ok {
+		if v, err = r.Next(n); err == nil {
+			return v, true, nil
+		}
+	}
+	v = make([]byte, n)
+	_, err = io.ReadFull(t, v)
+	return v, false, err
+}
+
+// readUvarint reads varint from t, from buffer of t if it holds whole varint.
+func readUvarint(t TExtraTransport) (uint64, error) {
+	if r, ok := t.(TBufferedReader); ok {
+		if n := r.Buffered(); n > 0 {
+			if n > binary.MaxVarintLen64 {
+				n = binary.MaxVarintLen64
+			}
+			b, _ := r.Peek(n)
+			if v, m := binary.Uvarint(b); m > 0 {
+				r.Next(m)
+				return v, nil
+			}
+		}
+	}
+	return binary.ReadUvarint(t)
+}
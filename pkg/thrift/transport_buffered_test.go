@@ -0,0 +1,60 @@
+package thrift_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/b1avk/thrift/pkg/thrift"
+)
+
+// countingTransport counts reads of underlying TMemoryBuffer.
+type countingTransport struct {
+	*thrift.TMemoryBuffer
+	reads int
+}
+
+func (t *countingTransport) Read(v []byte) (int, error) {
+	t.reads++
+	return t.TMemoryBuffer.Read(v)
+}
+
+func TestTBufferedTransport(t *testing.T) {
+	ctx := context.Background()
+	long := strings.Repeat("x", 100)
+	for name, f := range map[string]thrift.TProtocolFactory{
+		"Binary":  thrift.NewTBinaryProtocolFactory(nil),
+		"Compact": thrift.NewTCompactProtocolFactory(nil),
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := &countingTransport{TMemoryBuffer: thrift.NewTMemoryBuffer()}
+			b := thrift.NewTBufferedTransport(c, &thrift.TConfiguration{MaxBufferSize: 64})
+			p := f.GetProtocol(b)
+			for i := 0; i < 100; i++ {
+				v := &thrift.TApplicationException{Message: "Hello", Type: thrift.TApplicationError(i)}
+				if i%10 == 0 {
+					v.Message = long
+				}
+				if err := v.Write(p); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := p.Flush(ctx); err != nil {
+				t.Fatal(err)
+			}
+			size := c.Len()
+			for i := 0; i < 100; i++ {
+				v := new(thrift.TApplicationException)
+				if err := v.Read(p); err != nil {
+					t.Fatal(err)
+				}
+				if int(v.Type) != i || (v.Message != "Hello" && v.Message != long) {
+					t.Fatalf("unexpected value: %+v", v)
+				}
+			}
+			if c.reads > size/64+20 {
+				t.Fatalf("too many reads of underlying transport: %d for %d bytes", c.reads, size)
+			}
+		})
+	}
+}
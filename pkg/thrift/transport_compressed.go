@@ -0,0 +1,159 @@
+package thrift
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"io"
+)
+
+// TCompressedTransport a TTransport which compresses data written to and
+// decompresses data read from underlying TTransport as a single stream.
+// Flush flushes compressed data, so the peer can read everything written before.
+type TCompressedTransport struct {
+	t         TTransport
+	cfg       *TConfiguration
+	w         compressedWriter
+	r         io.ReadCloser
+	newReader func(r io.Reader) (io.ReadCloser, error)
+	read      int
+}
+
+type compressedWriter interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// NewTZlibTransport returns new TCompressedTransport with zlib format,
+// which is compatible with TZlibTransport of Apache Thrift.
+func NewTZlibTransport(t TTransport, level int) (*TCompressedTransport, error) {
+	w, err := zlib.NewWriterLevel(t, level)
+	if err != nil {
+		return nil, err
+	}
+	return newTCompressedTransport(t, w, zlib.NewReader), nil
+}
+
+// NewTGzipTransport returns new TCompressedTransport with gzip format.
+func NewTGzipTransport(t TTransport, level int) (*TCompressedTransport, error) {
+	w, err := gzip.NewWriterLevel(t, level)
+	if err != nil {
+		return nil, err
+	}
+	return newTCompressedTransport(t, w, func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	}), nil
+}
+
+// NewTFlateTransport returns new TCompressedTransport with raw deflate format.
+func NewTFlateTransport(t TTransport, level int) (*TCompressedTransport, error) {
+	w, err := flate.NewWriter(t, level)
+	if err != nil {
+		return nil, err
+	}
+	return newTCompressedTransport(t, w, func(r io.Reader) (io.ReadCloser, error) {
+		return flate.NewReader(r), nil
+	}), nil
+}
+
+func newTCompressedTransport(t TTransport, w compressedWriter, newReader func(io.Reader) (io.ReadCloser, error)) *TCompressedTransport {
+	return &TCompressedTransport{t: t, cfg: DefaultTConfiguration, w: w, newReader: newReader}
+}
+
+// SetTConfiguration sets configuration of transport and underlying transport.
+func (c *TCompressedTransport) SetTConfiguration(cfg *TConfiguration) {
+	c.cfg = cfg.NonNil()
+	c.cfg.Propagate(c.t)
+}
+
+// Write writes compressed v to underlying transport.
+func (c *TCompressedTransport) Write(v []byte) (int, error) {
+	n, err := c.w.Write(v)
+	return n, NewTTransportExceptionFromError(err)
+}
+
+// Read reads decompressed data from underlying transport.
+// data read by each message must not exceed max decompressed size
+// of configuration, see ResetReadLimit.
+func (c *TCompressedTransport) Read(v []byte) (n int, err error) {
+	if c.r == nil {
+		if c.r, err = c.newReader(c.t); err != nil {
+			return 0, NewTTransportExceptionFromError(err)
+		}
+	}
+	max := c.cfg.GetMaxDecompressedSize()
+	if left := max - c.read + 1; len(v) > left {
+		v = v[:left]
+	}
+	n, err = c.r.Read(v)
+	if c.read += n; c.read > max {
+		return 0, NewTTransportException(TTransportErrorSizeLimit, fmt.Sprintf("decompressed size exceeded max allowed: %d", max))
+	}
+	return n, NewTTransportExceptionFromError(err)
+}
+
+// ResetReadLimit starts limit of next message, protocols call it at ReadMessageBegin.
+// data read without messages is limited in total until it is called.
+func (c *TCompressedTransport) ResetReadLimit() {
+	c.read = 0
+}
+
+// Flush flushes compressed data to underlying transport and flushes it.
+func (c *TCompressedTransport) Flush(ctx context.Context) error {
+	if err := c.w.Flush(); err != nil {
+		return NewTTransportExceptionFromError(err)
+	}
+	return NewTTransportExceptionFromError(c.t.Flush(ctx))
+}
+
+// Close finishes compressed stream and closes underlying transport if it is io.Closer.
+func (c *TCompressedTransport) Close() (err error) {
+	if err = c.w.Close(); err == nil {
+		err = c.t.Flush(context.Background())
+	}
+	if c.r != nil {
+		c.r.Close()
+	}
+	if closer, ok := c.t.(io.Closer); ok {
+		if e := closer.Close(); err == nil {
+			err = e
+		}
+	}
+	return NewTTransportExceptionFromError(err)
+}
+
+// NewTZlibTransportFactory returns new TTransportFactory of NewTZlibTransport,
+// which wraps transport of f, or given transport if f is nil.
+func NewTZlibTransportFactory(f TTransportFactory, level int) TTransportFactory {
+	return &tCompressedTransportFactory{f, level, NewTZlibTransport}
+}
+
+// NewTGzipTransportFactory returns new TTransportFactory of NewTGzipTransport,
+// which wraps transport of f, or given transport if f is nil.
+func NewTGzipTransportFactory(f TTransportFactory, level int) TTransportFactory {
+	return &tCompressedTransportFactory{f, level, NewTGzipTransport}
+}
+
+// NewTFlateTransportFactory returns new TTransportFactory of NewTFlateTransport,
+// which wraps transport of f, or given transport if f is nil.
+func NewTFlateTransportFactory(f TTransportFactory, level int) TTransportFactory {
+	return &tCompressedTransportFactory{f, level, NewTFlateTransport}
+}
+
+type tCompressedTransportFactory struct {
+	f     TTransportFactory
+	level int
+	new   func(TTransport, int) (*TCompressedTransport, error)
+}
+
+func (f *tCompressedTransportFactory) GetTransport(t TTransport) (TTransport, error) {
+	if f.f != nil {
+		var err error
+		if t, err = f.f.GetTransport(t); err != nil {
+			return nil, err
+		}
+	}
+	return f.new(t, f.level)
+}
@@ -0,0 +1,153 @@
+package thrift_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+
+	"github.com/b1avk/thrift/pkg/thrift"
+)
+
+func TestTCompressedTransport(t *testing.T) {
+	ctx := context.Background()
+	for name, f := range map[string]thrift.TTransportFactory{
+		"Zlib":  thrift.NewTZlibTransportFactory(nil, flate.BestSpeed),
+		"Gzip":  thrift.NewTGzipTransportFactory(nil, flate.DefaultCompression),
+		"Flate": thrift.NewTFlateTransportFactory(nil, flate.BestCompression),
+	} {
+		t.Run(name, func(t *testing.T) {
+			b := thrift.NewTMemoryBuffer()
+			tr, err := f.GetTransport(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := thrift.NewTBinaryProtocol(tr, nil)
+			for i := 0; i < 3; i++ {
+				v := &thrift.TApplicationException{Message: "Hello", Type: thrift.TApplicationError(i)}
+				if err = v.Write(p); err != nil {
+					t.Fatal(err)
+				}
+				if err = p.Flush(ctx); err != nil {
+					t.Fatal(err)
+				}
+				r := new(thrift.TApplicationException)
+				if err = r.Read(p); err != nil {
+					t.Fatal(err)
+				}
+				if *r != *v {
+					t.Fatal("value obtained for compressed transport mismatch")
+				}
+			}
+		})
+	}
+}
+
+func TestTZlibTransportCompatible(t *testing.T) {
+	b := thrift.NewTMemoryBuffer()
+	tr, err := thrift.NewTZlibTransport(b, zlib.DefaultCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.Write([]byte("Hello"))
+	if err = tr.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := zlib.NewReader(bytes.NewReader(b.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := ioutil.ReadAll(r); err != nil || string(v) != "Hello" {
+		t.Fatal("zlib stream must be readable by compress/zlib", err)
+	}
+}
+
+func TestTCompressedTransportLimit(t *testing.T) {
+	b := thrift.NewTMemoryBuffer()
+	w := zlib.NewWriter(b)
+	w.Write(make([]byte, 1<<20))
+	w.Close()
+	tr, err := thrift.NewTZlibTransport(b, zlib.DefaultCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.SetTConfiguration(&thrift.TConfiguration{MaxDecompressedSize: 1024})
+	_, err = ioutil.ReadAll(tr)
+	if e, ok := err.(*thrift.TTransportException); !ok || e.Kind() != thrift.TTransportErrorSizeLimit {
+		t.Fatal("expected size limit error", err)
+	}
+}
+
+func TestTCompressedTransportLimitPerMessage(t *testing.T) {
+	b := thrift.NewTMemoryBuffer()
+	w, err := thrift.NewTZlibTransport(b, zlib.DefaultCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := thrift.NewTZlibTransport(b, zlib.DefaultCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &thrift.TConfiguration{MaxDecompressedSize: 1024}
+	wp, rp := thrift.NewTBinaryProtocol(w, cfg), thrift.NewTBinaryProtocol(r, cfg)
+	rnd := rand.New(rand.NewSource(1))
+	// messages of a read-only peer exceed limit in total, but not each.
+	for i := 0; i < 8; i++ {
+		v := make([]byte, 800)
+		rnd.Read(v)
+		wp.WriteMessageBegin(thrift.TMessageHeader{Name: "log", Type: thrift.ONEWAY, Identity: int32(i)})
+		wp.WriteBinary(v)
+		wp.WriteMessageEnd()
+		if err = wp.Flush(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = rp.ReadMessageBegin(); err != nil {
+			t.Fatalf("message %d: %v", i, err)
+		}
+		got, err := rp.ReadBinary()
+		if err != nil {
+			t.Fatalf("message %d: %v", i, err)
+		}
+		if !bytes.Equal(got, v) {
+			t.Fatalf("message %d: unexpected data", i)
+		}
+	}
+}
+
+func TestTCompressedTransportLimitDefault(t *testing.T) {
+	b := thrift.NewTMemoryBuffer()
+	w := zlib.NewWriter(b)
+	w.Write(make([]byte, thrift.DefaultMaxDecompressedSize+1))
+	w.Close()
+	tr, err := thrift.NewTZlibTransport(b, zlib.DefaultCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = io.Copy(ioutil.Discard, tr)
+	if e, ok := err.(*thrift.TTransportException); !ok || e.Kind() != thrift.TTransportErrorSizeLimit {
+		t.Fatal("expected size limit error", err)
+	}
+}
+
+func TestTCompressedTransportLimitBeforeRead(t *testing.T) {
+	b := thrift.NewTMemoryBuffer()
+	w := zlib.NewWriter(b)
+	w.Write(make([]byte, 1<<20))
+	w.Flush()
+	tr, err := thrift.NewTZlibTransport(b, zlib.DefaultCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.SetTConfiguration(&thrift.TConfiguration{MaxDecompressedSize: 1024})
+	v, err := ioutil.ReadAll(tr)
+	if e, ok := err.(*thrift.TTransportException); !ok || e.Kind() != thrift.TTransportErrorSizeLimit {
+		t.Fatal("expected size limit error", err)
+	}
+	if len(v) > 1024 {
+		t.Fatal("expected no more than limit to be read", len(v))
+	}
+}
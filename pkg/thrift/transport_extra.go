@@ -36,6 +36,10 @@ func (t *tExtraTransport) ReadByte() (byte, error) {
 	return t.cache[0], NewTTransportExceptionFromError(err)
 }
 
+func (t *tExtraTransport) ResetReadLimit() {
+	resetReadLimit(t.TTransport)
+}
+
 func (t *tExtraTransport) SetTConfiguration(cfg *TConfiguration) {
 	cfg.Propagate(t.TTransport)
 }
@@ -110,7 +110,7 @@ func (c *THttpClient) Read(v []byte) (int, error) {
 
 // ReadByte reads next one byte from response body.
 func (c *THttpClient) ReadByte() (byte, error) {
-	_, err := c.Read(c.cache[:])
+	_, err := io.ReadFull(c, c.cache[:])
 	return c.cache[0], err
 }
 
@@ -19,3 +19,8 @@ func NewTMemoryBuffer() *TMemoryBuffer {
 func (*TMemoryBuffer) Flush(ctx context.Context) error {
 	return nil
 }
+
+// RemainingBytes returns count of unread bytes.
+func (b *TMemoryBuffer) RemainingBytes() int {
+	return b.Len()
+}
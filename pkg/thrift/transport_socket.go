@@ -0,0 +1,231 @@
+package thrift
+
+import (
+	"context"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TSocketOptions options of TSocket and TServerSocket.
+type TSocketOptions struct {
+	// ConnectTimeout timeout of connecting, no timeout if zero.
+	ConnectTimeout time.Duration
+
+	// SocketTimeout timeout of each read and write, no timeout if zero.
+	SocketTimeout time.Duration
+
+	// FileMode permission of unix socket file created by TServerSocket,
+	// default permission if zero. the file is created with no more than it.
+	// Listen fails if the file exists, a stale file left by a crashed server must be removed.
+	FileMode os.FileMode
+}
+
+// parseTSocketAddress returns unix network and path if address is of unix:// scheme.
+// path of linux abstract socket starts with @.
+func parseTSocketAddress(network, address string) (string, string) {
+	if strings.HasPrefix(address, "unix://") {
+		return "unix", strings.TrimPrefix(address, "unix://")
+	}
+	return network, address
+}
+
+// TSocket a TTransport of net.Conn, writes are not buffered,
+// see TBufferedTransport.
+type TSocket struct {
+	network, address string
+	opts             TSocketOptions
+	conn             net.Conn
+	closed           int32
+}
+
+// NewTSocket returns new TSocket which connects to address on Open,
+// address of unix:// scheme is unix socket regardless of network.
+func NewTSocket(network, address string, opts TSocketOptions) *TSocket {
+	network, address = parseTSocketAddress(network, address)
+	return &TSocket{network: network, address: address, opts: opts}
+}
+
+// NewTSocketFromConn returns new TSocket of opened conn.
+func NewTSocketFromConn(conn net.Conn, opts TSocketOptions) *TSocket {
+	return &TSocket{network: conn.RemoteAddr().Network(), address: conn.RemoteAddr().String(), opts: opts, conn: conn}
+}
+
+// Open connects to address of s.
+func (s *TSocket) Open(ctx context.Context) error {
+	if s.IsOpen() {
+		return NewTTransportException(TTransportErrorAlreadyOpen, "socket already open")
+	}
+	d := net.Dialer{Timeout: s.opts.ConnectTimeout}
+	conn, err := d.DialContext(ctx, s.network, s.address)
+	if err != nil {
+		return NewTTransportExceptionFromError(err)
+	}
+	s.conn = conn
+	atomic.StoreInt32(&s.closed, 0)
+	return nil
+}
+
+// IsOpen returns true if s is connected and not closed.
+func (s *TSocket) IsOpen() bool {
+	return s.conn != nil && atomic.LoadInt32(&s.closed) == 0
+}
+
+// Conn returns underlying net.Conn, it is nil if s is not open.
+func (s *TSocket) Conn() net.Conn {
+	return s.conn
+}
+
+// RemoteAddr returns address of peer.
+func (s *TSocket) RemoteAddr() net.Addr {
+	if s.conn != nil {
+		return s.conn.RemoteAddr()
+	}
+	return nil
+}
+
+// Read reads data from connection.
+func (s *TSocket) Read(v []byte) (int, error) {
+	if s.conn == nil {
+		return 0, NewTTransportException(TTransportErrorNotOpen, "socket not open")
+	}
+	if s.opts.SocketTimeout > 0 {
+		s.conn.SetReadDeadline(time.Now().Add(s.opts.SocketTimeout))
+	}
+	n, err := s.conn.Read(v)
+	return n, NewTTransportExceptionFromError(err)
+}
+
+// Write writes data to connection.
+func (s *TSocket) Write(v []byte) (int, error) {
+	if s.conn == nil {
+		return 0, NewTTransportException(TTransportErrorNotOpen, "socket not open")
+	}
+	if s.opts.SocketTimeout > 0 {
+		s.conn.SetWriteDeadline(time.Now().Add(s.opts.SocketTimeout))
+	}
+	n, err := s.conn.Write(v)
+	return n, NewTTransportExceptionFromError(err)
+}
+
+// Flush flushing is no-op; always returns nil.
+func (s *TSocket) Flush(ctx context.Context) error {
+	return nil
+}
+
+// Close closes connection, it is safe to call concurrently with Read and Write.
+func (s *TSocket) Close() error {
+	if s.conn == nil || !atomic.CompareAndSwapInt32(&s.closed, 0, 1) {
+		return nil
+	}
+	return NewTTransportExceptionFromError(s.conn.Close())
+}
+
+// NewTSocketFactory returns new TTransportFactory of opened TSocket.
+func NewTSocketFactory(network, address string, opts TSocketOptions) TTransportFactory {
+	return &tSocketFactory{network, address, opts}
+}
+
+type tSocketFactory struct {
+	network, address string
+	opts             TSocketOptions
+}
+
+func (f *tSocketFactory) GetTransport(TTransport) (TTransport, error) {
+	s := NewTSocket(f.network, f.address, f.opts)
+	if err := s.Open(context.Background()); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// TServerTransport a listener of connections.
+type TServerTransport interface {
+	// Listen starts listening, it is no-op if already listening.
+	Listen() error
+
+	// Accept waits for and returns next connection.
+	Accept() (TTransport, error)
+
+	// Close stops listening, blocked Accept returns error.
+	Close() error
+}
+
+// TServerSocket a TServerTransport of net.Listener which accepts TSocket.
+type TServerSocket struct {
+	network, address string
+	opts             TSocketOptions
+	mutex            sync.Mutex
+	listener         net.Listener
+}
+
+// NewTServerSocket returns new TServerSocket which listens on address,
+// address of unix:// scheme is unix socket regardless of network.
+func NewTServerSocket(network, address string, opts TSocketOptions) *TServerSocket {
+	network, address = parseTSocketAddress(network, address)
+	return &TServerSocket{network: network, address: address, opts: opts}
+}
+
+// NewTServerSocketFromListener returns new TServerSocket of l.
+func NewTServerSocketFromListener(l net.Listener, opts TSocketOptions) *TServerSocket {
+	return &TServerSocket{network: l.Addr().Network(), address: l.Addr().String(), opts: opts, listener: l}
+}
+
+// Listen starts listening on address, it fails if unix socket file at address exists.
+func (s *TServerSocket) Listen() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.listener != nil {
+		return nil
+	}
+	var l net.Listener
+	var err error
+	if strings.HasPrefix(s.network, "unix") && s.opts.FileMode != 0 && !strings.HasPrefix(s.address, "@") {
+		l, err = listenUnix(s.network, s.address, s.opts.FileMode)
+	} else {
+		l, err = net.Listen(s.network, s.address)
+	}
+	if err != nil {
+		return NewTTransportExceptionFromError(err)
+	}
+	s.listener = l
+	return nil
+}
+
+// Addr returns listening address, it is nil if s is not listening.
+func (s *TServerSocket) Addr() net.Addr {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.listener != nil {
+		return s.listener.Addr()
+	}
+	return nil
+}
+
+// Accept waits for and returns next TSocket.
+func (s *TServerSocket) Accept() (TTransport, error) {
+	s.mutex.Lock()
+	l := s.listener
+	s.mutex.Unlock()
+	if l == nil {
+		return nil, NewTTransportException(TTransportErrorNotOpen, "server socket not listening")
+	}
+	conn, err := l.Accept()
+	if err != nil {
+		return nil, NewTTransportExceptionFromError(err)
+	}
+	return NewTSocketFromConn(conn, s.opts), nil
+}
+
+// Close stops listening.
+func (s *TServerSocket) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return NewTTransportExceptionFromError(s.listener.Close())
+}
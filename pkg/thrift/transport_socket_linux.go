@@ -0,0 +1,31 @@
+//go:build linux
+// +build linux
+
+package thrift
+
+import (
+	"net"
+	"syscall"
+)
+
+// PeerCred returns credentials of peer of unix socket, by SO_PEERCRED.
+func (s *TSocket) PeerCred() (*TPeerCred, error) {
+	conn, ok := s.conn.(*net.UnixConn)
+	if !ok {
+		return nil, NewTTransportException(TTransportErrorUnknown, "peer credentials require unix socket")
+	}
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return nil, NewTTransportExceptionFromError(err)
+	}
+	var cred *syscall.Ucred
+	if e := raw.Control(func(fd uintptr) {
+		cred, err = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); e != nil {
+		err = e
+	}
+	if err != nil {
+		return nil, NewTTransportExceptionFromError(err)
+	}
+	return &TPeerCred{PID: cred.Pid, UID: cred.Uid, GID: cred.Gid}, nil
+}
@@ -0,0 +1,100 @@
+//go:build linux
+// +build linux
+
+package thrift_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/b1avk/thrift/pkg/thrift"
+)
+
+func testUnixServer(t *testing.T, address string, opts thrift.TSocketOptions) {
+	p := newEchoProcessor(0, nil)
+	creds := make(chan *thrift.TPeerCred, 1)
+	p.Use(func(ctx context.Context, call thrift.TCallInfo, args thrift.TStruct, next thrift.THandler) (thrift.TStruct, error) {
+		creds <- thrift.TPeerCredFromContext(ctx)
+		return next(ctx, args)
+	})
+	l := thrift.NewTServerSocket("tcp", address, opts)
+	if err := l.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	s := thrift.NewTServer(l, p, thrift.TServerOptions{})
+	go s.Serve()
+	defer s.Stop(context.Background())
+
+	sock := thrift.NewTSocket("tcp", address, thrift.TSocketOptions{ConnectTimeout: time.Second})
+	if err := sock.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer sock.Close()
+	c := thrift.NewTStandardClient(thrift.NewTBinaryProtocol(thrift.NewTBufferedTransport(sock, nil), nil), nil)
+	if err := echo(c, 1); err != nil {
+		t.Fatal(err)
+	}
+	cred := <-creds
+	if cred == nil || cred.PID != int32(os.Getpid()) || cred.UID != uint32(os.Getuid()) || cred.GID != uint32(os.Getgid()) {
+		t.Fatalf("unexpected peer credentials: %+v", cred)
+	}
+}
+
+func TestUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "thrift.sock")
+	testUnixServer(t, "unix://"+path, thrift.TSocketOptions{FileMode: 0600})
+}
+
+func TestUnixSocketFileMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "thrift.sock")
+	l := thrift.NewTServerSocket("unix", path, thrift.TSocketOptions{FileMode: 0600})
+	if err := l.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatal("unexpected permission of socket file", info.Mode())
+	}
+}
+
+func TestUnixSocketUmask(t *testing.T) {
+	umask := syscall.Umask(022)
+	defer syscall.Umask(umask)
+	path := filepath.Join(t.TempDir(), "thrift.sock")
+	l := thrift.NewTServerSocket("unix", path, thrift.TSocketOptions{FileMode: 0666})
+	if err := l.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0666 {
+		t.Fatal("unexpected permission of socket file", info, err)
+	}
+	if m := syscall.Umask(022); m != 022 {
+		t.Fatalf("umask must be restored, got %o", m)
+	}
+}
+
+func TestUnixSocketStaleFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "thrift.sock")
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	l := thrift.NewTServerSocket("unix", path, thrift.TSocketOptions{FileMode: 0600})
+	if err := l.Listen(); err == nil {
+		l.Close()
+		t.Fatal("must fail on stale socket file")
+	}
+}
+
+func TestAbstractUnixSocket(t *testing.T) {
+	testUnixServer(t, fmt.Sprintf("unix://@thrift-test-%d", os.Getpid()), thrift.TSocketOptions{FileMode: 0600})
+}
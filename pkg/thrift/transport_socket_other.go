@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package thrift
+
+// PeerCred returns credentials of peer of unix socket, it is supported only on linux.
+func (s *TSocket) PeerCred() (*TPeerCred, error) {
+	return nil, NewTTransportException(TTransportErrorUnknown, "peer credentials not supported")
+}
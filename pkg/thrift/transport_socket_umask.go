@@ -0,0 +1,34 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package thrift
+
+import (
+	"net"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// umaskMutex serializes changes of umask, which is shared by the process.
+var umaskMutex sync.Mutex
+
+// listenUnix listens on unix socket file at address with permission mode,
+// the file is created under umask denying permissions outside mode,
+// so it is never accessible beyond mode.
+func listenUnix(network, address string, mode os.FileMode) (net.Listener, error) {
+	umaskMutex.Lock()
+	umask := syscall.Umask(int(^mode & os.ModePerm))
+	l, err := net.Listen(network, address)
+	syscall.Umask(umask)
+	umaskMutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	// umask only clears permissions, grant the rest of mode.
+	if err = os.Chmod(address, mode); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
@@ -0,0 +1,23 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package thrift
+
+import (
+	"net"
+	"os"
+)
+
+// listenUnix listens on unix socket file at address with permission mode,
+// umask is not supported, so the file is changed to mode after it is created.
+func listenUnix(network, address string, mode os.FileMode) (net.Listener, error) {
+	l, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.Chmod(address, mode); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
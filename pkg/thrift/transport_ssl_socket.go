@@ -0,0 +1,190 @@
+package thrift
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TSSLSocket a TSocket of TLS connection.
+type TSSLSocket struct {
+	*TSocket
+	cfg *tls.Config
+}
+
+// NewTSSLSocket returns new TSSLSocket which connects to address with cfg on Open.
+// server name of cfg is host of address if empty.
+func NewTSSLSocket(network, address string, cfg *tls.Config, opts TSocketOptions) *TSSLSocket {
+	return &TSSLSocket{NewTSocket(network, address, opts), cfg}
+}
+
+// NewTSSLSocketFromConn returns new TSSLSocket of opened conn.
+func NewTSSLSocketFromConn(conn *tls.Conn, opts TSocketOptions) *TSSLSocket {
+	return &TSSLSocket{NewTSocketFromConn(conn, opts), nil}
+}
+
+// Open connects to address of s and completes handshake.
+func (s *TSSLSocket) Open(ctx context.Context) error {
+	if s.IsOpen() {
+		return NewTTransportException(TTransportErrorAlreadyOpen, "socket already open")
+	}
+	d := tls.Dialer{NetDialer: &net.Dialer{Timeout: s.opts.ConnectTimeout}, Config: s.cfg}
+	conn, err := d.DialContext(ctx, s.network, s.address)
+	if err != nil {
+		return NewTTransportExceptionFromError(err)
+	}
+	s.conn = conn
+	atomic.StoreInt32(&s.closed, 0)
+	return nil
+}
+
+// Handshake completes handshake if it has not been done,
+// it is limited by socket timeout of s.
+func (s *TSSLSocket) Handshake() error {
+	conn, ok := s.conn.(*tls.Conn)
+	if !ok {
+		return NewTTransportException(TTransportErrorNotOpen, "socket not open")
+	}
+	if s.opts.SocketTimeout > 0 {
+		conn.SetDeadline(time.Now().Add(s.opts.SocketTimeout))
+		defer conn.SetDeadline(time.Time{})
+	}
+	return NewTTransportExceptionFromError(conn.Handshake())
+}
+
+// ConnectionState returns state of TLS connection,
+// such as verified chains of peer and negotiated ALPN protocol.
+func (s *TSSLSocket) ConnectionState() tls.ConnectionState {
+	if conn, ok := s.conn.(*tls.Conn); ok {
+		return conn.ConnectionState()
+	}
+	return tls.ConnectionState{}
+}
+
+// NewTSSLSocketFactory returns new TTransportFactory of opened TSSLSocket.
+func NewTSSLSocketFactory(network, address string, cfg *tls.Config, opts TSocketOptions) TTransportFactory {
+	return &tSSLSocketFactory{network, address, cfg, opts}
+}
+
+type tSSLSocketFactory struct {
+	network, address string
+	cfg              *tls.Config
+	opts             TSocketOptions
+}
+
+func (f *tSSLSocketFactory) GetTransport(TTransport) (TTransport, error) {
+	s := NewTSSLSocket(f.network, f.address, f.cfg, f.opts)
+	if err := s.Open(context.Background()); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// TSSLServerSocket a TServerSocket which accepts TSSLSocket.
+// handshake is done by TServer before connection is served, so verified
+// peer certificates are available by TPeerCertificatesFromContext.
+type TSSLServerSocket struct {
+	*TServerSocket
+	cfg *tls.Config
+}
+
+// NewTSSLServerSocket returns new TSSLServerSocket which listens on address
+// and accepts connections with cfg.
+// set ClientAuth of cfg to tls.RequireAndVerifyClientCert for mutual TLS.
+func NewTSSLServerSocket(network, address string, cfg *tls.Config, opts TSocketOptions) *TSSLServerSocket {
+	return &TSSLServerSocket{NewTServerSocket(network, address, opts), cfg}
+}
+
+// NewTSSLServerSocketFromListener returns new TSSLServerSocket of l,
+// which accepts connections with cfg.
+func NewTSSLServerSocketFromListener(l net.Listener, cfg *tls.Config, opts TSocketOptions) *TSSLServerSocket {
+	return &TSSLServerSocket{NewTServerSocketFromListener(l, opts), cfg}
+}
+
+// Accept waits for and returns next TSSLSocket, handshake is not done yet.
+func (s *TSSLServerSocket) Accept() (TTransport, error) {
+	t, err := s.TServerSocket.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return NewTSSLSocketFromConn(tls.Server(t.(*TSocket).conn, s.cfg), s.opts), nil
+}
+
+// TCertificateReloader a certificate which is loaded from files and
+// reloaded when the files are modified, so certificates can be rotated
+// without restart.
+// use GetCertificate and GetClientCertificate in tls.Config.
+type TCertificateReloader struct {
+	certFile, keyFile string
+
+	mutex   sync.Mutex
+	cert    *tls.Certificate
+	modTime [2]time.Time
+}
+
+// NewTCertificateReloader returns new TCertificateReloader of
+// PEM encoded certificate and key files.
+func NewTCertificateReloader(certFile, keyFile string) (*TCertificateReloader, error) {
+	r := &TCertificateReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload loads certificate and key from files.
+func (r *TCertificateReloader) Reload() error {
+	modTime, err := r.stat()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	r.mutex.Lock()
+	r.cert, r.modTime = &cert, modTime
+	r.mutex.Unlock()
+	return nil
+}
+
+// Certificate returns current certificate, it reloads files if they are modified.
+// previous certificate is kept if files can not be loaded, e.g. while they are written.
+func (r *TCertificateReloader) Certificate() *tls.Certificate {
+	if modTime, err := r.stat(); err == nil {
+		r.mutex.Lock()
+		changed := modTime != r.modTime
+		r.mutex.Unlock()
+		if changed {
+			r.Reload()
+		}
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.cert
+}
+
+// GetCertificate returns current certificate, for tls.Config.GetCertificate.
+func (r *TCertificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.Certificate(), nil
+}
+
+// GetClientCertificate returns current certificate, for tls.Config.GetClientCertificate.
+func (r *TCertificateReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.Certificate(), nil
+}
+
+func (r *TCertificateReloader) stat() (modTime [2]time.Time, err error) {
+	for i, name := range [2]string{r.certFile, r.keyFile} {
+		var info os.FileInfo
+		if info, err = os.Stat(name); err != nil {
+			return
+		}
+		modTime[i] = info.ModTime()
+	}
+	return
+}
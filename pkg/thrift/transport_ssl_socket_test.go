@@ -0,0 +1,169 @@
+package thrift_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/b1avk/thrift/pkg/thrift"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCert(t *testing.T, name string, parent *testCert, edit func(*x509.Certificate)) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if edit != nil {
+		edit(tmpl)
+	}
+	parentCert, parentKey := tmpl, key
+	if parent != nil {
+		parentCert, parentKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parentCert, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{cert, key}
+}
+
+func newTestCA(t *testing.T) *testCert {
+	return newTestCert(t, "ca", nil, func(c *x509.Certificate) {
+		c.IsCA, c.BasicConstraintsValid = true, true
+		c.KeyUsage |= x509.KeyUsageCertSign
+	})
+}
+
+func (c *testCert) writeFiles(t *testing.T, certFile, keyFile string) {
+	key, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: key}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key}
+}
+
+func newTestServerCert(t *testing.T, ca *testCert, name string) *testCert {
+	return newTestCert(t, name, ca, func(c *x509.Certificate) {
+		c.IPAddresses = []net.IP{net.IPv4(127, 0, 0, 1)}
+	})
+}
+
+func TestTSSLSocket(t *testing.T) {
+	ca := newTestCA(t)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "server.pem"), filepath.Join(dir, "server.key")
+	newTestServerCert(t, ca, "server-1").writeFiles(t, certFile, keyFile)
+	reloader, err := thrift.NewTCertificateReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := url.Parse("spiffe://example.org/client")
+	client := newTestCert(t, "client", ca, func(c *x509.Certificate) {
+		c.URIs = []*url.URL{id}
+	})
+
+	p := newEchoProcessor(0, nil)
+	calls := make(chan context.Context, 1)
+	p.Use(func(ctx context.Context, call thrift.TCallInfo, args thrift.TStruct, next thrift.THandler) (thrift.TStruct, error) {
+		calls <- ctx
+		return next(ctx, args)
+	})
+	l := thrift.NewTSSLServerSocket("tcp", "127.0.0.1:0", &tls.Config{
+		GetCertificate: reloader.GetCertificate,
+		ClientAuth:     tls.RequireAndVerifyClientCert,
+		ClientCAs:      pool,
+		NextProtos:     []string{"thrift"},
+	}, thrift.TSocketOptions{SocketTimeout: time.Second})
+	if err = l.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	s := thrift.NewTServer(l, p, thrift.TServerOptions{})
+	go s.Serve()
+	defer s.Stop(context.Background())
+
+	connect := func(certs ...tls.Certificate) (*thrift.TSSLSocket, thrift.TClient, error) {
+		sock := thrift.NewTSSLSocket("tcp", l.Addr().String(), &tls.Config{
+			RootCAs:      pool,
+			Certificates: certs,
+			NextProtos:   []string{"thrift"},
+		}, thrift.TSocketOptions{ConnectTimeout: time.Second, SocketTimeout: time.Second})
+		if err := sock.Open(context.Background()); err != nil {
+			return nil, nil, err
+		}
+		c := thrift.NewTStandardClient(thrift.NewTBinaryProtocol(thrift.NewTBufferedTransport(sock, nil), nil), nil)
+		return sock, c, echo(c, 1)
+	}
+
+	sock, _, err := connect(client.tlsCertificate())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sock.Close()
+	ctx := <-calls
+	if u := thrift.TPeerSPIFFEIDFromContext(ctx); u == nil || u.String() != id.String() {
+		t.Fatal("unexpected SPIFFE ID of peer", u)
+	}
+	if chain := thrift.TPeerCertificatesFromContext(ctx); len(chain) != 2 || !chain[1].Equal(ca.cert) {
+		t.Fatal("verified chain of peer must be in context")
+	}
+	state := sock.ConnectionState()
+	if state.NegotiatedProtocol != "thrift" || state.PeerCertificates[0].Subject.CommonName != "server-1" {
+		t.Fatal("unexpected connection state", state.NegotiatedProtocol, state.PeerCertificates[0].Subject)
+	}
+
+	if _, _, err = connect(); err == nil {
+		t.Fatal("client without certificate must be rejected")
+	}
+
+	newTestServerCert(t, ca, "server-2").writeFiles(t, certFile, keyFile)
+	sock, _, err = connect(client.tlsCertificate())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sock.Close()
+	<-calls
+	if name := sock.ConnectionState().PeerCertificates[0].Subject.CommonName; name != "server-2" {
+		t.Fatal("certificate must be reloaded", name)
+	}
+}
@@ -0,0 +1,246 @@
+package thrifttest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/b1avk/thrift/pkg/thrift"
+)
+
+// GoldenVector a message or struct of golden wire-format corpus,
+// its bytes in each protocol are stored in file <Name>.hex, see WriteGolden.
+type GoldenVector struct {
+	Name string
+
+	// Extension is true if vector has types of this package which are not
+	// in Apache Thrift: U16, U32 and U64.
+	Extension bool
+
+	message *thrift.TMessageHeader
+	value   structValue
+}
+
+// GoldenVectors returns vectors of golden wire-format corpus.
+func GoldenVectors() []GoldenVector {
+	// canonical quiet NaN, math.NaN has different bits.
+	nan := math.Float64frombits(0x7ff8000000000000)
+	return []GoldenVector{
+		{
+			Name:    "message_call",
+			message: &thrift.TMessageHeader{Name: "ping", Type: thrift.CALL, Identity: 1},
+			value:   structValue{},
+		},
+		{
+			Name:    "message_reply",
+			message: &thrift.TMessageHeader{Name: "add", Type: thrift.REPLY, Identity: 42},
+			value:   structValue{{0, int32(3)}},
+		},
+		{
+			Name:    "message_exception",
+			message: &thrift.TMessageHeader{Name: "ping", Type: thrift.EXCEPTION, Identity: -1},
+			value:   structValue{{1, "boom"}, {2, int32(thrift.TApplicationErrorInternalError)}},
+		},
+		{
+			Name:    "message_oneway",
+			message: &thrift.TMessageHeader{Name: "log", Type: thrift.ONEWAY, Identity: 1 << 20},
+			value:   structValue{{1, "hello"}},
+		},
+		{
+			Name: "struct_all_types",
+			value: structValue{
+				{1, true},
+				{2, false},
+				{3, byte(0x80)},
+				{4, int16(-2)},
+				{5, int32(100000)},
+				{6, int64(-1 << 40)},
+				{7, 1.5},
+				{8, "héllo"},
+				{9, []byte{0, 1, 0xfe, 0xff}},
+				{10, structValue{{1, int32(1)}}},
+				{11, &listValue{thrift.I32, []interface{}{int32(1), int32(2), int32(3)}}},
+				{12, &setValue{thrift.STRING, []interface{}{"a", "b"}}},
+				{13, &mapValue{thrift.STRING, thrift.I64, [][2]interface{}{{"x", int64(1)}}}},
+			},
+		},
+		{
+			Name: "struct_field_ids",
+			value: structValue{
+				{1, true},
+				{16, byte(1)},
+				{17, false},
+				{300, int32(-300)},
+				{2, true},
+				{-1, "negative"},
+				{math.MaxInt16, false},
+				{math.MinInt16, int16(math.MinInt16)},
+			},
+		},
+		{
+			Name: "struct_doubles",
+			value: structValue{
+				{1, 1.5},
+				{2, -0.25},
+				{3, math.Inf(1)},
+				{4, math.Inf(-1)},
+				{5, nan},
+			},
+		},
+		{
+			Name: "nested_containers",
+			value: structValue{
+				{1, &listValue{thrift.MAP, []interface{}{
+					&mapValue{thrift.STRING, thrift.SET, [][2]interface{}{
+						{"k", &setValue{thrift.I16, []interface{}{int16(-1), int16(1)}}},
+					}},
+				}}},
+				{2, &mapValue{thrift.I32, thrift.LIST, [][2]interface{}{
+					{int32(7), &listValue{thrift.STRUCT, []interface{}{
+						structValue{{1, true}},
+						structValue{},
+					}}},
+				}}},
+				{3, &listValue{thrift.LIST, []interface{}{
+					&listValue{thrift.BOOL, []interface{}{true, false}},
+					&listValue{thrift.BOOL, nil},
+				}}},
+				{4, &listValue{thrift.BYTE, []interface{}{
+					byte(0), byte(1), byte(2), byte(3), byte(4), byte(5), byte(6), byte(7),
+					byte(8), byte(9), byte(10), byte(11), byte(12), byte(13), byte(14),
+				}}},
+				{5, &mapValue{thrift.STRING, thrift.STRING, nil}},
+				{6, &setValue{thrift.STRING, nil}},
+			},
+		},
+		{
+			Name:      "extension_unsigned",
+			Extension: true,
+			value: structValue{
+				{1, uint16(math.MaxUint16)},
+				{2, uint32(math.MaxUint32)},
+				{3, uint64(math.MaxUint64)},
+				{4, &listValue{thrift.U32, []interface{}{uint32(1)}}},
+			},
+		},
+	}
+}
+
+// Write writes v to p and flushes p.
+func (v GoldenVector) Write(p thrift.TProtocol) (err error) {
+	if v.message != nil {
+		if err = p.WriteMessageBegin(*v.message); err != nil {
+			return
+		}
+	}
+	if err = writeValue(p, v.value); err != nil {
+		return
+	}
+	if v.message != nil {
+		if err = p.WriteMessageEnd(); err != nil {
+			return
+		}
+	}
+	return p.Flush(context.Background())
+}
+
+// Read reads v from p, it returns error if read value differs from v.
+func (v GoldenVector) Read(p thrift.TProtocol) (err error) {
+	if v.message != nil {
+		var h thrift.TMessageHeader
+		if h, err = p.ReadMessageBegin(); err != nil {
+			return
+		}
+		if h != *v.message {
+			return fmt.Errorf("expected message %+v, got %+v", *v.message, h)
+		}
+	}
+	if err = readValue(p, v.value); err != nil {
+		return
+	}
+	if v.message != nil {
+		err = p.ReadMessageEnd()
+	}
+	return
+}
+
+// WriteGolden writes b to w in format of golden files, which is lines of
+// hex encoded bytes, after comment lines starting with #.
+func WriteGolden(w io.Writer, comment string, b []byte) error {
+	var buf bytes.Buffer
+	for _, line := range strings.Split(comment, "\n") {
+		fmt.Fprintf(&buf, "# %s\n", line)
+	}
+	for len(b) > 0 {
+		n := 16
+		if n > len(b) {
+			n = len(b)
+		}
+		for i, c := range b[:n] {
+			if i > 0 {
+				buf.WriteByte(' ')
+			}
+			fmt.Fprintf(&buf, "%02x", c)
+		}
+		buf.WriteByte('\n')
+		b = b[n:]
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// ReadGolden returns bytes of golden file of which content is b, see WriteGolden.
+func ReadGolden(b []byte) ([]byte, error) {
+	var h strings.Builder
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		h.WriteString(strings.Join(strings.Fields(line), ""))
+	}
+	return hex.DecodeString(h.String())
+}
+
+// TestGolden tests that TProtocol of f reads every golden vector from
+// its file in dir as the vector, and writes the vector as bytes of the file.
+// vectors with Extension are skipped if they have no file in dir.
+func TestGolden(t *testing.T, f thrift.TProtocolFactory, dir string) {
+	for _, v := range GoldenVectors() {
+		v := v
+		t.Run(v.Name, func(t *testing.T) {
+			name := filepath.Join(dir, v.Name+".hex")
+			content, err := ioutil.ReadFile(name)
+			if os.IsNotExist(err) && v.Extension {
+				t.Skip("no golden file of extension vector")
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := ReadGolden(content)
+			if err != nil {
+				t.Fatalf("invalid golden file %s: %v", name, err)
+			}
+			if err = v.Read(f.GetProtocol(newMemoryBuffer(want))); err != nil {
+				t.Fatalf("fail to read %s: %v", name, err)
+			}
+			b := thrift.NewTMemoryBuffer()
+			if err = v.Write(f.GetProtocol(b)); err != nil {
+				t.Fatal(err)
+			}
+			if got := b.Bytes(); !bytes.Equal(got, want) {
+				t.Fatalf("bytes differ from %s:\nwant %x\ngot  %x", name, want, got)
+			}
+		})
+	}
+}
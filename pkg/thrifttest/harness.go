@@ -0,0 +1,67 @@
+package thrifttest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/b1avk/thrift/pkg/thrift"
+)
+
+// Harness a thrift.TServer of processor on Listener and a client connected to it.
+type Harness struct {
+	Server   *thrift.TServer
+	Listener *Listener
+
+	// Conn client end of connection, its peer is the server end.
+	// set faults of Conn or its peer to inject faults into requests or replies.
+	Conn *Conn
+
+	// Client client of Conn.
+	Client *thrift.TStandardClient
+}
+
+// NewHarness starts processor on new Listener and returns Harness with a connected client,
+// requests and replies are encoded with protocol of f, or binary protocol if f is nil.
+// the server is stopped when tb and its subtests are done.
+func NewHarness(tb testing.TB, processor thrift.TProcessor, f thrift.TProtocolFactory) *Harness {
+	return NewHarnessWithOptions(tb, processor, thrift.TServerOptions{InputProtocolFactory: f})
+}
+
+// NewHarnessWithOptions same as NewHarness but starts server with opts,
+// client writes with InputProtocolFactory and reads with OutputProtocolFactory of opts.
+func NewHarnessWithOptions(tb testing.TB, processor thrift.TProcessor, opts thrift.TServerOptions) *Harness {
+	tb.Helper()
+	if opts.InputProtocolFactory == nil {
+		opts.InputProtocolFactory = thrift.NewTBinaryProtocolFactory(nil)
+	}
+	if opts.OutputProtocolFactory == nil {
+		opts.OutputProtocolFactory = opts.InputProtocolFactory
+	}
+	if opts.InputTransportFactory == nil && opts.OutputTransportFactory == nil {
+		// each message is written at once, so faults apply to whole frames.
+		opts.InputTransportFactory = thrift.NewTBufferedTransportFactory(nil, nil)
+		opts.OutputTransportFactory = opts.InputTransportFactory
+	}
+	h := &Harness{Listener: NewListener()}
+	h.Server = thrift.NewTServer(h.Listener, processor, opts)
+	done := make(chan error, 1)
+	go func() {
+		done <- h.Server.Serve()
+	}()
+	tb.Cleanup(func() {
+		h.Server.Stop(context.Background())
+		if err := <-done; err != nil {
+			tb.Error(err)
+		}
+	})
+	var err error
+	if h.Conn, err = h.Listener.Dial(context.Background()); err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() {
+		h.Conn.Close()
+	})
+	t := thrift.NewTBufferedTransport(h.Conn, nil)
+	h.Client = thrift.NewTStandardClient(opts.OutputProtocolFactory.GetProtocol(t), opts.InputProtocolFactory.GetProtocol(t))
+	return h
+}
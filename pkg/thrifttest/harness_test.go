@@ -0,0 +1,82 @@
+package thrifttest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/b1avk/thrift/pkg/thrift"
+	"github.com/b1avk/thrift/pkg/thrifttest"
+)
+
+func newEchoHarness(t *testing.T) *thrifttest.Harness {
+	p := thrift.NewTStandardProcessor()
+	p.Handle("echo", func() thrift.TStruct {
+		return new(thrift.TApplicationException)
+	}, func(ctx context.Context, args thrift.TStruct) (thrift.TStruct, error) {
+		return args, nil
+	})
+	return thrifttest.NewHarness(t, p, nil)
+}
+
+func echo(c thrift.TClient) error {
+	args := &thrift.TApplicationException{Message: "Hello", Type: 1}
+	r := new(thrift.TApplicationException)
+	if err := c.Call(context.Background(), "echo", args, r); err != nil {
+		return err
+	}
+	if *r != *args {
+		return errors.New("echo mismatch")
+	}
+	return nil
+}
+
+func TestHarness(t *testing.T) {
+	h := newEchoHarness(t)
+	for i := 0; i < 3; i++ {
+		if err := echo(h.Client); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestHarnessLatency(t *testing.T) {
+	h := newEchoHarness(t)
+	h.Conn.Peer().SetFaults(thrifttest.Faults{Latency: 20 * time.Millisecond})
+	start := time.Now()
+	if err := echo(h.Client); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) < 20*time.Millisecond {
+		t.Fatal("reply must be delayed")
+	}
+}
+
+func TestHarnessDropEvery(t *testing.T) {
+	h := newEchoHarness(t)
+	h.Conn.Peer().SetFaults(thrifttest.Faults{DropEvery: 5})
+	h.Conn.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	if err := echo(h.Client); err == nil {
+		t.Fatal("reply with dropped bytes must not be decoded")
+	}
+}
+
+func TestHarnessTruncate(t *testing.T) {
+	h := newEchoHarness(t)
+	h.Conn.SetFaults(thrifttest.Faults{TruncateAfter: 10})
+	if err := echo(h.Client); err == nil {
+		t.Fatal("truncated request must fail")
+	}
+}
+
+func TestHarnessReset(t *testing.T) {
+	h := newEchoHarness(t)
+	h.Conn.SetFaults(thrifttest.Faults{ResetAfter: 10})
+	if err := echo(h.Client); !errors.Is(err, thrifttest.ErrReset) {
+		t.Fatal("expected reset", err)
+	}
+	if err := echo(h.Client); !errors.Is(err, thrifttest.ErrReset) {
+		t.Fatal("writes after reset must fail", err)
+	}
+}
@@ -0,0 +1,215 @@
+// Package thrifttest provides utilities for testing of thrift processors,
+// protocols and clients without sockets.
+package thrifttest
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/b1avk/thrift/pkg/thrift"
+)
+
+// ErrReset is returned by Write of Conn which is reset by Faults.ResetAfter.
+var ErrReset = errors.New("thrifttest: connection reset")
+
+// Faults faults injected into data written to Conn.
+// zero value injects nothing.
+type Faults struct {
+	// Latency delay of each write.
+	Latency time.Duration
+
+	// DropEvery drops every n-th byte written, if > 0.
+	DropEvery int
+
+	// TruncateAfter closes Conn once n bytes are written, if > 0;
+	// the peer reads truncated data while writes keep succeeding.
+	TruncateAfter int
+
+	// ResetAfter closes Conn once n bytes are written, if > 0;
+	// the write and later ones fail with ErrReset.
+	ResetAfter int
+}
+
+// Conn a thrift.TTransport and net.Conn of one end of in-memory connection, see Pipe.
+// writes block until the peer reads data.
+type Conn struct {
+	conn net.Conn
+	peer *Conn
+
+	mutex   sync.Mutex
+	faults  Faults
+	written int
+	state   int
+}
+
+const (
+	connOpen = iota
+	connTruncated
+	connReset
+)
+
+// Pipe returns connected ends of in-memory connection.
+func Pipe() (*Conn, *Conn) {
+	a, b := net.Pipe()
+	x, y := &Conn{conn: a}, &Conn{conn: b}
+	x.peer, y.peer = y, x
+	return x, y
+}
+
+// Peer returns the other end of connection.
+func (c *Conn) Peer() *Conn {
+	return c.peer
+}
+
+// SetFaults sets faults of data written from now on.
+func (c *Conn) SetFaults(f Faults) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.faults, c.written = f, 0
+}
+
+// LocalAddr returns address of c.
+func (c *Conn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+// RemoteAddr returns address of peer of c.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// SetDeadline sets deadline of reads and writes.
+func (c *Conn) SetDeadline(t time.Time) error {
+	return c.conn.SetDeadline(t)
+}
+
+// SetReadDeadline sets deadline of reads.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets deadline of writes.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// Read reads data written by peer.
+func (c *Conn) Read(v []byte) (int, error) {
+	n, err := c.conn.Read(v)
+	return n, thrift.NewTTransportExceptionFromError(err)
+}
+
+// Write writes v to peer with faults of c.
+func (c *Conn) Write(v []byte) (int, error) {
+	c.mutex.Lock()
+	f, offset, state := c.faults, c.written, c.state
+	// cut is number of bytes of v written before connection is cut, -1 if it is not.
+	cut, next := -1, connOpen
+	if n := f.TruncateAfter - offset; f.TruncateAfter > 0 && n <= len(v) {
+		cut, next = n, connTruncated
+	}
+	if n := f.ResetAfter - offset; f.ResetAfter > 0 && n <= len(v) && (cut < 0 || n < cut) {
+		cut, next = n, connReset
+	}
+	if state == connOpen {
+		c.written += len(v)
+		c.state = next
+	}
+	c.mutex.Unlock()
+	switch state {
+	case connTruncated:
+		return len(v), nil
+	case connReset:
+		return 0, thrift.NewTTransportExceptionFromError(ErrReset)
+	}
+	if f.Latency > 0 {
+		time.Sleep(f.Latency)
+	}
+	data := v
+	if cut >= 0 {
+		data = v[:cut]
+	}
+	if f.DropEvery > 0 {
+		kept := make([]byte, 0, len(data))
+		for i, b := range data {
+			if (offset+i+1)%f.DropEvery != 0 {
+				kept = append(kept, b)
+			}
+		}
+		data = kept
+	}
+	if len(data) > 0 {
+		if _, err := c.conn.Write(data); err != nil {
+			return 0, thrift.NewTTransportExceptionFromError(err)
+		}
+	}
+	if cut >= 0 {
+		c.conn.Close()
+		if next == connReset {
+			return cut, thrift.NewTTransportExceptionFromError(ErrReset)
+		}
+	}
+	return len(v), nil
+}
+
+// Flush flushing is no-op; always returns nil.
+func (c *Conn) Flush(ctx context.Context) error {
+	return nil
+}
+
+// Close closes connection, the peer reads EOF.
+func (c *Conn) Close() error {
+	return thrift.NewTTransportExceptionFromError(c.conn.Close())
+}
+
+// Listener a thrift.TServerTransport of in-memory connections,
+// clients connect by Dial.
+type Listener struct {
+	conns     chan *Conn
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewListener returns new Listener.
+func NewListener() *Listener {
+	return &Listener{conns: make(chan *Conn), closed: make(chan struct{})}
+}
+
+// Listen listening is no-op; always returns nil.
+func (l *Listener) Listen() error {
+	return nil
+}
+
+// Accept waits for and returns server end of connection of next Dial.
+func (l *Listener) Accept() (thrift.TTransport, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case <-l.closed:
+		return nil, thrift.NewTTransportException(thrift.TTransportErrorNotOpen, "listener closed")
+	}
+}
+
+// Close stops accepting, blocked Accept and Dial return error.
+func (l *Listener) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.closed)
+	})
+	return nil
+}
+
+// Dial returns client end of new connection which is accepted by l.
+func (l *Listener) Dial(ctx context.Context) (*Conn, error) {
+	c, s := Pipe()
+	select {
+	case l.conns <- s:
+		return c, nil
+	case <-l.closed:
+		return nil, thrift.NewTTransportException(thrift.TTransportErrorNotOpen, "listener closed")
+	case <-ctx.Done():
+		return nil, thrift.NewTTransportExceptionFromError(ctx.Err())
+	}
+}
@@ -0,0 +1,407 @@
+package thrifttest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/b1avk/thrift/pkg/thrift"
+)
+
+// TestProtocol tests that TProtocol of f reads what it writes: messages,
+// values of every TType with their boundaries, nested containers and fields,
+// Skip of every TType, and that truncated input, nesting over max recursion depth
+// and sizes over max message size of configuration of f or over remaining bytes
+// of TMemoryBuffer are rejected.
+// values of unsigned types may be read with field and container headers of
+// signed types of same size, key and value types of empty maps are not compared.
+func TestProtocol(t *testing.T, f thrift.TProtocolFactory) {
+	t.Run("Message", func(t *testing.T) {
+		testProtocolMessage(t, f)
+	})
+	t.Run("Values", func(t *testing.T) {
+		for _, typ := range protocolScalarTypes {
+			values := protocolScalarValues(f)[typ]
+			t.Run(protocolTypeNames[typ], func(t *testing.T) {
+				s := make(structValue, len(values))
+				for i, v := range values {
+					s[i] = field{int16(i + 1), v}
+				}
+				testProtocolRoundTrip(t, f, s)
+			})
+		}
+	})
+	t.Run("Containers", func(t *testing.T) {
+		for name, v := range protocolContainerValues {
+			t.Run(name, func(t *testing.T) {
+				testProtocolRoundTrip(t, f, structValue{{1, v}})
+			})
+		}
+	})
+	t.Run("Fields", func(t *testing.T) {
+		testProtocolRoundTrip(t, f, protocolFieldsValue)
+	})
+	t.Run("Skip", func(t *testing.T) {
+		for _, v := range protocolSkipValues(f) {
+			t.Run(protocolTypeNames[typeOf(v)], func(t *testing.T) {
+				testProtocolSkip(t, f, v)
+			})
+		}
+	})
+	t.Run("Truncated", func(t *testing.T) {
+		testProtocolTruncated(t, f)
+	})
+	t.Run("Oversize", func(t *testing.T) {
+		testProtocolOversize(t, f)
+	})
+	t.Run("Remaining", func(t *testing.T) {
+		testProtocolRemaining(t, f)
+	})
+	t.Run("Depth", func(t *testing.T) {
+		testProtocolDepth(t, f)
+	})
+}
+
+var protocolTypeNames = map[thrift.TType]string{
+	thrift.BOOL:   "BOOL",
+	thrift.BYTE:   "BYTE",
+	thrift.DOUBLE: "DOUBLE",
+	thrift.U16:    "U16",
+	thrift.I16:    "I16",
+	thrift.U32:    "U32",
+	thrift.I32:    "I32",
+	thrift.U64:    "U64",
+	thrift.I64:    "I64",
+	thrift.STRING: "STRING",
+	thrift.STRUCT: "STRUCT",
+	thrift.MAP:    "MAP",
+	thrift.SET:    "SET",
+	thrift.LIST:   "LIST",
+}
+
+var protocolScalarTypes = []thrift.TType{
+	thrift.BOOL, thrift.BYTE, thrift.DOUBLE,
+	thrift.U16, thrift.I16, thrift.U32, thrift.I32, thrift.U64, thrift.I64,
+	thrift.STRING,
+}
+
+// protocolScalarValues returns boundary values of each scalar type,
+// STRING values include binaries and huge values within max message size of f.
+func protocolScalarValues(f thrift.TProtocolFactory) map[thrift.TType][]interface{} {
+	huge := thrift.TConfigurationOf(f).GetMaxMessageSize()
+	if huge > 1<<20 {
+		huge = 1 << 20
+	}
+	all := make([]byte, 256)
+	for i := range all {
+		all[i] = byte(i)
+	}
+	return map[thrift.TType][]interface{}{
+		thrift.BOOL: {true, false},
+		thrift.BYTE: {byte(0), byte(1), byte(0x7f), byte(0x80), byte(0xff)},
+		thrift.DOUBLE: {
+			0.0, math.Copysign(0, -1), 1.5, -1e300, math.MaxFloat64, -math.MaxFloat64,
+			math.SmallestNonzeroFloat64, math.NaN(), math.Inf(1), math.Inf(-1),
+		},
+		thrift.U16: {uint16(0), uint16(1), uint16(math.MaxUint16)},
+		thrift.I16: {int16(0), int16(1), int16(-1), int16(math.MinInt16), int16(math.MaxInt16)},
+		thrift.U32: {uint32(0), uint32(1), uint32(math.MaxUint32)},
+		thrift.I32: {int32(0), int32(1), int32(-1), int32(math.MinInt32), int32(math.MaxInt32)},
+		thrift.U64: {uint64(0), uint64(1), uint64(math.MaxUint64)},
+		thrift.I64: {int64(0), int64(1), int64(-1), int64(math.MinInt64), int64(math.MaxInt64)},
+		thrift.STRING: {
+			"", "a", "héllo, 世界", "\"quote\" \\ / \b\f\n\r\t \x00\x01\x1f\x7f",
+			strings.Repeat("x", huge),
+			[]byte{}, []byte{0}, all,
+			// binary may be encoded larger than it is, e.g. base64.
+			bytes.Repeat([]byte{0xa5}, huge/2),
+		},
+	}
+}
+
+var protocolContainerValues = map[string]interface{}{
+	"EmptyList": &listValue{thrift.I32, nil},
+	"EmptySet":  &setValue{thrift.STRING, nil},
+	"EmptyMap":  &mapValue{thrift.STRING, thrift.I64, nil},
+	"ListOfBool": &listValue{thrift.BOOL, []interface{}{
+		true, false, false, true,
+	}},
+	"SetOfBinary": &setValue{thrift.STRING, []interface{}{
+		[]byte{1, 2}, []byte{}, []byte{0xff},
+	}},
+	"MapOfUnsigned": &mapValue{thrift.U16, thrift.U64, [][2]interface{}{
+		{uint16(1), uint64(math.MaxUint64)}, {uint16(math.MaxUint16), uint64(0)},
+	}},
+	"MapOfBoolDouble": &mapValue{thrift.BOOL, thrift.DOUBLE, [][2]interface{}{
+		{true, math.Inf(-1)}, {false, 0.5},
+	}},
+	"ListOfMapOfSet": &listValue{thrift.MAP, []interface{}{
+		&mapValue{thrift.STRING, thrift.SET, [][2]interface{}{
+			{"a", &setValue{thrift.I64, []interface{}{int64(math.MinInt64), int64(0)}}},
+			{"b", &setValue{thrift.I64, nil}},
+		}},
+		&mapValue{thrift.STRING, thrift.SET, nil},
+	}},
+	"MapOfListOfStruct": &mapValue{thrift.I32, thrift.LIST, [][2]interface{}{
+		{int32(-1), &listValue{thrift.STRUCT, []interface{}{
+			structValue{{1, "x"}, {2, true}},
+			structValue{},
+			structValue{{3, &listValue{thrift.LIST, []interface{}{
+				&listValue{thrift.BYTE, []interface{}{byte(1)}},
+			}}}},
+		}}},
+	}},
+}
+
+// protocolFieldsValue struct with bool fields mixed with other fields,
+// and field ids which are both close and far from previous ones.
+var protocolFieldsValue = structValue{
+	{1, true},
+	{2, false},
+	{20, true},
+	{3, byte(7)},
+	{-1, false},
+	{math.MaxInt16, true},
+	{math.MinInt16, false},
+	{4, structValue{{1, true}, {2, false}, {18, structValue{{1, false}}}}},
+	{5, &listValue{thrift.BOOL, []interface{}{true, false}}},
+	{6, true},
+	{22, int32(1)},
+	{21, false},
+}
+
+func protocolSkipValues(f thrift.TProtocolFactory) []interface{} {
+	var values []interface{}
+	for _, typ := range protocolScalarTypes {
+		values = append(values, protocolScalarValues(f)[typ][1])
+	}
+	return append(values,
+		protocolFieldsValue,
+		protocolContainerValues["MapOfListOfStruct"],
+		protocolContainerValues["SetOfBinary"],
+		protocolContainerValues["ListOfMapOfSet"],
+	)
+}
+
+func testProtocolMessage(t *testing.T, f thrift.TProtocolFactory) {
+	headers := []thrift.TMessageHeader{
+		{Name: "", Type: thrift.CALL, Identity: 0},
+		{Name: "method", Type: thrift.REPLY, Identity: 1},
+		{Name: "世界", Type: thrift.EXCEPTION, Identity: math.MinInt32},
+		{Name: "oneway", Type: thrift.ONEWAY, Identity: math.MaxInt32},
+	}
+	for _, h := range headers {
+		b := thrift.NewTMemoryBuffer()
+		p := f.GetProtocol(b)
+		args := structValue{{1, h.Identity}}
+		err := p.WriteMessageBegin(h)
+		if err == nil {
+			if err = writeValue(p, args); err == nil {
+				if err = p.WriteMessageEnd(); err == nil {
+					err = p.Flush(context.Background())
+				}
+			}
+		}
+		if err != nil {
+			t.Fatalf("fail to write message %+v: %v", h, err)
+		}
+		p = f.GetProtocol(b)
+		r, err := p.ReadMessageBegin()
+		if err != nil {
+			t.Fatalf("fail to read message %+v: %v", h, err)
+		}
+		if r != h {
+			t.Fatalf("expected message %+v, got %+v", h, r)
+		}
+		if err = readValue(p, args); err == nil {
+			err = p.ReadMessageEnd()
+		}
+		if err != nil {
+			t.Fatalf("fail to read message %+v: %v", h, err)
+		}
+	}
+}
+
+// encode returns bytes of v written by protocol of f.
+func encode(t *testing.T, f thrift.TProtocolFactory, v interface{}) []byte {
+	b := thrift.NewTMemoryBuffer()
+	p := f.GetProtocol(b)
+	err := writeValue(p, v)
+	if err == nil {
+		err = p.Flush(context.Background())
+	}
+	if err != nil {
+		t.Fatalf("fail to write %s: %v", protocolTypeNames[typeOf(v)], err)
+	}
+	return b.Bytes()
+}
+
+func newMemoryBuffer(b []byte) *thrift.TMemoryBuffer {
+	m := thrift.NewTMemoryBuffer()
+	m.Write(b)
+	return m
+}
+
+func testProtocolRoundTrip(t *testing.T, f thrift.TProtocolFactory, v interface{}) {
+	b := encode(t, f, v)
+	p := f.GetProtocol(newMemoryBuffer(b))
+	if err := readValue(p, v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testProtocolSkip(t *testing.T, f thrift.TProtocolFactory, v interface{}) {
+	const sentinel = int32(0x5eed)
+	b := encode(t, f, structValue{{1, v}, {2, sentinel}})
+	p := f.GetProtocol(newMemoryBuffer(b))
+	if _, err := p.ReadStructBegin(); err != nil {
+		t.Fatal(err)
+	}
+	h, err := p.ReadFieldBegin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Skip(h.Type); err != nil {
+		t.Fatalf("fail to skip %s: %v", protocolTypeNames[h.Type], err)
+	}
+	if err = p.ReadFieldEnd(); err != nil {
+		t.Fatal(err)
+	}
+	if err = readFields(p, structValue{{2, sentinel}}); err != nil {
+		t.Fatal("fail to read field after skipped one:", err)
+	}
+
+	p = f.GetProtocol(newMemoryBuffer(b))
+	if err = p.Skip(thrift.STRUCT); err != nil {
+		t.Fatal("fail to skip struct:", err)
+	}
+}
+
+func testProtocolTruncated(t *testing.T, f thrift.TProtocolFactory) {
+	v := structValue{
+		{1, protocolFieldsValue},
+		{2, protocolContainerValues["MapOfListOfStruct"]},
+		{3, protocolContainerValues["ListOfMapOfSet"]},
+		{4, "string"},
+		{5, []byte("binary")},
+		{6, 1.5},
+		{7, uint64(math.MaxUint64)},
+	}
+	b := encode(t, f, v)
+	for n := 0; n < len(b); n++ {
+		if err := readValue(f.GetProtocol(newMemoryBuffer(b[:n])), v); err == nil {
+			t.Fatalf("must error on reading %d of %d bytes", n, len(b))
+		}
+		if err := f.GetProtocol(newMemoryBuffer(b[:n])).Skip(thrift.STRUCT); err == nil {
+			t.Fatalf("must error on skipping %d of %d bytes", n, len(b))
+		}
+	}
+}
+
+func testProtocolOversize(t *testing.T, f thrift.TProtocolFactory) {
+	size := thrift.TConfigurationOf(f).GetMaxMessageSize() + 1
+	cases := map[string]struct {
+		write func(p thrift.TProtocol) error
+		read  func(p thrift.TProtocol) error
+	}{
+		"String": {
+			func(p thrift.TProtocol) error { return p.WriteString(strings.Repeat("x", size)) },
+			func(p thrift.TProtocol) (err error) { _, err = p.ReadString(); return },
+		},
+		"Binary": {
+			func(p thrift.TProtocol) error { return p.WriteBinary(make([]byte, size)) },
+			func(p thrift.TProtocol) (err error) { _, err = p.ReadBinary(); return },
+		},
+		"List": {
+			func(p thrift.TProtocol) error {
+				return p.WriteListBegin(thrift.TListHeader{Element: thrift.I32, Size: size})
+			},
+			func(p thrift.TProtocol) (err error) { _, err = p.ReadListBegin(); return },
+		},
+		"Set": {
+			func(p thrift.TProtocol) error {
+				return p.WriteSetBegin(thrift.TSetHeader{Element: thrift.I32, Size: size})
+			},
+			func(p thrift.TProtocol) (err error) { _, err = p.ReadSetBegin(); return },
+		},
+		"Map": {
+			func(p thrift.TProtocol) error {
+				return p.WriteMapBegin(thrift.TMapHeader{Key: thrift.I32, Value: thrift.I32, Size: size})
+			},
+			func(p thrift.TProtocol) (err error) { _, err = p.ReadMapBegin(); return },
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			b := thrift.NewTMemoryBuffer()
+			p := f.GetProtocol(b)
+			if err := c.write(p); err != nil {
+				// rejected on writing.
+				return
+			}
+			if err := p.Flush(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.read(f.GetProtocol(b)); err == nil {
+				t.Fatalf("must error on reading size %d over max message size", size)
+			}
+		})
+	}
+}
+
+func testProtocolRemaining(t *testing.T, f thrift.TProtocolFactory) {
+	size := thrift.TConfigurationOf(f).GetMaxMessageSize()
+	b := thrift.NewTMemoryBuffer()
+	p := f.GetProtocol(b)
+	err := p.WriteListBegin(thrift.TListHeader{Element: thrift.I64, Size: size})
+	if err == nil {
+		err = p.Flush(context.Background())
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.GetProtocol(b).ReadListBegin(); err == nil {
+		t.Fatalf("must error on reading size %d over remaining bytes", size)
+	}
+}
+
+// nestedValue returns struct of which fields nest structs and containers in
+// depth, counting the struct.
+func nestedValue(depth int) structValue {
+	var v interface{} = &listValue{thrift.I32, []interface{}{int32(1)}}
+	for i := 2; i < depth; i++ {
+		switch i % 4 {
+		case 0:
+			v = &listValue{typeOf(v), []interface{}{v}}
+		case 1:
+			v = &setValue{typeOf(v), []interface{}{v}}
+		case 2:
+			v = &mapValue{thrift.I32, typeOf(v), [][2]interface{}{{int32(1), v}}}
+		case 3:
+			v = structValue{{1, v}}
+		}
+	}
+	return structValue{{1, v}}
+}
+
+func testProtocolDepth(t *testing.T, f thrift.TProtocolFactory) {
+	depth := thrift.TConfigurationOf(f).GetMaxRecursionDepth()
+	testProtocolRoundTrip(t, f, nestedValue(depth))
+
+	b := encode(t, f, nestedValue(depth+1))
+	err := readValue(f.GetProtocol(newMemoryBuffer(b)), nestedValue(depth+1))
+	if !isDepthLimit(err) {
+		t.Fatalf("expected depth limit on reading depth %d, got %v", depth+1, err)
+	}
+	if err = f.GetProtocol(newMemoryBuffer(b)).Skip(thrift.STRUCT); !isDepthLimit(err) {
+		t.Fatalf("expected depth limit on skipping depth %d, got %v", depth+1, err)
+	}
+}
+
+func isDepthLimit(err error) bool {
+	var e *thrift.TProtocolException
+	return errors.As(err, &e) && e.Kind() == thrift.TProtocolErrorDepthLimit
+}
@@ -0,0 +1,279 @@
+package thrifttest
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+
+	"github.com/b1avk/thrift/pkg/thrift"
+)
+
+// values of TProtocol tests are bool, byte, float64, uint16, int16, uint32, int32,
+// uint64, int64, string, []byte, structValue, *listValue, *setValue and *mapValue.
+
+type field struct {
+	id int16
+	v  interface{}
+}
+
+type structValue []field
+
+type listValue struct {
+	elem  thrift.TType
+	items []interface{}
+}
+
+type setValue listValue
+
+type mapValue struct {
+	key, value thrift.TType
+	items      [][2]interface{}
+}
+
+func typeOf(v interface{}) thrift.TType {
+	switch v.(type) {
+	case bool:
+		return thrift.BOOL
+	case byte:
+		return thrift.BYTE
+	case float64:
+		return thrift.DOUBLE
+	case uint16:
+		return thrift.U16
+	case int16:
+		return thrift.I16
+	case uint32:
+		return thrift.U32
+	case int32:
+		return thrift.I32
+	case uint64:
+		return thrift.U64
+	case int64:
+		return thrift.I64
+	case string, []byte:
+		return thrift.STRING
+	case structValue:
+		return thrift.STRUCT
+	case *listValue:
+		return thrift.LIST
+	case *setValue:
+		return thrift.SET
+	case *mapValue:
+		return thrift.MAP
+	}
+	panic(fmt.Sprintf("thrifttest: unexpected value %T", v))
+}
+
+// sameType returns true if got is want, or signed type of same size as unsigned want.
+func sameType(want, got thrift.TType) bool {
+	switch want {
+	case thrift.U16:
+		return got == want || got == thrift.I16
+	case thrift.U32:
+		return got == want || got == thrift.I32
+	case thrift.U64:
+		return got == want || got == thrift.I64
+	}
+	return got == want
+}
+
+func writeValue(p thrift.TProtocol, v interface{}) (err error) {
+	switch v := v.(type) {
+	case bool:
+		err = p.WriteBool(v)
+	case byte:
+		err = p.WriteByte(v)
+	case float64:
+		err = p.WriteDouble(v)
+	case uint16:
+		err = p.WriteU16(v)
+	case int16:
+		err = p.WriteI16(v)
+	case uint32:
+		err = p.WriteU32(v)
+	case int32:
+		err = p.WriteI32(v)
+	case uint64:
+		err = p.WriteU64(v)
+	case int64:
+		err = p.WriteI64(v)
+	case string:
+		err = p.WriteString(v)
+	case []byte:
+		err = p.WriteBinary(v)
+	case structValue:
+		if err = p.WriteStructBegin(thrift.TStructHeader{Name: "test"}); err != nil {
+			return
+		}
+		for _, f := range v {
+			if err = p.WriteFieldBegin(thrift.TFieldHeader{Name: fmt.Sprint("f", f.id), Type: typeOf(f.v), Identity: f.id}); err != nil {
+				return
+			}
+			if err = writeValue(p, f.v); err != nil {
+				return
+			}
+			if err = p.WriteFieldEnd(); err != nil {
+				return
+			}
+		}
+		if err = p.WriteFieldStop(); err == nil {
+			err = p.WriteStructEnd()
+		}
+	case *listValue:
+		if err = p.WriteListBegin(thrift.TListHeader{Element: v.elem, Size: len(v.items)}); err != nil {
+			return
+		}
+		if err = writeValues(p, v.items); err == nil {
+			err = p.WriteListEnd()
+		}
+	case *setValue:
+		if err = p.WriteSetBegin(thrift.TSetHeader{Element: v.elem, Size: len(v.items)}); err != nil {
+			return
+		}
+		if err = writeValues(p, v.items); err == nil {
+			err = p.WriteSetEnd()
+		}
+	case *mapValue:
+		if err = p.WriteMapBegin(thrift.TMapHeader{Key: v.key, Value: v.value, Size: len(v.items)}); err != nil {
+			return
+		}
+		for _, kv := range v.items {
+			if err = writeValues(p, kv[:]); err != nil {
+				return
+			}
+		}
+		err = p.WriteMapEnd()
+	}
+	return
+}
+
+func writeValues(p thrift.TProtocol, values []interface{}) (err error) {
+	for _, v := range values {
+		if err = writeValue(p, v); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// readValue reads value of type of want from p and compares it with want.
+func readValue(p thrift.TProtocol, want interface{}) (err error) {
+	var got interface{}
+	switch want := want.(type) {
+	case bool:
+		got, err = p.ReadBool()
+	case byte:
+		got, err = p.ReadByte()
+	case float64:
+		var v float64
+		if v, err = p.ReadDouble(); err == nil && math.IsNaN(want) && math.IsNaN(v) {
+			return nil
+		}
+		if err == nil && math.Float64bits(v) != math.Float64bits(want) {
+			return fmt.Errorf("expected double %v, got %v", want, v)
+		}
+		return
+	case uint16:
+		got, err = p.ReadU16()
+	case int16:
+		got, err = p.ReadI16()
+	case uint32:
+		got, err = p.ReadU32()
+	case int32:
+		got, err = p.ReadI32()
+	case uint64:
+		got, err = p.ReadU64()
+	case int64:
+		got, err = p.ReadI64()
+	case string:
+		got, err = p.ReadString()
+	case []byte:
+		var v []byte
+		if v, err = p.ReadBinary(); err == nil && !bytes.Equal(v, want) {
+			return fmt.Errorf("expected binary %x, got %x", want, v)
+		}
+		return
+	case structValue:
+		if _, err = p.ReadStructBegin(); err != nil {
+			return
+		}
+		return readFields(p, want)
+	case *listValue:
+		var h thrift.TListHeader
+		if h, err = p.ReadListBegin(); err != nil {
+			return
+		}
+		if !sameType(want.elem, h.Element) || h.Size != len(want.items) {
+			return fmt.Errorf("expected list<%d> of size %d, got list<%d> of size %d", want.elem, len(want.items), h.Element, h.Size)
+		}
+		if err = readValues(p, want.items); err == nil {
+			err = p.ReadListEnd()
+		}
+		return
+	case *setValue:
+		var h thrift.TSetHeader
+		if h, err = p.ReadSetBegin(); err != nil {
+			return
+		}
+		if !sameType(want.elem, h.Element) || h.Size != len(want.items) {
+			return fmt.Errorf("expected set<%d> of size %d, got set<%d> of size %d", want.elem, len(want.items), h.Element, h.Size)
+		}
+		if err = readValues(p, want.items); err == nil {
+			err = p.ReadSetEnd()
+		}
+		return
+	case *mapValue:
+		var h thrift.TMapHeader
+		if h, err = p.ReadMapBegin(); err != nil {
+			return
+		}
+		if h.Size != len(want.items) || h.Size > 0 && !(sameType(want.key, h.Key) && sameType(want.value, h.Value)) {
+			return fmt.Errorf("expected map<%d,%d> of size %d, got map<%d,%d> of size %d", want.key, want.value, len(want.items), h.Key, h.Value, h.Size)
+		}
+		for _, kv := range want.items {
+			if err = readValues(p, kv[:]); err != nil {
+				return
+			}
+		}
+		return p.ReadMapEnd()
+	}
+	if err == nil && got != want {
+		return fmt.Errorf("expected %s %#v, got %#v", protocolTypeNames[typeOf(want)], want, got)
+	}
+	return
+}
+
+func readValues(p thrift.TProtocol, values []interface{}) (err error) {
+	for _, v := range values {
+		if err = readValue(p, v); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// readFields reads fields of struct of which header has been read, and the struct end.
+func readFields(p thrift.TProtocol, want structValue) (err error) {
+	var h thrift.TFieldHeader
+	for _, f := range want {
+		if h, err = p.ReadFieldBegin(); err != nil {
+			return
+		}
+		if h.Identity != f.id || !sameType(typeOf(f.v), h.Type) {
+			return fmt.Errorf("expected field %d of type %d, got field %d of type %d", f.id, typeOf(f.v), h.Identity, h.Type)
+		}
+		if err = readValue(p, f.v); err != nil {
+			return
+		}
+		if err = p.ReadFieldEnd(); err != nil {
+			return
+		}
+	}
+	if h, err = p.ReadFieldBegin(); err != nil {
+		return
+	}
+	if h.Type != thrift.STOP {
+		return fmt.Errorf("expected end of struct, got field %d of type %d", h.Identity, h.Type)
+	}
+	return p.ReadStructEnd()
+}